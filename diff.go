@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/check-payload/dist/releases"
+	"github.com/openshift/check-payload/internal/types"
+)
+
+// loadDiffConfig returns the config for a version of the embedded configs,
+// or else for a config file, with its extends and include resolved.
+func loadDiffConfig(arg string) (*types.ConfigFile, error) {
+	var cfg *types.ConfigFile
+	var warn, err error
+	if slices.Contains(releases.GetVersions(), arg) {
+		cfg, warn, err = releases.ResolveConfigFor(arg)
+	} else {
+		var data []byte
+		if data, err = os.ReadFile(arg); err != nil {
+			return nil, err
+		}
+		cfg, warn, err = releases.ResolveConfig(arg, data)
+	}
+	if warn != nil {
+		klog.Warningf("config %s: %v", arg, warn)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't parse config %s: %w", arg, err)
+	}
+	return cfg, nil
+}
+
+// diffConfigs prints the changes in the lists and tables of the new config
+// compared to the old one, as text or json. Nothing is printed as text if
+// there are none.
+func diffConfigs(oldArg, newArg, format string) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown output format %q (must be text or json)", format)
+	}
+	oldCfg, err := loadDiffConfig(oldArg)
+	if err != nil {
+		return err
+	}
+	newCfg, err := loadDiffConfig(newArg)
+	if err != nil {
+		return err
+	}
+	changes := types.DiffConfigs(oldCfg, newCfg)
+
+	if format == "json" {
+		if changes == nil {
+			changes = []types.ConfigChange{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Old     string               `json:"old"`
+			New     string               `json:"new"`
+			Changes []types.ConfigChange `json:"changes"`
+		}{oldArg, newArg, changes})
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	fmt.Printf("--- %s\n+++ %s\n", oldArg, newArg)
+	for _, c := range changes {
+		fmt.Println(c)
+		for _, e := range c.Removed {
+			fmt.Printf("- %s\n", e)
+		}
+		for _, e := range c.Added {
+			fmt.Printf("+ %s\n", e)
+		}
+	}
+	return nil
+}
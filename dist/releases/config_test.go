@@ -1,6 +1,9 @@
 package releases_test
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/BurntSushi/toml"
@@ -34,12 +37,18 @@ func TestConfigs(t *testing.T) {
 				t.Errorf("main config validation failed: errors: %v; warnings: %v", err, warn)
 			}
 		}
-		add := decodeConfig(t, dir+"/config.toml")
+		add, warn, err := releases.ResolveConfigFor(dir)
+		if err != nil {
+			t.Errorf("%s config can't be resolved: %v", dir, err)
+			continue
+		}
+		if warn != nil {
+			t.Errorf("%s config has duplicates with the configs it extends or includes: %v", dir, warn)
+		}
 		if err, warn := add.Validate(); err != nil || warn != nil {
 			t.Errorf("%s config failed validation: errors: %v; warnings: %v", dir, err, warn)
 		}
-		err := main.Add(add)
-		if err != nil {
+		if err := main.Add(add); err != nil {
 			t.Errorf("%s config has duplicates: %v", dir, err)
 		}
 		// Re-validate the combined config.
@@ -49,3 +58,161 @@ func TestConfigs(t *testing.T) {
 
 	}
 }
+
+func writeConfigs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestResolveConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    map[string]string
+		want     *types.ConfigFile
+		wantWarn bool
+		wantErr  string
+	}{
+		{
+			name: "include",
+			files: map[string]string{
+				"config.toml":           "include = [\"common/net.toml\", \"common/java.toml\"]\nfilter_files = [\"/main\"]\n",
+				"common/net.toml":       "include = [\"java.toml\"]\nfilter_files = [\"/net\"]\n",
+				"common/java.toml":      "filter_files = [\"/java\"]\n",
+				"common/unrelated.toml": "filter_files = [\"/unrelated\"]\n",
+			},
+			// Files included twice are only added once.
+			want: &types.ConfigFile{FilterFiles: []string{"/java", "/net", "/main"}},
+		},
+		{
+			name: "include keeps all entries",
+			files: map[string]string{
+				"config.toml": "include = [\"a.toml\"]\njava_fips_disabled_algorithms = [\"MD5\"]\n\n" +
+					"[[fips_certified_modules]]\nmodule = \"openssl\"\ncertified_artifact = \"openssl-libs\"\ncertified_artifact_min_version = \"1.1.1\"\n",
+				"a.toml": "java_fips_disabled_algorithms = [\"SHA1\"]\n\n" +
+					"[[fips_certified_modules]]\nmodule = \"openssl\"\ncertified_artifact = \"openssl-libs\"\ncertified_artifact_min_version = \"3.0.7\"\n",
+			},
+			// The same artifact, with another version, is another module.
+			want: &types.ConfigFile{
+				JavaDisabledAlgorithms: []string{"SHA1", "MD5"},
+				FIPSCertifiedModules: []types.FipsModule{
+					{Module: "openssl", CertifiedArtifact: "openssl-libs", CertifiedArtifactMinVersion: "3.0.7"},
+					{Module: "openssl", CertifiedArtifact: "openssl-libs", CertifiedArtifactMinVersion: "1.1.1"},
+				},
+			},
+		},
+		{
+			name:     "duplicate entries",
+			files:    map[string]string{"config.toml": "include = [\"a.toml\"]\nfilter_files = [\"/a\"]\n", "a.toml": "filter_files = [\"/a\"]\n"},
+			want:     &types.ConfigFile{FilterFiles: []string{"/a"}},
+			wantWarn: true,
+		},
+		{
+			name:    "include cycle",
+			files:   map[string]string{"config.toml": "include = [\"a.toml\"]\n", "a.toml": "include = [\"b.toml\"]\n", "b.toml": "include = [\"a.toml\"]\n"},
+			wantErr: "config cycle: ",
+		},
+		{
+			name:    "missing include",
+			files:   map[string]string{"config.toml": "include = [\"a.toml\"]\n"},
+			wantErr: "no such file",
+		},
+		{
+			name:    "unknown key",
+			files:   map[string]string{"config.toml": "include = [\"a.toml\"]\n", "a.toml": "extend = \"4.21\"\n"},
+			wantErr: "unknown keys",
+		},
+		{
+			name:    "extends unknown version",
+			files:   map[string]string{"config.toml": "extends = \"1.0\"\n"},
+			wantErr: "embedded config for version 1.0 is not available",
+		},
+		{
+			name:  "extends",
+			files: map[string]string{"config.toml": "extends = \"4.21\"\n\n[[rpm.foo.ignore]]\nerror = \"ErrNotDynLinked\"\nfiles = [\"/usr/bin/foo\"]\n"},
+			want: func() *types.ConfigFile {
+				want, _, _ := releases.ResolveConfigFor("4.21")
+				add := &types.ConfigFile{RPMIgnores: map[string]types.IgnoreLists{"foo": {ErrIgnores: types.ErrIgnoreList{{
+					Error: types.KnownError{Str: "ErrNotDynLinked", Err: types.ErrNotDynLinked},
+					Files: []string{"/usr/bin/foo"},
+				}}}}}
+				if err := want.Add(add); err != nil {
+					t.Fatal(err)
+				}
+				return want
+			}(),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(writeConfigs(t, tc.files), "config.toml")
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, warn, err := releases.ResolveConfig(file, data)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (warn != nil) != tc.wantWarn {
+				t.Errorf("got warnings %v, want %v", warn, tc.wantWarn)
+			}
+			// Compare the configs as written, without the sources of
+			// their entries.
+			gotData, err := types.EncodeConfigFile("config.toml", got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantData, err := types.EncodeConfigFile("config.toml", tc.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(gotData) != string(wantData) {
+				t.Errorf("got:\n%s\nwant:\n%s", gotData, wantData)
+			}
+		})
+	}
+}
+
+// TestResolveMainConfig checks that resolving a config with no extends or
+// include directives gives the config as decoded.
+func TestResolveMainConfig(t *testing.T) {
+	data, err := os.ReadFile(mainConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, warn, err := releases.ResolveConfig(mainConfig, data)
+	if err != nil || warn != nil {
+		t.Fatalf("got error %v, warnings %v", err, warn)
+	}
+	want, err := types.DecodeConfig(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotData, err := types.EncodeConfigFile("config.toml", got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantData, err := types.EncodeConfigFile("config.toml", want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gotData) != string(wantData) {
+		t.Errorf("got:\n%s\nwant:\n%s", gotData, wantData)
+	}
+}
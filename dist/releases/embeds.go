@@ -3,7 +3,9 @@ package releases
 import (
 	"embed"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -26,8 +28,10 @@ func GetVersions() []string {
 	}
 	names := make([]string, 0, len(dirs))
 	for _, d := range dirs {
-		if name := d.Name(); name != "java" {
-			names = append(names, name)
+		// Skip the directories with no config, such as java or those
+		// with files to include.
+		if _, err := fs.Stat(configs, path.Join(d.Name(), "config.toml")); err == nil {
+			names = append(names, d.Name())
 		}
 	}
 	sort.Slice(names, func(i, j int) bool {
@@ -0,0 +1,151 @@
+package releases
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/multierr"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+// configRef is a config file, either embedded or on disk.
+type configRef struct {
+	file     string
+	embedded bool
+}
+
+func (r configRef) String() string {
+	if r.embedded {
+		return "embedded:" + r.file
+	}
+	return r.file
+}
+
+func (r configRef) read() ([]byte, error) {
+	if r.embedded {
+		return configs.ReadFile(r.file)
+	}
+	return os.ReadFile(r.file)
+}
+
+// include returns the config file name, relative to r.
+func (r configRef) include(name string) (configRef, error) {
+	if r.embedded {
+		file := path.Join(path.Dir(r.file), name)
+		if path.IsAbs(name) || !fs.ValidPath(file) {
+			return configRef{}, fmt.Errorf("%s: bad include %q", r, name)
+		}
+		return configRef{file: file, embedded: true}, nil
+	}
+	if filepath.IsAbs(name) {
+		return configRef{file: name}, nil
+	}
+	return configRef{file: filepath.Join(filepath.Dir(r.file), name)}, nil
+}
+
+// resolver resolves the extends and include directives of configs.
+type resolver struct {
+	stack []string        // The configs being resolved, to detect cycles.
+	seen  map[string]bool // The configs already added.
+	warn  error           // Duplicate entries.
+}
+
+func (rs *resolver) resolve(ref configRef, data []byte) (*types.ConfigFile, error) {
+	key := ref.String()
+	for i, s := range rs.stack {
+		if s == key {
+			return nil, fmt.Errorf("config cycle: %s", strings.Join(append(rs.stack[i:], key), " -> "))
+		}
+	}
+	if rs.seen[key] {
+		// Already added through another config.
+		return &types.ConfigFile{}, nil
+	}
+	rs.seen[key] = true
+	rs.stack = append(rs.stack, key)
+	defer func() { rs.stack = rs.stack[:len(rs.stack)-1] }()
+
+	cfg, err := types.DecodeConfigFile(ref.file, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, err)
+	}
+	types.RecordSources(key, data, cfg)
+	if cfg.Extends == "" && len(cfg.Include) == 0 {
+		return cfg, nil
+	}
+	res := &types.ConfigFile{}
+	if cfg.Extends != "" {
+		data, err := GetConfigFor(cfg.Extends)
+		if err != nil {
+			return nil, fmt.Errorf("%s: extends: %w", ref, err)
+		}
+		res, err = rs.resolve(configRef{file: path.Join(cfg.Extends, "config.toml"), embedded: true}, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range cfg.Include {
+		inc, err := ref.include(name)
+		if err != nil {
+			return nil, err
+		}
+		data, err := inc.read()
+		if err != nil {
+			return nil, fmt.Errorf("%s: include: %w", ref, err)
+		}
+		add, err := rs.resolve(inc, data)
+		if err != nil {
+			return nil, err
+		}
+		rs.add(res, add)
+	}
+	cfg.Extends, cfg.Include = "", nil
+	rs.add(res, cfg)
+	return res, nil
+}
+
+func (rs *resolver) add(main, add *types.ConfigFile) {
+	multierr.AppendInto(&rs.warn, main.Add(add))
+}
+
+// ResolveConfig decodes the config data read from file, and resolves its
+// extends and include directives. The embedded config of the version it
+// extends comes first, then the files it includes, in order, then the
+// config itself. A config with neither is returned as decoded. Included files are relative to file. Duplicate entries are
+// returned as warnings.
+func ResolveConfig(file string, data []byte) (cfg *types.ConfigFile, warn, err error) {
+	return resolveConfig(configRef{file: file}, data)
+}
+
+// ResolveConfigFor returns the embedded configuration for a given version,
+// with its extends and include directives resolved, as ResolveConfig does.
+func ResolveConfigFor(version string) (cfg *types.ConfigFile, warn, err error) {
+	data, err := GetConfigFor(version)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resolveConfig(configRef{file: path.Join(version, "config.toml"), embedded: true}, data)
+}
+
+// ResolveEmbeddedConfig resolves the config data embedded in the binary as
+// name, such as the default config.toml, as ResolveConfig does. The files it
+// includes are embedded configs too.
+func ResolveEmbeddedConfig(name string, data []byte) (cfg *types.ConfigFile, warn, err error) {
+	return resolveConfig(configRef{file: name, embedded: true}, data)
+}
+
+func resolveConfig(ref configRef, data []byte) (*types.ConfigFile, error, error) {
+	rs := &resolver{seen: make(map[string]bool)}
+	cfg, err := rs.resolve(ref, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	klog.V(1).Infof("resolved config %s", ref)
+	return cfg, rs.warn, nil
+}
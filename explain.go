@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+// explainErrors prints the description, causes and remediation of the
+// known errors names, as text or json. With no names, it lists all the
+// known errors with their description.
+func explainErrors(names []string, format string) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown output format %q (must be text or json)", format)
+	}
+	list := len(names) == 0
+	if list {
+		names = types.KnownErrorNames()
+	}
+	infos := make([]types.ErrorInfo, 0, len(names))
+	for _, name := range names {
+		info, ok := types.ExplainError(name)
+		if !ok {
+			return fmt.Errorf("unknown error %q (see check-payload explain for the known errors)", name)
+		}
+		infos = append(infos, info)
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	}
+
+	if list {
+		for _, info := range infos {
+			fmt.Printf("%-32s %s\n", info.Name, info.Description)
+		}
+		return nil
+	}
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s: %s\n\n", info.Name, info.Description)
+		fmt.Printf("Reported by: %s\n", info.Validator)
+		if info.GoVersions != "" {
+			fmt.Printf("Go versions: %s\n", info.GoVersions)
+		}
+		fmt.Printf("\nLikely causes:\n%s", bullets(info.Causes))
+		fmt.Printf("\nRemediation:\n%s", bullets(info.Remediation))
+	}
+	return nil
+}
+
+func bullets(lines []string) string {
+	var sb strings.Builder
+	for _, l := range lines {
+		sb.WriteString("  - " + l + "\n")
+	}
+	return sb.String()
+}
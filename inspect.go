@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/openshift/check-payload/internal/validations"
+)
+
+// inspectBinary prints what the validations see of the binary file, in
+// the root directory if set, and their verdicts, as text or json.
+func inspectBinary(file, root, format string) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown output format %q (must be text or json)", format)
+	}
+	if root == "" {
+		// The file is on this system, which the checks look at, for
+		// example for the libcrypto it needs.
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		root, file = "/", abs
+	}
+	ins, err := validations.InspectBinary(context.Background(), root, file)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(ins)
+	}
+	fmt.Print(ins)
+	return nil
+}
@@ -85,6 +85,22 @@ func tryParseTable(tableData []byte, offset int, textAddr uint64) *gosym.Table {
 	return symTable
 }
 
+// PclntabSection returns the ELF section of the Go executable the pclntab
+// is looked for in, or nil if there is none.
+func PclntabSection(exe *elf.File) *elf.Section {
+	// The pclntab lives in different ELF sections depending on Go version
+	// and link mode:
+	//   .gopclntab              - non-PIE, or Go 1.26+ PIE
+	//   .data.rel.ro.gopclntab  - Go <= 1.25 internal PIE (CGO_ENABLED=0)
+	//   .data.rel.ro            - Go <= 1.25 external PIE (CGO_ENABLED=1)
+	for _, name := range []string{".gopclntab", ".data.rel.ro.gopclntab", ".data.rel.ro"} {
+		if s := exe.Section(name); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
 // ReadTable opens a Go ELF executable and reads its symbol table from the pclntab.
 func ReadTable(fileName string, bi *buildinfo.BuildInfo) (*gosym.Table, error) {
 	exe, err := elf.Open(fileName)
@@ -98,18 +114,7 @@ func ReadTable(fileName string, bi *buildinfo.BuildInfo) (*gosym.Table, error) {
 		return nil, fmt.Errorf("missing .text section in %s", fileName)
 	}
 
-	// The pclntab lives in different ELF sections depending on Go version
-	// and link mode:
-	//   .gopclntab              - non-PIE, or Go 1.26+ PIE
-	//   .data.rel.ro.gopclntab  - Go <= 1.25 internal PIE (CGO_ENABLED=0)
-	//   .data.rel.ro            - Go <= 1.25 external PIE (CGO_ENABLED=1)
-	var section *elf.Section
-	for _, name := range []string{".gopclntab", ".data.rel.ro.gopclntab", ".data.rel.ro"} {
-		if s := exe.Section(name); s != nil {
-			section = s
-			break
-		}
-	}
+	section := PclntabSection(exe)
 	if section == nil {
 		return nil, fmt.Errorf("could not find pclntab section in %s", fileName)
 	}
@@ -150,10 +155,16 @@ func ReadTable(fileName string, bi *buildinfo.BuildInfo) (*gosym.Table, error) {
 
 // ExpectedSyms checks that .gopclntab contains any of the expectedSymbols.
 func ExpectedSyms(expectedSymbols []string, symTable *gosym.Table) bool {
+	return len(MatchedSyms(expectedSymbols, symTable)) > 0
+}
+
+// MatchedSyms returns the expectedSymbols .gopclntab contains.
+func MatchedSyms(expectedSymbols []string, symTable *gosym.Table) []string {
+	var matched []string
 	for _, s := range expectedSymbols {
 		if symTable.LookupFunc(s) != nil {
-			return true
+			matched = append(matched, s)
 		}
 	}
-	return false
+	return matched
 }
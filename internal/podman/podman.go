@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -58,9 +59,14 @@ func Inspect(ctx context.Context, image string, args ...string) (string, error)
 }
 
 func runPodman(ctx context.Context, args ...string) (bytes.Buffer, error) {
+	stdout, _, err := runPodmanOutput(ctx, args...)
+	return stdout, err
+}
+
+// runPodmanOutput is runPodman, also returning the stderr of a successful
+// run.
+func runPodmanOutput(ctx context.Context, args ...string) (stdout, stderr bytes.Buffer, _ error) {
 	klog.V(1).InfoS("podman "+args[0], "args", args[1:])
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
 	retry := true
 	cmd := exec.CommandContext(ctx, "podman", args...)
 	cmd.Stdout = &stdout
@@ -81,36 +87,41 @@ again:
 		const javaExitCode = 8
 		var exiterr *exec.ExitError
 		if errors.As(err, &exiterr); exiterr.ExitCode() == javaExitCode {
-			return stdout, errors.New(stderr.String())
+			return stdout, stderr, errors.New(stderr.String())
 		}
-		return stdout, fmt.Errorf("podman error (args=%v) (stderr=%v) (error=%w)", args, stderr.String(), err)
+		return stdout, stderr, fmt.Errorf("podman error (args=%v) (stderr=%v) (error=%w)", args, stderr.String(), err)
 	}
-	return stdout, nil
+	return stdout, stderr, nil
 }
 
-func ScanJava(ctx context.Context, image string, javaDisabledAlgorithms []string) error {
-	data, err := Inspect(ctx, image, "--format", "{{index  .Config.Entrypoint}}|{{index  .Config.Cmd}}|{{index  .Config.WorkingDir}}")
+// InspectJava returns the image configuration needed to find out which
+// Java runtime the image entrypoint uses.
+func InspectJava(ctx context.Context, image string) (*types.JavaComponent, error) {
+	data, err := Inspect(ctx, image, "--format", "{{json .Config}}")
 	if err != nil {
-		return err
+		return nil, err
 	}
-	parts := strings.Split(data, "|")
-
-	jInfo := &types.JavaComponent{
-		Entrypoint: strings.Split(strings.Trim(parts[0], "[]"), ","),
-		Cmd:        strings.Split(strings.Trim(parts[1], "[]"), ","),
-		WorkingDir: strings.TrimSpace(parts[2]),
+	jInfo := &types.JavaComponent{}
+	if err := json.Unmarshal([]byte(data), jInfo); err != nil {
+		return nil, fmt.Errorf("can't parse image config: %w", err)
 	}
+	return jInfo, nil
+}
 
+// ValidateJavaRuntime runs FIPS checks using the Java runtime rt in the
+// image. A nil return means the runtime passed the checks.
+func ValidateJavaRuntime(ctx context.Context, image string, jInfo *types.JavaComponent, rt types.JavaRuntime, javaDisabledAlgorithms []string) error {
 	// This was done because java versions before 1.8 cannot use the java class `java.util.stream.Collectors`.
 	// Also, java versions prior to 1.11 cannot execute .Java source files without compiling first.
-	cmdArgs := []string{"run", "-it", "--rm", "--entrypoint", "", image, "java", "-XshowSettings:properties", "-version"}
-	stdout, err := runPodman(ctx, cmdArgs...)
+	// Java prints the settings to stderr.
+	cmdArgs := []string{"run", "--rm", "--entrypoint", rt.Java, image, "-XshowSettings:properties", "-version"}
+	_, settings, err := runPodmanOutput(ctx, cmdArgs...)
 	if err != nil {
 		return err
 	}
 
 	jClassVer := ""
-	scanner := bufio.NewScanner(strings.NewReader(stdout.String()))
+	scanner := bufio.NewScanner(strings.NewReader(settings.String()))
 	for scanner.Scan() {
 		if strings.Contains(scanner.Text(), "java.class.version =") {
 			jClassVer = strings.TrimSpace(strings.Split(scanner.Text(), "=")[1])
@@ -131,21 +142,25 @@ func ScanJava(ctx context.Context, image string, javaDisabledAlgorithms []string
 	}
 	defer os.Remove(algFilePath)
 
-	cmdArgs = []string{"run", "--rm", "--entrypoint", "", "-v", javaFilePath + ":" + jInfo.WorkingDir + "/" + releases.JavaFips + ":z", "-v", algFilePath + ":" + jInfo.WorkingDir + "/" + algFile + ":z", image}
+	cmdArgs = []string{"run", "--rm", "-v", javaFilePath + ":" + jInfo.WorkingDir + "/" + releases.JavaFips + ":z", "-v", algFilePath + ":" + jInfo.WorkingDir + "/" + algFile + ":z"}
 	javaClassVersion, err := semver.NewVersion(jClassVer)
 	if err != nil {
-		return err
+		return fmt.Errorf("can't get java class version: %w", err)
 	}
 
 	if validations.JavaClassLessThan52.Check(javaClassVersion) {
 		return errors.New("this scan tool supports java 1.8+")
 	}
 	if validations.JavaClassLessThan55.Check(javaClassVersion) {
-		cmdArgs = append(cmdArgs, []string{"/bin/sh", "-c", "javac " + releases.JavaFips + " && java FIPS " + algFile}...)
+		// The check has to be compiled first, which needs a JDK.
+		if rt.Javac == "" {
+			return fmt.Errorf("no javac in %s: java runtimes before java 11 can only be checked if they are a JDK", rt.Home)
+		}
+		cmdArgs = append(cmdArgs, "--entrypoint", "/bin/sh", image, "-c", rt.Javac+" "+releases.JavaFips+" && "+rt.Java+" FIPS "+algFile)
 	} else {
-		cmdArgs = append(cmdArgs, []string{"java", releases.JavaFips, algFile}...)
+		cmdArgs = append(cmdArgs, "--entrypoint", rt.Java, image, releases.JavaFips, algFile)
 	}
-	stdout, err = runPodman(ctx, cmdArgs...)
+	stdout, err := runPodman(ctx, cmdArgs...)
 	if err != nil {
 		klog.Infoln(stdout.String())
 		return err
@@ -0,0 +1,139 @@
+package rpm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// A minimal reader of Berkeley DB hash databases, as used by the rpmdb
+// Packages file on RHEL 8 and earlier.
+
+const (
+	bdbHashMagic    = 0x061561
+	bdbPageHdrSize  = 26
+	bdbHashMetaPage = 8
+
+	bdbPageHashUnsorted = 2
+	bdbPageOverflow     = 7
+	bdbPageHash         = 13
+
+	bdbKeyData = 1 // H_KEYDATA
+	bdbOffPage = 3 // H_OFFPAGE
+)
+
+var errBadBdb = errors.New("malformed berkeley db")
+
+// readBdbPackages returns the header blobs from the rpmdb Packages
+// Berkeley DB hash database in file.
+func readBdbPackages(file string) ([][]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := parseBdbHash(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return blobs, nil
+}
+
+func parseBdbHash(data []byte) ([][]byte, error) {
+	if len(data) < 512 {
+		return nil, fmt.Errorf("%w: file too short", errBadBdb)
+	}
+	// Pages are stored in the byte order of the host which created them.
+	var bo binary.ByteOrder = binary.LittleEndian
+	if bo.Uint32(data[12:]) != bdbHashMagic {
+		bo = binary.BigEndian
+		if bo.Uint32(data[12:]) != bdbHashMagic {
+			return nil, fmt.Errorf("%w: not a hash database", errBadBdb)
+		}
+	}
+	if data[25] != bdbHashMetaPage {
+		return nil, fmt.Errorf("%w: bad metadata page", errBadBdb)
+	}
+	pageSize := int(bo.Uint32(data[20:]))
+	if pageSize < 512 || pageSize > 65536 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("%w: bad page size %d", errBadBdb, pageSize)
+	}
+	npages := len(data) / pageSize
+
+	page := func(n uint32) []byte {
+		if int(n) >= npages {
+			return nil
+		}
+		return data[int(n)*pageSize : int(n+1)*pageSize]
+	}
+
+	var blobs [][]byte
+	for n := 1; n < npages; n++ {
+		p := page(uint32(n))
+		if typ := p[25]; typ != bdbPageHash && typ != bdbPageHashUnsorted {
+			continue
+		}
+		entries := int(bo.Uint16(p[20:]))
+		if bdbPageHdrSize+2*entries > pageSize {
+			return nil, fmt.Errorf("%w: bad entry count on page %d", errBadBdb, n)
+		}
+		// Entries are key/data pairs, stored from the end of the page.
+		for i := 1; i < entries; i += 2 {
+			keyOff := int(bo.Uint16(p[bdbPageHdrSize+2*(i-1):]))
+			keyEnd := pageSize
+			if i > 1 {
+				keyEnd = int(bo.Uint16(p[bdbPageHdrSize+2*(i-2):]))
+			}
+			off, end := int(bo.Uint16(p[bdbPageHdrSize+2*i:])), keyOff
+			if off >= end || keyOff >= keyEnd || keyEnd > pageSize {
+				return nil, fmt.Errorf("%w: bad entry offset on page %d", errBadBdb, n)
+			}
+			// The keys are the header instance numbers; rpm keeps the
+			// next instance number under key 0, which is not a header.
+			if key := p[keyOff:keyEnd]; len(key) == 5 && key[0] == bdbKeyData && bo.Uint32(key[1:]) == 0 {
+				continue
+			}
+			item := p[off:end]
+			switch item[0] {
+			case bdbKeyData:
+				blobs = append(blobs, item[1:])
+			case bdbOffPage:
+				if len(item) < 12 {
+					return nil, fmt.Errorf("%w: bad off-page item on page %d", errBadBdb, n)
+				}
+				blob, err := readBdbOverflow(page, bo, bo.Uint32(item[4:]), bo.Uint32(item[8:]))
+				if err != nil {
+					return nil, err
+				}
+				blobs = append(blobs, blob)
+			}
+		}
+	}
+	return blobs, nil
+}
+
+// readBdbOverflow reads an item of length size stored in a chain of
+// overflow pages, starting from page pgno.
+func readBdbOverflow(page func(uint32) []byte, bo binary.ByteOrder, pgno, size uint32) ([]byte, error) {
+	if size > maxHeaderData {
+		return nil, fmt.Errorf("%w: overflow item too big", errBadBdb)
+	}
+	blob := make([]byte, 0, size)
+	for pgno != 0 && uint32(len(blob)) < size {
+		p := page(pgno)
+		if p == nil || p[25] != bdbPageOverflow {
+			return nil, fmt.Errorf("%w: bad overflow page %d", errBadBdb, pgno)
+		}
+		// For overflow pages, hf_offset is the length of data on the page.
+		n := int(bo.Uint16(p[22:]))
+		if bdbPageHdrSize+n > len(p) {
+			return nil, fmt.Errorf("%w: bad overflow page %d", errBadBdb, pgno)
+		}
+		blob = append(blob, p[bdbPageHdrSize:bdbPageHdrSize+n]...)
+		pgno = bo.Uint32(p[16:])
+	}
+	if uint32(len(blob)) != size {
+		return nil, fmt.Errorf("%w: overflow chain length mismatch", errBadBdb)
+	}
+	return blob, nil
+}
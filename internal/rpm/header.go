@@ -0,0 +1,244 @@
+package rpm
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Header tags, see rpmtag.h.
+const (
+	tagSigPGP         = 259
+	tagSigGPG         = 262
+	tagDSAHeader      = 267
+	tagRSAHeader      = 268
+	tagName           = 1000
+	tagVersion        = 1001
+	tagRelease        = 1002
+	tagEpoch          = 1003
+	tagVendor         = 1011
+	tagArch           = 1022
+	tagOldFileNames   = 1027
+	tagFileSizes      = 1028
+	tagFileModes      = 1030
+	tagFileDigests    = 1035
+	tagFileLinkTos    = 1036
+	tagSourceRPM      = 1044
+	tagDirIndexes     = 1116
+	tagBaseNames      = 1117
+	tagDirNames       = 1118
+	tagLongFileSizes  = 5008
+	tagFileDigestAlgo = 5011
+)
+
+// Header data types.
+const (
+	typeNull        = 0
+	typeChar        = 1
+	typeInt8        = 2
+	typeInt16       = 3
+	typeInt32       = 4
+	typeInt64       = 5
+	typeString      = 6
+	typeBin         = 7
+	typeStringArray = 8
+	typeI18NString  = 9
+)
+
+const (
+	maxHeaderEntries = 0xffff
+	maxHeaderData    = 256 << 20
+)
+
+var errBadHeader = errors.New("malformed rpm header")
+
+type indexEntry struct {
+	Tag    int32
+	Type   uint32
+	Offset int32
+	Count  uint32
+}
+
+// header is an rpm header, as stored in the rpmdb (i.e. without the lead
+// and header magic).
+type header struct {
+	entries map[int32]indexEntry
+	data    []byte
+}
+
+func parseHeader(blob []byte) (*header, error) {
+	if len(blob) < 8 {
+		return nil, errBadHeader
+	}
+	il := binary.BigEndian.Uint32(blob[0:])
+	dl := binary.BigEndian.Uint32(blob[4:])
+	if il > maxHeaderEntries || dl > maxHeaderData || uint64(len(blob)) < 8+uint64(il)*16+uint64(dl) {
+		return nil, errBadHeader
+	}
+	h := &header{
+		entries: make(map[int32]indexEntry, il),
+		data:    blob[8+il*16 : 8+il*16+dl],
+	}
+	for i := uint32(0); i < il; i++ {
+		e := blob[8+i*16:]
+		entry := indexEntry{
+			Tag:    int32(binary.BigEndian.Uint32(e[0:])),
+			Type:   binary.BigEndian.Uint32(e[4:]),
+			Offset: int32(binary.BigEndian.Uint32(e[8:])),
+			Count:  binary.BigEndian.Uint32(e[12:]),
+		}
+		if entry.Offset < 0 || int(entry.Offset) > len(h.data) {
+			return nil, fmt.Errorf("%w: tag %d offset out of range", errBadHeader, entry.Tag)
+		}
+		h.entries[entry.Tag] = entry
+	}
+	return h, nil
+}
+
+// strings returns the value of a string, or string array, tag.
+func (h *header) strings(tag int32) []string {
+	e, ok := h.entries[tag]
+	if !ok {
+		return nil
+	}
+	count := e.Count
+	switch e.Type {
+	case typeString:
+		count = 1
+	case typeStringArray, typeI18NString:
+	default:
+		return nil
+	}
+	var ss []string
+	data := h.data[e.Offset:]
+	for i := uint32(0); i < count; i++ {
+		end := bytes.IndexByte(data, 0)
+		if end == -1 {
+			break
+		}
+		ss = append(ss, string(data[:end]))
+		data = data[end+1:]
+	}
+	return ss
+}
+
+// string returns the value of a string tag.
+func (h *header) string(tag int32) string {
+	if ss := h.strings(tag); len(ss) > 0 {
+		return ss[0]
+	}
+	return ""
+}
+
+// bin returns the value of a binary tag.
+func (h *header) bin(tag int32) []byte {
+	e, ok := h.entries[tag]
+	if !ok || e.Type != typeBin || uint64(e.Offset)+uint64(e.Count) > uint64(len(h.data)) {
+		return nil
+	}
+	return h.data[e.Offset : int64(e.Offset)+int64(e.Count)]
+}
+
+// signingKeyID returns the key ID of the header signature (as shown by
+// "rpm -qi"), or an empty string if the package is not signed.
+func (h *header) signingKeyID() string {
+	for _, tag := range []int32{tagRSAHeader, tagDSAHeader, tagSigPGP, tagSigGPG} {
+		if id := pgpSigKeyID(h.bin(tag)); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// ints returns the value of an integer tag of any size.
+func (h *header) ints(tag int32) []int64 {
+	e, ok := h.entries[tag]
+	if !ok {
+		return nil
+	}
+	var size uint32
+	switch e.Type {
+	case typeChar, typeInt8:
+		size = 1
+	case typeInt16:
+		size = 2
+	case typeInt32:
+		size = 4
+	case typeInt64:
+		size = 8
+	default:
+		return nil
+	}
+	data := h.data[e.Offset:]
+	if uint64(len(data)) < uint64(e.Count)*uint64(size) {
+		return nil
+	}
+	v := make([]int64, e.Count)
+	for i := range v {
+		switch size {
+		case 1:
+			v[i] = int64(data[i])
+		case 2:
+			v[i] = int64(binary.BigEndian.Uint16(data[i*2:]))
+		case 4:
+			v[i] = int64(binary.BigEndian.Uint32(data[i*4:]))
+		case 8:
+			v[i] = int64(binary.BigEndian.Uint64(data[i*8:]))
+		}
+	}
+	return v
+}
+
+// fileNames returns the full paths of all the files in the package.
+func (h *header) fileNames() []string {
+	base := h.strings(tagBaseNames)
+	if len(base) == 0 {
+		// Very old packages.
+		return h.strings(tagOldFileNames)
+	}
+	dirs := h.strings(tagDirNames)
+	idx := h.ints(tagDirIndexes)
+	files := make([]string, 0, len(base))
+	for i, b := range base {
+		if i >= len(idx) || idx[i] < 0 || idx[i] >= int64(len(dirs)) {
+			break
+		}
+		files = append(files, dirs[idx[i]]+b)
+	}
+	return files
+}
+
+// fileAttrs returns the rpmdb records of files, as returned by fileNames.
+func (h *header) fileAttrs(files []string) []File {
+	sizes := h.ints(tagLongFileSizes)
+	if sizes == nil {
+		sizes = h.ints(tagFileSizes)
+	}
+	modes := h.ints(tagFileModes)
+	digests := h.strings(tagFileDigests)
+	links := h.strings(tagFileLinkTos)
+	algo := crypto.MD5 // The default for old packages.
+	if a := h.ints(tagFileDigestAlgo); len(a) > 0 {
+		algo = digestAlgos[a[0]]
+	}
+
+	attrs := make([]File, len(files))
+	for i, f := range files {
+		attrs[i] = File{Path: f, DigestAlgo: algo}
+		if i < len(sizes) {
+			attrs[i].Size = sizes[i]
+		}
+		if i < len(modes) {
+			attrs[i].Mode = uint32(modes[i])
+		}
+		if i < len(digests) {
+			attrs[i].Digest = digests[i]
+		}
+		if i < len(links) {
+			attrs[i].LinkTo = links[i]
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,191 @@
+package rpm
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// rpmdbBackends are the rpmdb file names, and the functions to read them,
+// in the order of preference (same as rpm itself).
+var rpmdbBackends = []struct {
+	file string
+	read func(string) ([][]byte, error)
+}{
+	{"rpmdb.sqlite", readSqlitePackages},
+	{"Packages.db", readNdbPackages},
+	{"Packages", readBdbPackages},
+}
+
+// Package is an installed package, as read from the rpmdb.
+type Package struct {
+	Name         string
+	Version      string
+	Release      string
+	Epoch        *int64
+	Arch         string
+	Vendor       string
+	SourceRPM    string
+	SigningKeyID string // Key ID of the header signature, in hex.
+	Files        []string
+
+	fileAttrs []File
+}
+
+// NVRA returns the package Name-Version-Release.Arch.
+func (p *Package) NVRA() string {
+	nvra := p.Name + "-" + p.Version + "-" + p.Release
+	if p.Arch != "" {
+		nvra += "." + p.Arch
+	}
+	return nvra
+}
+
+// NEVRA returns the package Name-[Epoch:]Version-Release.Arch.
+func (p *Package) NEVRA() string {
+	nevra := p.Name + "-" + p.EVR()
+	if p.Arch != "" {
+		nevra += "." + p.Arch
+	}
+	return nevra
+}
+
+// EVR returns the package [Epoch:]Version-Release.
+func (p *Package) EVR() string {
+	evr := p.Version + "-" + p.Release
+	if p.Epoch != nil {
+		evr = strconv.FormatInt(*p.Epoch, 10) + ":" + evr
+	}
+	return evr
+}
+
+// Index is the package database of a root, with an index of file ownership.
+type Index struct {
+	file    string
+	size    int64
+	modTime time.Time
+
+	pkgs []Package
+
+	ownersOnce sync.Once
+	owners     map[string]*Package
+}
+
+// Packages returns all the installed packages.
+func (idx *Index) Packages() []Package {
+	return idx.pkgs
+}
+
+// Package returns the installed package of the given name, or nil.
+func (idx *Index) Package(name string) *Package {
+	for i := range idx.pkgs {
+		if idx.pkgs[i].Name == name {
+			return &idx.pkgs[i]
+		}
+	}
+	return nil
+}
+
+// Owner returns the package which owns the file, or nil.
+func (idx *Index) Owner(file string) *Package {
+	idx.ownersOnce.Do(func() {
+		idx.owners = make(map[string]*Package)
+		for i := range idx.pkgs {
+			for _, f := range idx.pkgs[i].Files {
+				// Keep the first owner of files shared between packages.
+				if _, ok := idx.owners[f]; !ok {
+					idx.owners[f] = &idx.pkgs[i]
+				}
+			}
+		}
+	})
+	return idx.owners[path.Clean("/"+file)]
+}
+
+var (
+	indexCacheMu sync.Mutex
+	indexCache   = make(map[string]*Index)
+)
+
+// IndexOf returns the package index of the rootfs under root. The index is
+// built once and cached, and only rebuilt if the rpmdb changes. Use
+// ForgetIndex once done with root.
+func IndexOf(root string) (*Index, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	dbpath, err := rpmDBPath(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range rpmdbBackends {
+		file := filepath.Join(root, dbpath, b.file)
+		st, err := os.Lstat(file)
+		if err != nil || !st.Mode().IsRegular() {
+			continue
+		}
+
+		indexCacheMu.Lock()
+		idx := indexCache[root]
+		indexCacheMu.Unlock()
+		if idx != nil && idx.file == file && idx.size == st.Size() && idx.modTime.Equal(st.ModTime()) {
+			return idx, nil
+		}
+		// The rpmdb is read without holding the lock, so that scans of
+		// different roots run in parallel.
+		klog.V(1).Infof("reading rpmdb %s", file)
+		blobs, err := b.read(file)
+		if err != nil {
+			return nil, fmt.Errorf("can't read rpmdb: %w", err)
+		}
+		idx = &Index{file: file, size: st.Size(), modTime: st.ModTime(), pkgs: make([]Package, 0, len(blobs))}
+		for _, blob := range blobs {
+			h, err := parseHeader(blob)
+			if err != nil {
+				return nil, fmt.Errorf("can't read rpmdb %s: %w", file, err)
+			}
+			p := Package{
+				Name:         h.string(tagName),
+				Version:      h.string(tagVersion),
+				Release:      h.string(tagRelease),
+				Arch:         h.string(tagArch),
+				Vendor:       h.string(tagVendor),
+				SourceRPM:    h.string(tagSourceRPM),
+				SigningKeyID: h.signingKeyID(),
+				Files:        h.fileNames(),
+			}
+			if p.Name == "" {
+				// Not a package header.
+				continue
+			}
+			p.fileAttrs = h.fileAttrs(p.Files)
+			if e := h.ints(tagEpoch); len(e) > 0 {
+				p.Epoch = &e[0]
+			}
+			idx.pkgs = append(idx.pkgs, p)
+		}
+		indexCacheMu.Lock()
+		indexCache[root] = idx
+		indexCacheMu.Unlock()
+		return idx, nil
+	}
+	return nil, fmt.Errorf("can't find rpmdb under %q", filepath.Join(root, dbpath))
+}
+
+// ForgetIndex drops the cached package index of root, if any.
+func ForgetIndex(root string) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return
+	}
+	indexCacheMu.Lock()
+	delete(indexCache, root)
+	indexCacheMu.Unlock()
+}
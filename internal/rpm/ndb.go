@@ -0,0 +1,77 @@
+package rpm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// A minimal reader of the rpm native database (ndb) Packages.db file, as
+// used by SUSE. All values are little-endian.
+
+const (
+	ndbHeaderMagic = "RpmP"
+	ndbSlotMagic   = "Slot"
+	ndbBlobMagic   = "BlbS"
+
+	ndbPageSize  = 4096
+	ndbSlotStart = 32 // The first two slots hold the file header.
+	ndbSlotSize  = 16
+	ndbBlkSize   = 16
+	ndbBlobHead  = 16
+)
+
+var errBadNdb = errors.New("malformed ndb database")
+
+// readNdbPackages returns the header blobs from the rpmdb Packages.db
+// database in file.
+func readNdbPackages(file string) ([][]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := parseNdb(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return blobs, nil
+}
+
+func parseNdb(data []byte) ([][]byte, error) {
+	le := binary.LittleEndian
+	if len(data) < ndbSlotStart || string(data[:4]) != ndbHeaderMagic {
+		return nil, fmt.Errorf("%w: bad magic", errBadNdb)
+	}
+	slotEnd := uint64(le.Uint32(data[12:])) * ndbPageSize
+	if slotEnd > uint64(len(data)) {
+		return nil, fmt.Errorf("%w: bad slot page count", errBadNdb)
+	}
+
+	var blobs [][]byte
+	for off := uint64(ndbSlotStart); off+ndbSlotSize <= slotEnd; off += ndbSlotSize {
+		slot := data[off:]
+		if string(slot[:4]) != ndbSlotMagic {
+			return nil, fmt.Errorf("%w: bad slot at %d", errBadNdb, off)
+		}
+		pkgIdx := le.Uint32(slot[4:])
+		if pkgIdx == 0 {
+			// A free slot.
+			continue
+		}
+		blkOff := uint64(le.Uint32(slot[8:])) * ndbBlkSize
+		if blkOff+ndbBlobHead > uint64(len(data)) {
+			return nil, fmt.Errorf("%w: package %d: bad blob offset", errBadNdb, pkgIdx)
+		}
+		blob := data[blkOff:]
+		if string(blob[:4]) != ndbBlobMagic || le.Uint32(blob[4:]) != pkgIdx {
+			return nil, fmt.Errorf("%w: package %d: bad blob header", errBadNdb, pkgIdx)
+		}
+		size := uint64(le.Uint32(blob[12:]))
+		if ndbBlobHead+size > uint64(len(blob)) {
+			return nil, fmt.Errorf("%w: package %d: bad blob length", errBadNdb, pkgIdx)
+		}
+		blobs = append(blobs, blob[ndbBlobHead:ndbBlobHead+size])
+	}
+	return blobs, nil
+}
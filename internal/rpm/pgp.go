@@ -0,0 +1,138 @@
+package rpm
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// OpenPGP signature subpacket types, see RFC 4880 and RFC 9580.
+const (
+	pgpSubIssuer            = 16
+	pgpSubIssuerFingerprint = 33
+)
+
+// pgpSigKeyID returns the issuer key ID of an OpenPGP signature packet, in
+// hex, or an empty string if it can't be found.
+func pgpSigKeyID(pkt []byte) string {
+	body, ok := pgpPacketBody(pkt)
+	if !ok || len(body) == 0 {
+		return ""
+	}
+	switch body[0] {
+	case 3:
+		// Version, hashed length (5), type, creation time, key ID.
+		if len(body) < 15 {
+			return ""
+		}
+		return hex.EncodeToString(body[7:15])
+	case 4:
+		// Version, type, public key and hash algorithms, then hashed
+		// and unhashed subpackets.
+		if len(body) < 6 {
+			return ""
+		}
+		hashed := int(binary.BigEndian.Uint16(body[4:]))
+		if 6+hashed+2 > len(body) {
+			return ""
+		}
+		id := pgpSubpacketsKeyID(body[6 : 6+hashed])
+		if id == "" {
+			unhashed := int(binary.BigEndian.Uint16(body[6+hashed:]))
+			if 8+hashed+unhashed > len(body) {
+				return ""
+			}
+			id = pgpSubpacketsKeyID(body[8+hashed : 8+hashed+unhashed])
+		}
+		return id
+	}
+	return ""
+}
+
+// pgpPacketBody returns the body of the OpenPGP signature packet pkt.
+func pgpPacketBody(pkt []byte) ([]byte, bool) {
+	if len(pkt) < 2 || pkt[0]&0x80 == 0 {
+		return nil, false
+	}
+	var tag byte
+	var n, hlen int
+	if pkt[0]&0x40 == 0 {
+		// Old format.
+		tag = (pkt[0] >> 2) & 0x0f
+		switch pkt[0] & 3 {
+		case 0:
+			n, hlen = int(pkt[1]), 2
+		case 1:
+			if len(pkt) < 3 {
+				return nil, false
+			}
+			n, hlen = int(binary.BigEndian.Uint16(pkt[1:])), 3
+		case 2:
+			if len(pkt) < 5 {
+				return nil, false
+			}
+			n, hlen = int(binary.BigEndian.Uint32(pkt[1:])), 5
+		default:
+			// Indeterminate length.
+			n, hlen = len(pkt)-1, 1
+		}
+	} else {
+		// New format.
+		tag = pkt[0] & 0x3f
+		switch o := pkt[1]; {
+		case o < 192:
+			n, hlen = int(o), 2
+		case o < 224:
+			if len(pkt) < 3 {
+				return nil, false
+			}
+			n, hlen = (int(o)-192)<<8+int(pkt[2])+192, 3
+		case o == 255:
+			if len(pkt) < 6 {
+				return nil, false
+			}
+			n, hlen = int(binary.BigEndian.Uint32(pkt[2:])), 6
+		default:
+			// Partial body lengths are not used for signatures.
+			return nil, false
+		}
+	}
+	const tagSignature = 2
+	if tag != tagSignature || n < 0 || hlen+n > len(pkt) {
+		return nil, false
+	}
+	return pkt[hlen : hlen+n], true
+}
+
+// pgpSubpacketsKeyID returns the issuer key ID from signature subpackets.
+func pgpSubpacketsKeyID(sub []byte) string {
+	for len(sub) > 0 {
+		var n, hlen int
+		switch o := sub[0]; {
+		case o < 192:
+			n, hlen = int(o), 1
+		case o < 255:
+			if len(sub) < 2 {
+				return ""
+			}
+			n, hlen = (int(o)-192)<<8+int(sub[1])+192, 2
+		default:
+			if len(sub) < 5 {
+				return ""
+			}
+			n, hlen = int(binary.BigEndian.Uint32(sub[1:])), 5
+		}
+		if n < 1 || hlen+n > len(sub) {
+			return ""
+		}
+		typ, data := sub[hlen]&0x7f, sub[hlen+1:hlen+n]
+		switch {
+		case typ == pgpSubIssuer && len(data) == 8:
+			return hex.EncodeToString(data)
+		case typ == pgpSubIssuerFingerprint && len(data) == 21 && data[0] == 4:
+			// The key ID of a v4 key is the low 64 bits of its fingerprint.
+			return hex.EncodeToString(data[13:])
+		}
+		sub = sub[hlen+n:]
+	}
+	return ""
+}
@@ -1,14 +1,10 @@
 package rpm
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 
 	"k8s.io/klog/v2"
 )
@@ -18,58 +14,38 @@ type Info struct {
 	NVRA string // Name-Version-Release.Arch
 }
 
-func GetFilesFromRPM(ctx context.Context, root, rpm string) ([]string, error) {
-	klog.V(1).Infof("rpm -ql %v root=%s", rpm, root)
-	dbpath, err := rpmDBPath(root)
+// GetFilesFromRPM returns the files of the rpm under root, which is given
+// either by name or by Name-Version-Release.Arch.
+func GetFilesFromRPM(_ context.Context, root, rpm string) ([]string, error) {
+	klog.V(1).Infof("listing files of %v root=%s", rpm, root)
+	idx, err := IndexOf(root)
 	if err != nil {
 		return nil, err
 	}
-	cmd := exec.CommandContext(ctx, "rpm", "-ql", "--dbpath", dbpath, "--root", root, rpm)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("rpm -ql error: %w (stderr=%v)", err, stderr.String())
+	var files []string
+	found := false
+	for i := range idx.pkgs {
+		if p := &idx.pkgs[i]; p.Name == rpm || p.NVRA() == rpm {
+			files = append(files, p.Files...)
+			found = true
+		}
 	}
-
-	files := []string{}
-	scanner := bufio.NewScanner(&stdout)
-	for scanner.Scan() {
-		files = append(files, scanner.Text())
+	if !found {
+		return nil, fmt.Errorf("package %s is not installed under %q", rpm, root)
 	}
 	return files, nil
 }
 
-func GetAllRPMs(ctx context.Context, root string) ([]Info, error) {
-	klog.V(1).Infof("rpm -qa root=%s", root)
-	root, err := filepath.Abs(root)
-	if err != nil {
-		return nil, err
-	}
-	dbpath, err := rpmDBPath(root)
+// GetAllRPMs returns all the rpms installed under root.
+func GetAllRPMs(_ context.Context, root string) ([]Info, error) {
+	klog.V(1).Infof("listing rpms root=%s", root)
+	idx, err := IndexOf(root)
 	if err != nil {
 		return nil, err
 	}
-	cmd := exec.CommandContext(ctx, "rpm", "-qa", "--dbpath", dbpath, "--root", root, "--qf", "%{NAME} %{NVRA}\n")
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("rpm -qa error: %w (stderr=%v)", err, stderr.String())
-	}
-	rpms := []Info{}
-
-	scanner := bufio.NewScanner(&stdout)
-	for scanner.Scan() {
-		f := strings.Fields(scanner.Text())
-		if len(f) != 2 {
-			// Should never happen.
-			continue
-		}
-		rpms = append(rpms, Info{Name: f[0], NVRA: f[1]})
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading rpm -qa: %w", err)
+	rpms := make([]Info, 0, len(idx.pkgs))
+	for i := range idx.pkgs {
+		rpms = append(rpms, Info{Name: idx.pkgs[i].Name, NVRA: idx.pkgs[i].NVRA()})
 	}
 	if len(rpms) == 0 {
 		return nil, fmt.Errorf("no rpms found under %q", root)
@@ -78,66 +54,31 @@ func GetAllRPMs(ctx context.Context, root string) ([]Info, error) {
 }
 
 // VersionOf returns the version string of the named RPM package.
-func VersionOf(ctx context.Context, root, pkg string) (string, error) {
-	root, err := filepath.Abs(root)
-	if err != nil {
-		return "", err
-	}
-	dbpath, err := rpmDBPath(root)
+func VersionOf(_ context.Context, root, pkg string) (string, error) {
+	idx, err := IndexOf(root)
 	if err != nil {
 		return "", err
 	}
-	cmd := exec.CommandContext(ctx, "rpm", "-q", "--dbpath", dbpath, "--root", root, "--queryformat=%{VERSION}", pkg)
-	var outbuf, errbuf bytes.Buffer
-	cmd.Stdout = &outbuf
-	cmd.Stderr = &errbuf
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("rpm -q %s: %w (stderr=%s)", pkg, err, strings.TrimSpace(errbuf.String()))
+	if p := idx.Package(pkg); p != nil {
+		return p.Version, nil
 	}
-	return strings.TrimSpace(outbuf.String()), nil
+	return "", fmt.Errorf("package %s is not installed under %q", pkg, root)
 }
 
 // NameFromFile tells which rpm the given file belongs to, under a given root.
-func NameFromFile(ctx context.Context, root, path string) (string, error) {
-	// We can either:
-	//  1: Execute host's rpm binary;
-	//  2: Execute in-root rpm binary using chroot;
-	//  3: Use something like https://github.com/knqyf263/go-rpmdb
-	//
-	// Every approach has its pros and cons.
-	//  1: We have to find where rpmdb is located, as in-root configuration
-	//     may differ from the host one, and we assume host rpm understands
-	//     in-root rpmdb format.
-	//  2: We have to trust rpm binary, and it has to be of the same arch.
-	//  3: We have to trust the third-party package, and it might be slow
-	//     or not have all the required functionality.
-	//
-	// Let's settle on 1 for now.
-
-	dbpath, err := rpmDBPath(root)
+// If the file does not belong to any rpm, an empty string is returned.
+//
+// The rpmdb is read in-process (see IndexOf) rather than by running rpm,
+// as the host rpm may not support the rpmdb format used in the root.
+func NameFromFile(_ context.Context, root, path string) (string, error) {
+	idx, err := IndexOf(root)
 	if err != nil {
 		return "", err
 	}
-	cmd := exec.CommandContext(ctx, "rpm", "-qf", "--dbpath", dbpath, "--root", root, "--queryformat=%{NAME}", path)
-	cmd.Env = append(cmd.Environ(), "LANG=C") // Do not localize error messages.
-	var outbuf, errbuf bytes.Buffer
-	cmd.Stdout = &outbuf
-	cmd.Stderr = &errbuf
-
-	if err := cmd.Run(); err != nil {
-		errB := errbuf.Bytes()
-		// If the file does not belong to any package, do not return an error.
-		if bytes.Contains(errB, []byte("is not owned by any package")) ||
-			// If the file is not owned by any package, and a file
-			// with the same path does not exist *on the host*, the
-			// error message is ENOENT. This seems to be rpm bug, see
-			// https://github.com/rpm-software-management/rpm/issues/2576.
-			bytes.Contains(errB, []byte("No such file or directory")) {
-			return "", nil
-		}
-		return "", fmt.Errorf("rpm -qf error: %w (stderr=%s)", err, strings.TrimSpace(errbuf.String()))
+	if p := idx.Owner(path); p != nil {
+		return p.Name, nil
 	}
-	return strings.TrimSpace(outbuf.String()), nil
+	return "", nil
 }
 
 // rpmDBPath tries to guess the location of the rpmdb inside a given root.
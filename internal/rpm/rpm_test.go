@@ -0,0 +1,402 @@
+package rpm
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/openshift/check-payload/internal/rpm/rpmtest"
+)
+
+// OpenPGP signature packets, with only the fields needed to find the key ID.
+var (
+	pgpSigV3 = []byte{
+		0x89, 0x00, 0x13, // Old format, 2 byte length.
+		3, 5, 0, 0x5e, 0x8f, 0x00, 0x00, // Version, hashed length, type, time.
+		0x19, 0x9e, 0x2f, 0x91, 0xfd, 0x43, 0x1d, 0x51, // Key ID.
+		1, 8, 0xab, 0xcd, // Algorithms and hash prefix.
+	}
+	pgpSigV4 = []byte{
+		0xc2, 0x18, // New format.
+		4, 0, 1, 8, // Version, type, algorithms.
+		0, 6, 5, 2, 0x5e, 0x8f, 0x00, 0x00, // Hashed: creation time.
+		0, 10, 9, 16, 0x19, 0x9e, 0x2f, 0x91, 0xfd, 0x43, 0x1d, 0x51, // Unhashed: issuer.
+	}
+	pgpSigV4Fingerprint = []byte{
+		0xc2, 0x1f, // New format.
+		4, 0, 1, 8, // Version, type, algorithms.
+		0, 23, 22, 33, 4, // Hashed: issuer fingerprint.
+		0x56, 0x7e, 0x34, 0x7a, 0xd0, 0x04, 0x4a, 0xde, 0x55, 0xba,
+		0x8a, 0x5f, 0x19, 0x9e, 0x2f, 0x91, 0xfd, 0x43, 0x1d, 0x51,
+		0, 0,
+	}
+)
+
+func TestPgpSigKeyID(t *testing.T) {
+	testCases := []struct {
+		name string
+		sig  []byte
+		want string
+	}{
+		{name: "v3", sig: pgpSigV3, want: "199e2f91fd431d51"},
+		{name: "v4 issuer", sig: pgpSigV4, want: "199e2f91fd431d51"},
+		{name: "v4 issuer fingerprint", sig: pgpSigV4Fingerprint, want: "199e2f91fd431d51"},
+		{name: "truncated", sig: pgpSigV4[:10]},
+		{name: "not a signature", sig: []byte{0x99, 0x00, 0x01, 4}},
+		{name: "empty"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := pgpSigKeyID(tc.sig); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func epoch(e int32) *int32 {
+	return &e
+}
+
+var testPkgs = []rpmtest.Package{
+	{
+		Name: "openssl-libs", Version: "3.0.7", Release: "27.el9", Arch: "x86_64", Epoch: epoch(1),
+		Vendor: "Red Hat, Inc.", SourceRPM: "openssl-3.0.7-27.el9.src.rpm", Signature: pgpSigV4,
+		Files: []rpmtest.File{
+			{Path: "/usr/lib64/libcrypto.so.3"},
+			{Path: "/usr/lib64/libssl.so.3"},
+			{Path: "/usr/lib64/ossl-modules/fips.so"},
+			{Path: "/etc/pki/tls/openssl.cnf"},
+			{Path: "/etc/pki/tls/ct_log_list.cnf"},
+		},
+	},
+	{
+		Name: "bash", Version: "5.1.8", Release: "9.el9", Arch: "x86_64",
+		Files: []rpmtest.File{{Path: "/usr/bin/bash"}, {Path: "/usr/bin/sh"}},
+	},
+	{
+		Name: "gpg-pubkey", Version: "fd431d51", Release: "4ae0493b",
+	},
+}
+
+func TestRPMDB(t *testing.T) {
+	ctx := context.Background()
+	var blobs [][]byte
+	for _, p := range testPkgs {
+		blobs = append(blobs, rpmtest.Header(p))
+	}
+
+	testCases := []struct {
+		name string
+		file string
+		data []byte
+	}{
+		{name: "ndb", file: "Packages.db", data: rpmtest.Ndb(blobs)},
+		{name: "bdb little-endian", file: "Packages", data: rpmtest.Bdb(blobs, binary.LittleEndian)},
+		{name: "bdb big-endian", file: "Packages", data: rpmtest.Bdb(blobs, binary.BigEndian)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			rpmtest.WriteRPMDB(t, root, tc.file, tc.data)
+
+			rpms, err := GetAllRPMs(ctx, root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []Info{
+				{Name: "openssl-libs", NVRA: "openssl-libs-3.0.7-27.el9.x86_64"},
+				{Name: "bash", NVRA: "bash-5.1.8-9.el9.x86_64"},
+				{Name: "gpg-pubkey", NVRA: "gpg-pubkey-fd431d51-4ae0493b"},
+			}
+			if !reflect.DeepEqual(rpms, want) {
+				t.Errorf("GetAllRPMs: got %v, want %v", rpms, want)
+			}
+
+			files, err := GetFilesFromRPM(ctx, root, "bash-5.1.8-9.el9.x86_64")
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(files)
+			if want := []string{"/usr/bin/bash", "/usr/bin/sh"}; !reflect.DeepEqual(files, want) {
+				t.Errorf("GetFilesFromRPM: got %v, want %v", files, want)
+			}
+			if _, err := GetFilesFromRPM(ctx, root, "zsh"); err == nil {
+				t.Error("GetFilesFromRPM: expected an error for a package not installed")
+			}
+
+			for path, want := range map[string]string{
+				"/usr/lib64/ossl-modules/fips.so": "openssl-libs",
+				"usr/bin/sh":                      "bash",
+				"/usr/bin/../bin/bash":            "bash",
+				"/usr/bin/zsh":                    "",
+				"/usr/lib64":                      "",
+			} {
+				got, err := NameFromFile(ctx, root, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("NameFromFile(%q): got %q, want %q", path, got, want)
+				}
+			}
+
+			idx, err := IndexOf(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := idx.Owner("/etc/pki/tls/openssl.cnf")
+			if p == nil {
+				t.Fatal("Owner: no package found")
+			}
+			if p.NEVRA() != "openssl-libs-1:3.0.7-27.el9.x86_64" || p.Vendor != "Red Hat, Inc." ||
+				p.SourceRPM != "openssl-3.0.7-27.el9.src.rpm" || p.SigningKeyID != "199e2f91fd431d51" {
+				t.Errorf("Owner: got %+v", p)
+			}
+			if p := idx.Package("bash"); p == nil || p.NEVRA() != "bash-5.1.8-9.el9.x86_64" || p.SigningKeyID != "" {
+				t.Errorf("Package: got %+v", p)
+			}
+
+			if v, err := VersionOf(ctx, root, "openssl-libs"); err != nil || v != "3.0.7" {
+				t.Errorf("VersionOf: got %q, %v, want 3.0.7", v, err)
+			}
+			if _, err := VersionOf(ctx, root, "zsh"); err == nil {
+				t.Error("VersionOf: expected an error for a package not installed")
+			}
+		})
+	}
+
+	t.Run("no rpmdb", func(t *testing.T) {
+		if _, err := GetAllRPMs(ctx, t.TempDir()); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("cache invalidation", func(t *testing.T) {
+		root := t.TempDir()
+		rpmtest.WriteRPMDB(t, root, "Packages.db", rpmtest.Ndb(blobs[1:2]))
+		if rpms, err := GetAllRPMs(ctx, root); err != nil || len(rpms) != 1 {
+			t.Fatalf("got %v, %v, want 1 rpm", rpms, err)
+		}
+		rpmtest.WriteRPMDB(t, root, "Packages.db", rpmtest.Ndb(blobs))
+		if rpms, err := GetAllRPMs(ctx, root); err != nil || len(rpms) != len(blobs) {
+			t.Fatalf("got %v, %v, want %d rpms", rpms, err, len(blobs))
+		}
+	})
+}
+
+// sqliteFixtureBlob returns the blob of row i of the rpmdb.sqlite fixture,
+// see test/resources/build_rpmdb_sqlite_fixture.sh.
+func sqliteFixtureBlob(i int) []byte {
+	b := make([]byte, i*31%2500)
+	for j := range b {
+		b[j] = byte(i + j)
+	}
+	return b
+}
+
+func TestReadSqlitePackages(t *testing.T) {
+	blobs, err := readSqlitePackages("../../test/resources/rpmdb.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 100 {
+		t.Fatalf("got %d blobs, want 100", len(blobs))
+	}
+	for i, blob := range blobs {
+		if want := sqliteFixtureBlob(i + 1); !bytes.Equal(blob, want) {
+			t.Errorf("blob %d: got %d bytes, want %d bytes", i+1, len(blob), len(want))
+		}
+	}
+
+	if _, err := readSqlitePackages("../../test/resources/libcrypto.so"); err == nil {
+		t.Error("expected an error for a non-sqlite file")
+	}
+}
+
+// TestRealRPMDBs reads rpmdb files written by rpm itself, see
+// test/resources/fetch_rpmdb_bdb_ndb_fixtures.sh.
+func TestRealRPMDBs(t *testing.T) {
+	testCases := []struct {
+		file    string
+		fixture string
+		pkgs    int
+		name    string
+		nevra   string
+		vendor  string
+		keyID   string
+		owned   string
+	}{
+		{
+			file:    "Packages",
+			fixture: "../../test/resources/rpmdb_bdb/Packages",
+			pkgs:    1,
+			name:    "libuuid",
+			nevra:   "libuuid-2.32.1-42.el8_8.x86_64",
+			vendor:  "Red Hat, Inc.",
+			keyID:   "199e2f91fd431d51",
+			owned:   "/usr/lib64/libuuid.so.1",
+		},
+		{
+			file:    "Packages.db",
+			fixture: "../../test/resources/rpmdb_ndb/Packages.db",
+			pkgs:    35,
+			name:    "bash",
+			nevra:   "bash-4.4-19.6.1.x86_64",
+			vendor:  "SUSE LLC <https://www.suse.com/>",
+			keyID:   "70af9e8139db7c82",
+			owned:   "/bin/bash",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.file, func(t *testing.T) {
+			data, err := os.ReadFile(tc.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			root := t.TempDir()
+			rpmtest.WriteRPMDB(t, root, tc.file, data)
+			defer ForgetIndex(root)
+
+			idx, err := IndexOf(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := len(idx.Packages()); n != tc.pkgs {
+				t.Errorf("got %d packages, want %d", n, tc.pkgs)
+			}
+			p := idx.Package(tc.name)
+			if p == nil {
+				t.Fatalf("package %s not found", tc.name)
+			}
+			if p.NEVRA() != tc.nevra || p.Vendor != tc.vendor || p.SigningKeyID != tc.keyID {
+				t.Errorf("got %s, vendor %q, key %s; want %s, vendor %q, key %s", p.NEVRA(), p.Vendor, p.SigningKeyID, tc.nevra, tc.vendor, tc.keyID)
+			}
+			if o := idx.Owner(tc.owned); o == nil || o.Name != tc.name {
+				t.Errorf("Owner(%s): got %v, want %s", tc.owned, o, tc.name)
+			}
+		})
+	}
+}
+
+func TestParseSqliteRecordMalformed(t *testing.T) {
+	for _, payload := range [][]byte{
+		{},           // No header length.
+		{0x00},       // Header length shorter than its varint.
+		{0x80, 0x01}, // Header length shorter than its varint.
+		{0x05, 0x01}, // Header length past the payload.
+	} {
+		if _, err := parseSqliteRecord(payload); !errors.Is(err, errBadSqlite) {
+			t.Errorf("%x: got %v, want errBadSqlite", payload, err)
+		}
+	}
+}
+
+func TestWalkTableLoop(t *testing.T) {
+	// Page 2 is an interior page whose cells, and right-most pointer, all
+	// point back at itself.
+	const pageSize = 512
+	const ncells = 50
+	data := make([]byte, 2*pageSize)
+	p := data[pageSize:]
+	p[0] = sqlitePageInteriorTable
+	binary.BigEndian.PutUint16(p[3:], ncells)
+	binary.BigEndian.PutUint32(p[8:], 2)
+	for i := 0; i < ncells; i++ {
+		cell := 12 + 2*ncells + 4*i
+		binary.BigEndian.PutUint16(p[12+2*i:], uint16(cell))
+		binary.BigEndian.PutUint32(p[cell:], 2)
+	}
+	db := &sqliteDB{r: bytes.NewReader(data), pageSize: pageSize, usable: pageSize}
+	err := db.walkTable(2, func(int64, []any) error {
+		t.Fatal("unexpected row")
+		return nil
+	})
+	if !errors.Is(err, errBadSqlite) {
+		t.Errorf("got %v, want errBadSqlite", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const content = "fips provider"
+	sum := sha256.Sum256([]byte(content))
+	want := File{
+		Path:       "/usr/lib64/ossl-modules/fips.so",
+		Size:       int64(len(content)),
+		Mode:       modeRegular | 0o755,
+		Digest:     hex.EncodeToString(sum[:]),
+		DigestAlgo: crypto.SHA256,
+	}
+
+	testCases := []struct {
+		name    string
+		content string
+		mode    os.FileMode
+		link    string
+		wantErr string
+	}{
+		{name: "ok", content: content, mode: 0o755},
+		{name: "modified", content: "fips provider!", mode: 0o755, wantErr: "size differs (14, expected 13), SHA-256 digest differs"},
+		{name: "same size", content: "FIPS provider", mode: 0o755, wantErr: "SHA-256 digest differs"},
+		{name: "mode", content: content, mode: 0o777, wantErr: "mode differs (777, expected 755)"},
+		{name: "replaced by symlink", link: "/tmp/fips.so", wantErr: "file type differs"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			file := filepath.Join(root, want.Path)
+			if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if tc.link != "" {
+				if err := os.Symlink(tc.link, file); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				if err := os.WriteFile(file, []byte(tc.content), tc.mode); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(file, tc.mode); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := want.Verify(root)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+
+	t.Run("from rpmdb", func(t *testing.T) {
+		root := t.TempDir()
+		rpmtest.Install(t, root, rpmtest.Package{
+			Name: "openssl-fips-provider", Version: "3.0.7", Release: "2.el9", Arch: "x86_64",
+			DigestAlgo: 8,
+			Files:      []rpmtest.File{{Path: want.Path, Size: want.Size, Mode: uint16(want.Mode), Digest: want.Digest}},
+		})
+		idx, err := IndexOf(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, ok := idx.Package("openssl-fips-provider").File("usr/lib64/ossl-modules/fips.so")
+		if !ok || !reflect.DeepEqual(f, want) {
+			t.Errorf("got %+v, %v, want %+v", f, ok, want)
+		}
+	})
+}
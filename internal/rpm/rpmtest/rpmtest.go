@@ -0,0 +1,270 @@
+// Package rpmtest builds rpm databases for tests.
+package rpmtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+// File is a file of a test package.
+type File struct {
+	Path   string
+	Size   int64
+	Mode   uint16 // As in stat(2) st_mode.
+	Digest string // In hex.
+	LinkTo string
+}
+
+// Package is a test package.
+type Package struct {
+	Name, Version, Release, Arch string
+	Epoch                        *int32
+	Vendor, SourceRPM            string
+	Signature                    []byte // OpenPGP signature packet.
+	DigestAlgo                   int32  // Default (MD5) if 0.
+	Files                        []File
+}
+
+// Header tags and types, see rpmtag.h.
+const (
+	tagRSAHeader      = 268
+	tagName           = 1000
+	tagVersion        = 1001
+	tagRelease        = 1002
+	tagEpoch          = 1003
+	tagVendor         = 1011
+	tagArch           = 1022
+	tagFileModes      = 1030
+	tagFileDigests    = 1035
+	tagFileLinkTos    = 1036
+	tagSourceRPM      = 1044
+	tagDirIndexes     = 1116
+	tagBaseNames      = 1117
+	tagDirNames       = 1118
+	tagLongFileSizes  = 5008
+	tagFileDigestAlgo = 5011
+
+	typeInt16       = 3
+	typeInt32       = 4
+	typeInt64       = 5
+	typeString      = 6
+	typeBin         = 7
+	typeStringArray = 8
+)
+
+// Signature returns a minimal OpenPGP v4 signature packet made by the key
+// with the given hex key ID.
+func Signature(keyID string) []byte {
+	id, err := hex.DecodeString(keyID)
+	if err != nil || len(id) != 8 {
+		panic("bad key ID " + keyID)
+	}
+	body := []byte{4, 0, 1, 8, 0, 0, 0, 10, 9, 16} // No hashed subpackets, then issuer.
+	body = append(body, id...)
+	return append([]byte{0xc2, byte(len(body))}, body...)
+}
+
+// Header returns the rpmdb header blob of p.
+func Header(p Package) []byte {
+	type entry struct {
+		tag, typ uint32
+		count    int
+		data     []byte
+	}
+	be := binary.BigEndian
+	str := func(ss ...string) []byte {
+		var b []byte
+		for _, s := range ss {
+			b = append(append(b, s...), 0)
+		}
+		return b
+	}
+	entries := []entry{
+		{tagName, typeString, 1, str(p.Name)},
+		{tagVersion, typeString, 1, str(p.Version)},
+		{tagRelease, typeString, 1, str(p.Release)},
+		{tagArch, typeString, 1, str(p.Arch)},
+	}
+	if p.Vendor != "" {
+		entries = append(entries, entry{tagVendor, typeString, 1, str(p.Vendor)})
+	}
+	if p.SourceRPM != "" {
+		entries = append(entries, entry{tagSourceRPM, typeString, 1, str(p.SourceRPM)})
+	}
+	if p.Signature != nil {
+		entries = append(entries, entry{tagRSAHeader, typeBin, len(p.Signature), p.Signature})
+	}
+	if p.Epoch != nil {
+		entries = append(entries, entry{tagEpoch, typeInt32, 1, be.AppendUint32(nil, uint32(*p.Epoch))})
+	}
+	if p.DigestAlgo != 0 {
+		entries = append(entries, entry{tagFileDigestAlgo, typeInt32, 1, be.AppendUint32(nil, uint32(p.DigestAlgo))})
+	}
+	if len(p.Files) > 0 {
+		var dirs, bases, digests, links []string
+		var idx, sizes, modes []byte
+		dirIdx := make(map[string]int)
+		for _, f := range p.Files {
+			dir := path.Dir(f.Path) + "/"
+			if _, ok := dirIdx[dir]; !ok {
+				dirIdx[dir] = len(dirs)
+				dirs = append(dirs, dir)
+			}
+			idx = be.AppendUint32(idx, uint32(dirIdx[dir]))
+			bases = append(bases, path.Base(f.Path))
+			sizes = be.AppendUint64(sizes, uint64(f.Size))
+			modes = be.AppendUint16(modes, f.Mode)
+			digests = append(digests, f.Digest)
+			links = append(links, f.LinkTo)
+		}
+		n := len(p.Files)
+		entries = append(entries,
+			entry{tagDirIndexes, typeInt32, n, idx},
+			entry{tagBaseNames, typeStringArray, n, str(bases...)},
+			entry{tagDirNames, typeStringArray, len(dirs), str(dirs...)},
+			entry{tagLongFileSizes, typeInt64, n, sizes},
+			entry{tagFileModes, typeInt16, n, modes},
+			entry{tagFileDigests, typeStringArray, n, str(digests...)},
+			entry{tagFileLinkTos, typeStringArray, n, str(links...)},
+		)
+	}
+
+	var index, data []byte
+	for _, e := range entries {
+		align := map[uint32]int{typeInt16: 2, typeInt32: 4, typeInt64: 8}[e.typ]
+		for align > 0 && len(data)%align != 0 {
+			data = append(data, 0)
+		}
+		index = be.AppendUint32(index, e.tag)
+		index = be.AppendUint32(index, e.typ)
+		index = be.AppendUint32(index, uint32(len(data)))
+		index = be.AppendUint32(index, uint32(e.count))
+		data = append(data, e.data...)
+	}
+	blob := be.AppendUint32(nil, uint32(len(entries)))
+	blob = be.AppendUint32(blob, uint32(len(data)))
+	return append(append(blob, index...), data...)
+}
+
+// Ndb returns a Packages.db (ndb) file with the given header blobs.
+func Ndb(blobs [][]byte) []byte {
+	const (
+		pageSize  = 4096
+		slotStart = 32
+		slotSize  = 16
+		blkSize   = 16
+	)
+	le := binary.LittleEndian
+	db := make([]byte, pageSize)
+	copy(db, "RpmP")
+	le.PutUint32(db[12:], 1) // Slot pages.
+	for i := slotStart; i < pageSize; i += slotSize {
+		copy(db[i:], "Slot")
+	}
+	for i, blob := range blobs {
+		slot := db[slotStart+i*slotSize:]
+		le.PutUint32(slot[4:], uint32(i+1))
+		le.PutUint32(slot[8:], uint32(len(db)/blkSize))
+		hdr := []byte("BlbS")
+		hdr = le.AppendUint32(hdr, uint32(i+1))
+		hdr = le.AppendUint32(hdr, 1)
+		hdr = le.AppendUint32(hdr, uint32(len(blob)))
+		db = append(append(db, hdr...), blob...)
+		for len(db)%blkSize != 0 {
+			db = append(db, 0)
+		}
+	}
+	return db
+}
+
+// Bdb returns a Berkeley DB hash Packages file with the given header
+// blobs, in the given byte order. Blobs which do not fit on the hash page
+// are stored in overflow pages.
+func Bdb(blobs [][]byte, bo binary.ByteOrder) []byte {
+	const (
+		pageSize    = 512
+		pageHdrSize = 26
+		keyData     = 1
+		offPage     = 3
+	)
+	newPage := func(typ byte) []byte {
+		p := make([]byte, pageSize)
+		p[25] = typ
+		return p
+	}
+	meta := newPage(8)
+	bo.PutUint32(meta[12:], 0x061561)
+	bo.PutUint32(meta[20:], pageSize)
+	hash := newPage(13)
+	pages := [][]byte{meta, hash}
+
+	top := pageSize
+	var inp []uint16
+	add := func(item []byte) {
+		top -= len(item)
+		copy(hash[top:], item)
+		inp = append(inp, uint16(top))
+	}
+	// As rpm does, keep the next header instance number under key 0.
+	next := []byte{keyData, 0, 0, 0, 0}
+	bo.PutUint32(next[1:], uint32(len(blobs)+1))
+	add([]byte{keyData, 0, 0, 0, 0})
+	add(next)
+	for i, blob := range blobs {
+		key := []byte{keyData, 0, 0, 0, 0}
+		bo.PutUint32(key[1:], uint32(i+1))
+		add(key)
+		if len(blob) < 128 {
+			add(append([]byte{keyData}, blob...))
+			continue
+		}
+		item := make([]byte, 12)
+		item[0] = offPage
+		bo.PutUint32(item[4:], uint32(len(pages)))
+		bo.PutUint32(item[8:], uint32(len(blob)))
+		add(item)
+		for len(blob) > 0 {
+			p := newPage(7)
+			n := copy(p[pageHdrSize:], blob)
+			bo.PutUint16(p[22:], uint16(n))
+			blob = blob[n:]
+			if len(blob) > 0 {
+				bo.PutUint32(p[16:], uint32(len(pages)+1))
+			}
+			pages = append(pages, p)
+		}
+	}
+	bo.PutUint16(hash[20:], uint16(len(inp)))
+	for i, off := range inp {
+		bo.PutUint16(hash[pageHdrSize+2*i:], off)
+	}
+	return bytes.Join(pages, nil)
+}
+
+// WriteRPMDB writes the rpmdb file of the given name and content under
+// root.
+func WriteRPMDB(t testing.TB, root, file string, data []byte) {
+	t.Helper()
+	dir := filepath.Join(root, "var/lib/rpm")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, file), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Install writes an ndb rpmdb with the given packages under root.
+func Install(t testing.TB, root string, pkgs ...Package) {
+	t.Helper()
+	var blobs [][]byte
+	for _, p := range pkgs {
+		blobs = append(blobs, Header(p))
+	}
+	WriteRPMDB(t, root, "Packages.db", Ndb(blobs))
+}
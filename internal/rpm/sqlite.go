@@ -0,0 +1,375 @@
+package rpm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A minimal read-only reader of SQLite database files, just enough to read
+// all rows of the rpmdb Packages table. See
+// https://www.sqlite.org/fileformat.html for the file format.
+
+const (
+	sqliteMagic        = "SQLite format 3\x00"
+	sqliteHeaderSize   = 100
+	sqliteWALHeader    = 32
+	sqliteWALFrameHead = 24
+	sqliteMaxDepth     = 64
+
+	sqlitePageInteriorTable = 0x05
+	sqlitePageLeafTable     = 0x0d
+)
+
+var errBadSqlite = errors.New("malformed sqlite database")
+
+type sqliteDB struct {
+	r        io.ReaderAt
+	pageSize int
+	usable   int
+	// Pages from the write-ahead log, which supersede those in the
+	// database file.
+	wal    io.ReaderAt
+	walPgs map[uint32]int64
+}
+
+// readSqlitePackages returns the header blobs from the Packages table of
+// the rpmdb.sqlite database in file.
+func readSqlitePackages(file string) ([][]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hdr := make([]byte, sqliteHeaderSize)
+	if _, err := f.ReadAt(hdr, 0); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if string(hdr[:16]) != sqliteMagic {
+		return nil, fmt.Errorf("%s: %w: bad magic", file, errBadSqlite)
+	}
+	db := &sqliteDB{r: f, pageSize: int(binary.BigEndian.Uint16(hdr[16:]))}
+	if db.pageSize == 1 {
+		db.pageSize = 65536
+	}
+	db.usable = db.pageSize - int(hdr[20])
+	if db.pageSize < 512 || db.usable < 480 {
+		return nil, fmt.Errorf("%s: %w: bad page size", file, errBadSqlite)
+	}
+
+	if wal, err := os.Open(file + "-wal"); err == nil {
+		defer wal.Close()
+		if err := db.readWAL(wal); err != nil {
+			return nil, fmt.Errorf("%s-wal: %w", file, err)
+		}
+	}
+
+	root, err := db.tableRoot("Packages")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	var blobs [][]byte
+	err = db.walkTable(root, func(_ int64, rec []any) error {
+		// Columns are hnum (the rowid alias, stored as NULL), and blob.
+		if len(rec) < 2 {
+			return fmt.Errorf("%w: bad Packages row", errBadSqlite)
+		}
+		if blob, ok := rec[1].([]byte); ok {
+			blobs = append(blobs, blob)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return blobs, nil
+}
+
+// readWAL finds the latest committed version of all pages in the
+// write-ahead log.
+func (db *sqliteDB) readWAL(wal io.ReaderAt) error {
+	hdr := make([]byte, sqliteWALHeader)
+	if _, err := wal.ReadAt(hdr, 0); err != nil {
+		// An empty WAL.
+		return nil
+	}
+	if magic := binary.BigEndian.Uint32(hdr); magic&^1 != 0x377f0682 {
+		return fmt.Errorf("%w: bad WAL magic", errBadSqlite)
+	}
+	if int(binary.BigEndian.Uint32(hdr[8:])) != db.pageSize {
+		return fmt.Errorf("%w: WAL page size mismatch", errBadSqlite)
+	}
+	salt := hdr[16:24]
+
+	db.wal = wal
+	db.walPgs = make(map[uint32]int64)
+	pending := make(map[uint32]int64)
+	fh := make([]byte, sqliteWALFrameHead)
+	for off := int64(sqliteWALHeader); ; off += int64(sqliteWALFrameHead + db.pageSize) {
+		if _, err := wal.ReadAt(fh, off); err != nil {
+			break
+		}
+		if !bytes.Equal(fh[8:16], salt) {
+			// A frame left over from an earlier WAL generation.
+			break
+		}
+		pending[binary.BigEndian.Uint32(fh)] = off + sqliteWALFrameHead
+		if binary.BigEndian.Uint32(fh[4:]) != 0 {
+			// A commit frame.
+			for pg, o := range pending {
+				db.walPgs[pg] = o
+			}
+			clear(pending)
+		}
+	}
+	return nil
+}
+
+func (db *sqliteDB) page(n uint32) ([]byte, error) {
+	if n == 0 {
+		return nil, fmt.Errorf("%w: bad page number", errBadSqlite)
+	}
+	p := make([]byte, db.pageSize)
+	r, off := db.r, int64(n-1)*int64(db.pageSize)
+	if o, ok := db.walPgs[n]; ok {
+		r, off = db.wal, o
+	}
+	if _, err := r.ReadAt(p, off); err != nil {
+		return nil, fmt.Errorf("page %d: %w", n, err)
+	}
+	return p, nil
+}
+
+// tableRoot returns the root page of the named table.
+func (db *sqliteDB) tableRoot(name string) (uint32, error) {
+	var root int64
+	err := db.walkTable(1, func(_ int64, rec []any) error {
+		// Columns are type, name, tbl_name, rootpage, sql.
+		if len(rec) < 4 {
+			return nil
+		}
+		if typ, _ := rec[0].(string); typ != "table" {
+			return nil
+		}
+		if n, _ := rec[1].(string); n == name {
+			root, _ = rec[3].(int64)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if root <= 0 {
+		return 0, fmt.Errorf("table %s not found", name)
+	}
+	return uint32(root), nil
+}
+
+// walkTable calls fn for every row of the table b-tree rooted at page pgno.
+func (db *sqliteDB) walkTable(pgno uint32, fn func(rowid int64, rec []any) error) error {
+	return db.walkPage(pgno, 0, make(map[uint32]bool), fn)
+}
+
+// walkPage walks the b-tree page pgno, at depth in the tree. Every page is
+// only walked once, as a malformed tree may have loops.
+func (db *sqliteDB) walkPage(pgno uint32, depth int, visited map[uint32]bool, fn func(rowid int64, rec []any) error) error {
+	if depth > sqliteMaxDepth {
+		return fmt.Errorf("%w: b-tree too deep", errBadSqlite)
+	}
+	if visited[pgno] {
+		return fmt.Errorf("%w: page %d is in the b-tree twice", errBadSqlite, pgno)
+	}
+	visited[pgno] = true
+	p, err := db.page(pgno)
+	if err != nil {
+		return err
+	}
+	off := 0
+	if pgno == 1 {
+		off = sqliteHeaderSize
+	}
+	if len(p) < off+12 {
+		return fmt.Errorf("%w: short page %d", errBadSqlite, pgno)
+	}
+	typ := p[off]
+	ncells := int(binary.BigEndian.Uint16(p[off+3:]))
+	hdrSize := 8
+	if typ == sqlitePageInteriorTable {
+		hdrSize = 12
+	}
+	if off+hdrSize+2*ncells > len(p) {
+		return fmt.Errorf("%w: bad cell count on page %d", errBadSqlite, pgno)
+	}
+	for i := 0; i < ncells; i++ {
+		cell := int(binary.BigEndian.Uint16(p[off+hdrSize+2*i:]))
+		if cell >= len(p) {
+			return fmt.Errorf("%w: bad cell offset on page %d", errBadSqlite, pgno)
+		}
+		switch typ {
+		case sqlitePageInteriorTable:
+			if cell+4 > len(p) {
+				return fmt.Errorf("%w: bad cell on page %d", errBadSqlite, pgno)
+			}
+			if err := db.walkPage(binary.BigEndian.Uint32(p[cell:]), depth+1, visited, fn); err != nil {
+				return err
+			}
+		case sqlitePageLeafTable:
+			rowid, payload, err := db.leafCell(p, cell)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", pgno, err)
+			}
+			rec, err := parseSqliteRecord(payload)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", pgno, err)
+			}
+			if err := fn(rowid, rec); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: unexpected page type %#x on page %d", errBadSqlite, typ, pgno)
+		}
+	}
+	if typ == sqlitePageInteriorTable {
+		return db.walkPage(binary.BigEndian.Uint32(p[off+8:]), depth+1, visited, fn)
+	}
+	return nil
+}
+
+// leafCell returns the rowid and the full payload of a table leaf cell,
+// following any overflow pages.
+func (db *sqliteDB) leafCell(p []byte, cell int) (int64, []byte, error) {
+	size, n := sqliteVarint(p[cell:])
+	if n == 0 {
+		return 0, nil, errBadSqlite
+	}
+	cell += n
+	rowid, n := sqliteVarint(p[cell:])
+	if n == 0 {
+		return 0, nil, errBadSqlite
+	}
+	cell += n
+
+	u := int64(db.usable)
+	maxLocal := u - 35
+	local := size
+	if size > maxLocal {
+		minLocal := (u-12)*32/255 - 23
+		local = minLocal + (size-minLocal)%(u-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+	if size < 0 || size > maxHeaderData || int64(cell)+local > int64(len(p)) {
+		return 0, nil, fmt.Errorf("%w: bad payload size", errBadSqlite)
+	}
+	payload := make([]byte, 0, size)
+	payload = append(payload, p[cell:cell+int(local)]...)
+	if local == size {
+		return int64(rowid), payload, nil
+	}
+
+	if cell+int(local)+4 > len(p) {
+		return 0, nil, errBadSqlite
+	}
+	next := binary.BigEndian.Uint32(p[cell+int(local):])
+	for int64(len(payload)) < size {
+		if next == 0 {
+			return 0, nil, fmt.Errorf("%w: overflow chain too short", errBadSqlite)
+		}
+		op, err := db.page(next)
+		if err != nil {
+			return 0, nil, err
+		}
+		next = binary.BigEndian.Uint32(op)
+		n := min(int64(db.usable-4), size-int64(len(payload)))
+		payload = append(payload, op[4:4+n]...)
+	}
+	return int64(rowid), payload, nil
+}
+
+// parseSqliteRecord decodes a record into its values, which are nil,
+// int64, float64 (as raw bits), string or []byte.
+func parseSqliteRecord(payload []byte) ([]any, error) {
+	hdrLen, n := sqliteVarint(payload)
+	if n == 0 || hdrLen < int64(n) || hdrLen > int64(len(payload)) {
+		return nil, fmt.Errorf("%w: bad record header", errBadSqlite)
+	}
+	var rec []any
+	hdr, body := payload[n:hdrLen], payload[hdrLen:]
+	for len(hdr) > 0 {
+		st, n := sqliteVarint(hdr)
+		if n == 0 {
+			return nil, fmt.Errorf("%w: bad record header", errBadSqlite)
+		}
+		hdr = hdr[n:]
+
+		var size int64
+		switch {
+		case st == 0 || st == 8 || st == 9:
+		case st <= 4:
+			size = st
+		case st == 5:
+			size = 6
+		case st == 6 || st == 7:
+			size = 8
+		case st >= 12:
+			size = (st - 12) / 2
+		default:
+			return nil, fmt.Errorf("%w: bad serial type %d", errBadSqlite, st)
+		}
+		if size > int64(len(body)) {
+			return nil, fmt.Errorf("%w: record too short", errBadSqlite)
+		}
+		v := body[:size]
+		body = body[size:]
+
+		switch {
+		case st == 0:
+			rec = append(rec, nil)
+		case st == 8:
+			rec = append(rec, int64(0))
+		case st == 9:
+			rec = append(rec, int64(1))
+		case st <= 6:
+			// Big-endian two's complement integer.
+			var i int64
+			if v[0]&0x80 != 0 {
+				i = -1
+			}
+			for _, b := range v {
+				i = i<<8 | int64(b)
+			}
+			rec = append(rec, i)
+		case st == 7:
+			rec = append(rec, binary.BigEndian.Uint64(v))
+		case st%2 == 0:
+			rec = append(rec, v)
+		default:
+			rec = append(rec, string(v))
+		}
+	}
+	return rec, nil
+}
+
+// sqliteVarint decodes a variable-length integer, returning its value and
+// length (0 on error).
+func sqliteVarint(b []byte) (int64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+		if i == 8 {
+			return int64(v<<8 | uint64(b[i])), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return int64(v), i + 1
+		}
+	}
+	return 0, 0
+}
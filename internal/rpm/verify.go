@@ -0,0 +1,126 @@
+package rpm
+
+import (
+	"crypto"
+	_ "crypto/md5" // Register the digests used by rpm.
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Digest algorithms, see rpmpgp.h.
+var digestAlgos = map[int64]crypto.Hash{
+	1:  crypto.MD5,
+	2:  crypto.SHA1,
+	8:  crypto.SHA256,
+	9:  crypto.SHA384,
+	10: crypto.SHA512,
+	11: crypto.SHA224,
+}
+
+// File mode type bits, see stat(2).
+const (
+	modeTypeMask = 0o170000
+	modeRegular  = 0o100000
+	modeSymlink  = 0o120000
+	modeDir      = 0o040000
+)
+
+// File is a file of a package, as recorded in the rpmdb.
+type File struct {
+	Path       string
+	Size       int64
+	Mode       uint32 // As in stat(2) st_mode.
+	Digest     string // In hex; empty for anything but regular files.
+	DigestAlgo crypto.Hash
+	LinkTo     string // Symlink target.
+}
+
+// File returns the rpmdb record of the file, if it belongs to the package.
+func (p *Package) File(file string) (File, bool) {
+	file = path.Clean("/" + file)
+	for _, f := range p.fileAttrs {
+		if f.Path == file {
+			return f, true
+		}
+	}
+	return File{}, false
+}
+
+// Verify checks that the file under root matches its rpmdb record, similar
+// to "rpm -V". It returns an error describing all the differences found.
+func (f *File) Verify(root string) error {
+	name := filepath.Join(root, f.Path)
+	fi, err := os.Lstat(name)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	if typ := f.Mode & modeTypeMask; typ != fileModeType(fi.Mode()) {
+		problems = append(problems, fmt.Sprintf("file type differs (mode %o, expected %o)", fi.Mode(), f.Mode))
+	} else if perm := uint32(fi.Mode().Perm()); perm != f.Mode&0o777 {
+		problems = append(problems, fmt.Sprintf("mode differs (%o, expected %o)", perm, f.Mode&0o777))
+	}
+	switch {
+	case fi.Mode().IsRegular() && f.Mode&modeTypeMask == modeRegular:
+		if fi.Size() != f.Size {
+			problems = append(problems, fmt.Sprintf("size differs (%d, expected %d)", fi.Size(), f.Size))
+		}
+		if f.Digest != "" {
+			digest, err := fileDigest(name, f.DigestAlgo)
+			if err != nil {
+				return err
+			}
+			if digest != f.Digest {
+				problems = append(problems, fmt.Sprintf("%s digest differs (%s, expected %s)", f.DigestAlgo, digest, f.Digest))
+			}
+		}
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(name)
+		if err != nil {
+			return err
+		}
+		if target != f.LinkTo {
+			problems = append(problems, fmt.Sprintf("symlink target differs (%s, expected %s)", target, f.LinkTo))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: %s", f.Path, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func fileModeType(m os.FileMode) uint32 {
+	switch {
+	case m.IsRegular():
+		return modeRegular
+	case m&os.ModeSymlink != 0:
+		return modeSymlink
+	case m.IsDir():
+		return modeDir
+	}
+	return 0
+}
+
+func fileDigest(file string, algo crypto.Hash) (string, error) {
+	if !algo.Available() {
+		return "", fmt.Errorf("unsupported digest algorithm %v", algo)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := algo.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
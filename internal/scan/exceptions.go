@@ -0,0 +1,291 @@
+package scan
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	mapset "github.com/deckarep/golang-set/v2"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+// The files failing with the same error in a directory are replaced by the
+// directory in the generated exceptions if there are at least
+// exceptionDirMinFiles of them, and they are at least exceptionDirMinShare
+// of the files scanned in the directory.
+const (
+	exceptionDirMinFiles = 3
+	exceptionDirMinShare = 0.8
+)
+
+// exceptionPlaceholder is set as the reason and ticket of the generated
+// exceptions, to be filled in by hand.
+const exceptionPlaceholder = "TODO"
+
+// collectExceptions returns the per-prefix map of per-error sets of files
+// which failed, as well as the number of files scanned per directory.
+func collectExceptions(results []*types.ScanResults) (map[string]map[string]mapset.Set[string], map[string]int) {
+	exceptions := make(map[string]map[string]mapset.Set[string])
+	scanned := mapset.NewSet[string]()
+	for _, result := range results {
+		for _, res := range result.Items {
+			if res.Path != "" && !res.Skip {
+				scanned.Add(res.Path)
+			}
+			if res.Error == nil || res.Path == "" {
+				// Skip over successes and errors with no path set.
+				continue
+			}
+			prefix := getFilterPrefix(res)
+			errMap, ok := exceptions[prefix]
+			if !ok {
+				errMap = make(map[string]mapset.Set[string])
+				exceptions[prefix] = errMap
+			}
+
+			errName := types.KnownErrorName(res.Error.Error)
+			if set, ok := errMap[errName]; ok {
+				set.Add(res.Path)
+			} else {
+				errMap[errName] = mapset.NewSet(res.Path)
+			}
+		}
+	}
+
+	perDir := make(map[string]int)
+	for path := range scanned.Iter() {
+		perDir[filepath.Dir(path)]++
+	}
+	return exceptions, perDir
+}
+
+// sortedKeys returns the keys of the map, sorted.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// groupByDir returns the sorted files and dirs to ignore for the files,
+// replacing the files of a directory by the directory according to the
+// exceptionDir* thresholds.
+func groupByDir(files mapset.Set[string], perDir map[string]int) (fileList, dirList []string) {
+	byDir := make(map[string][]string)
+	for f := range files.Iter() {
+		dir := filepath.Dir(f)
+		byDir[dir] = append(byDir[dir], f)
+	}
+	for _, dir := range sortedKeys(byDir) {
+		inDir := byDir[dir]
+		if dir != "/" && len(inDir) >= exceptionDirMinFiles && float64(len(inDir)) >= exceptionDirMinShare*float64(perDir[dir]) {
+			dirList = append(dirList, dir)
+			continue
+		}
+		fileList = append(fileList, inDir...)
+	}
+	sort.Strings(fileList)
+	return fileList, dirList
+}
+
+// generateExceptions returns the config exceptions which would ignore all
+// the errors found.
+func generateExceptions(results []*types.ScanResults) *types.ConfigFile {
+	exceptions, perDir := collectExceptions(results)
+	cfg := &types.ConfigFile{}
+	for _, prefix := range sortedKeys(exceptions) {
+		errMap := exceptions[prefix]
+		var list types.ErrIgnoreList
+		for _, errName := range sortedKeys(errMap) {
+			if errName == "" {
+				klog.Warningf("can't write exceptions for unknown errors in %s", errMap[errName].ToSlice())
+				continue
+			}
+			ie := types.ErrIgnore{
+				Error:  types.KnownError{Err: types.KnownErrors[errName], Str: errName},
+				Waiver: types.Waiver{Reason: exceptionPlaceholder, Ticket: exceptionPlaceholder},
+			}
+			ie.Files, ie.Dirs = groupByDir(errMap[errName], perDir)
+			list = append(list, ie)
+		}
+		if len(list) == 0 {
+			continue
+		}
+
+		section, name, _ := strings.Cut(prefix, ".")
+		var lists *map[string]types.IgnoreLists
+		switch section {
+		case "":
+			cfg.ErrIgnores = list
+			continue
+		case "rpm":
+			lists = &cfg.RPMIgnores
+		case "payload":
+			lists = &cfg.PayloadIgnores
+		case "tag":
+			lists = &cfg.TagIgnores
+		}
+		if *lists == nil {
+			*lists = make(map[string]types.IgnoreLists)
+		}
+		(*lists)[name] = types.IgnoreLists{ErrIgnores: list}
+	}
+	return cfg
+}
+
+// pruneExceptions removes from gen the files and dirs cfg already has for
+// the same error, so that merging gen into cfg does not add them again,
+// with another waiver.
+func pruneExceptions(gen, cfg *types.ConfigFile) {
+	gen.ErrIgnores = pruneErrIgnores(gen.ErrIgnores, cfg.ErrIgnores)
+	for _, l := range []struct{ gen, have map[string]types.IgnoreLists }{
+		{gen.PayloadIgnores, cfg.PayloadIgnores},
+		{gen.TagIgnores, cfg.TagIgnores},
+		{gen.RPMIgnores, cfg.RPMIgnores},
+	} {
+		for k, v := range l.gen {
+			v.ErrIgnores = pruneErrIgnores(v.ErrIgnores, l.have[k].ErrIgnores)
+			if len(v.ErrIgnores) == 0 {
+				delete(l.gen, k)
+				continue
+			}
+			l.gen[k] = v
+		}
+	}
+}
+
+func pruneErrIgnores(gen, have types.ErrIgnoreList) types.ErrIgnoreList {
+	var res types.ErrIgnoreList
+	for _, ie := range gen {
+		known := mapset.NewSet[string]()
+		for _, h := range have {
+			if h.Error.Str == ie.Error.Str {
+				known.Append(h.Files...)
+				known.Append(h.Dirs...)
+			}
+		}
+		isKnown := func(path string) bool { return known.Contains(path) }
+		ie.Files = slices.DeleteFunc(ie.Files, isKnown)
+		ie.Dirs = slices.DeleteFunc(ie.Dirs, isKnown)
+		if len(ie.Files)+len(ie.Dirs) > 0 {
+			res = append(res, ie)
+		}
+	}
+	return res
+}
+
+// hasExceptions tells if c has any ignore entries.
+func hasExceptions(c *types.ConfigFile) bool {
+	return len(c.ErrIgnores)+len(c.PayloadIgnores)+len(c.TagIgnores)+len(c.RPMIgnores) > 0
+}
+
+// appendTOMLExceptions returns the toml text data with the exceptions of
+// gen appended as [[...ignore]] blocks, sorted, leaving the existing text,
+// with its comments and formatting, as is.
+func appendTOMLExceptions(data []byte, gen *types.ConfigFile) ([]byte, error) {
+	buf := bytes.NewBuffer(data)
+	add := func(key toml.Key, list types.ErrIgnoreList) error {
+		for _, ie := range list {
+			if buf.Len() > 0 {
+				if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+					buf.WriteByte('\n')
+				}
+				buf.WriteByte('\n')
+			}
+			fmt.Fprintf(buf, "[[%s]]\n", key)
+			enc := toml.NewEncoder(buf)
+			enc.Indent = ""
+			if err := enc.Encode(ie); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := add(toml.Key{"ignore"}, gen.ErrIgnores); err != nil {
+		return nil, err
+	}
+	for _, l := range []struct {
+		section string
+		lists   map[string]types.IgnoreLists
+	}{
+		{"payload", gen.PayloadIgnores},
+		{"rpm", gen.RPMIgnores},
+		{"tag", gen.TagIgnores},
+	} {
+		for _, name := range sortedKeys(l.lists) {
+			if err := add(toml.Key{l.section, name, "ignore"}, l.lists[name].ErrIgnores); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteExceptions writes the exceptions which would ignore all the errors
+// found to file, sorted. A new file is written as toml, or json or yaml
+// depending on its extension (see types.EncodeConfigFile). To an existing
+// toml file, only the exceptions it does not have yet are appended, as
+// [[...ignore]] blocks; existing json and yaml files are not modified. The
+// result is validated before writing, and nothing is written if there are
+// no new exceptions.
+func WriteExceptions(file string, results []*types.ScanResults) error {
+	gen := generateExceptions(results)
+	data, err := os.ReadFile(file)
+	exists := err == nil
+	switch {
+	case exists:
+		cfg, err := types.DecodeConfigFile(file, data)
+		if err != nil {
+			return fmt.Errorf("can't parse %s: %w", file, err)
+		}
+		pruneExceptions(gen, cfg)
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	if !hasExceptions(gen) {
+		klog.Infof("no new exceptions to write to %s", file)
+		return nil
+	}
+
+	switch {
+	case !exists:
+		data, err = types.EncodeConfigFile(file, gen)
+	case types.ConfigFormat(file, data) == types.FormatTOML:
+		data, err = appendTOMLExceptions(data, gen)
+	default:
+		return fmt.Errorf("can't add exceptions to %s: only toml files can be appended to, use a new file", file)
+	}
+	if err != nil {
+		return err
+	}
+
+	cfg, err := types.DecodeConfigFile(file, data)
+	if err != nil {
+		return fmt.Errorf("exceptions to be written to %s are invalid: %w", file, err)
+	}
+	err, warn := cfg.Validate()
+	if warn != nil {
+		klog.Warning(warn)
+	}
+	if err != nil {
+		return fmt.Errorf("exceptions to be written to %s are invalid: %w", file, err)
+	}
+
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		return err
+	}
+	klog.Infof("exceptions written to %s", file)
+	return nil
+}
@@ -2,6 +2,7 @@ package scan
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -23,19 +24,21 @@ func RunNodeScan(ctx context.Context, cfg *types.Config, root string) []*types.S
 
 func rpmRootScan(ctx context.Context, cfg *types.Config, root string) *types.ScanResults {
 	results := types.NewScanResults()
-	rpms, err := rpm.GetAllRPMs(ctx, root)
+	idx, err := rpm.IndexOf(root)
 	if err != nil {
 		results.Append(types.NewScanResult().SetError(err))
 		return results
 	}
-	for _, pkg := range rpms {
-		files, err := rpm.GetFilesFromRPM(ctx, root, pkg.NVRA)
-		if err != nil {
-			res := types.NewScanResult().SetRPM(pkg.Name).SetError(err)
-			results.Append(res)
-			continue
-		}
-		for _, innerPath := range files {
+	defer rpm.ForgetIndex(root)
+	osInfo := validations.DetectOS(root)
+	ctx = cfg.WithRules(ctx, nil, nil, osInfo.String())
+	ctx = cfg.WithExceptions(ctx)
+	if len(idx.Packages()) == 0 {
+		results.Append(types.NewScanResult().SetError(fmt.Errorf("no rpms found under %q", root)))
+		return results
+	}
+	for _, pkg := range idx.Packages() {
+		for _, innerPath := range pkg.Files {
 			if cfg.IgnoreFile(innerPath) || cfg.IgnoreDirPrefix(innerPath) || cfg.IgnoreFileByRpm(innerPath, pkg.Name) {
 				continue
 			}
@@ -69,5 +72,9 @@ func rpmRootScan(ctx context.Context, cfg *types.Config, root string) *types.Sca
 			results.Append(res)
 		}
 	}
+	// The rules may depend on the OS of the results.
+	results.SetOSName(osInfo.String())
+	cfg.ApplySeverity(results)
+	cfg.ApplyRules(results)
 	return results
 }
@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strings"
 
-	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"k8s.io/klog/v2"
 
@@ -19,9 +19,22 @@ const (
 	colTitleExeName      = "Executable Name"
 	colTitlePassedFailed = "Status"
 	colTitleImage        = "Image"
+	colTitleOS           = "OS"
+	colTitleSection      = "Section"
+	colTitleEntry        = "Entry"
+	colTitleTicket       = "Ticket"
+	colTitleReason       = "Reason"
+	colTitleExpires      = "Expires"
+	colTitleMatched      = "Matched"
+	colTitleSource       = "Source"
 )
 
 func PrintResults(cfg *types.Config, results []*types.ScanResults) {
+	if isStructuredFormat(cfg.OutputFormat) {
+		printStructuredReport(cfg, results)
+		return
+	}
+
 	var failureReport, warningReport, successReport string
 
 	var combinedReport string
@@ -43,6 +56,13 @@ func PrintResults(cfg *types.Config, results []*types.ScanResults) {
 		combinedReport = failureReport
 	}
 
+	if cfg.OutputFormat == "html" && (isFailed || isWarnings) {
+		remediationReport := renderRemediation(results)
+		fmt.Println("---- Remediation Report")
+		fmt.Println(remediationReport)
+		combinedReport += "\n\n ---- Remediation Report\n" + remediationReport
+	}
+
 	if cfg.Verbose {
 		fmt.Println("---- Success Report")
 		fmt.Println(successReport)
@@ -59,6 +79,20 @@ func PrintResults(cfg *types.Config, results []*types.ScanResults) {
 		fmt.Println("---- Successful run")
 	}
 
+	if cfg.ReportWaivers {
+		waiverReport := renderExceptions(cfg, cfg.AppliedWaivers())
+		fmt.Println("---- Waivers Report")
+		fmt.Println(waiverReport)
+		combinedReport += "\n\n ---- Waivers Report\n" + waiverReport
+	}
+
+	if cfg.ReportUnusedExceptions {
+		unusedReport := renderExceptions(cfg, cfg.UnusedExceptions())
+		fmt.Println("---- Unused Exceptions Report")
+		fmt.Println(unusedReport)
+		combinedReport += "\n\n ---- Unused Exceptions Report\n" + unusedReport
+	}
+
 	if cfg.OutputFile != "" {
 		if err := os.WriteFile(cfg.OutputFile, []byte(combinedReport), 0o777); err != nil {
 			klog.Errorf("could not write file: %v", err)
@@ -85,31 +119,12 @@ func getFilterPrefix(res *types.ScanResult) string {
 
 func displayExceptions(results []*types.ScanResults) {
 	// Per-prefix map of per-error map of files to be excluded.
-	exceptions := make(map[string]map[string]mapset.Set[string])
-	for _, result := range results {
-		for _, res := range result.Items {
-			if res.Error == nil || res.Path == "" {
-				// Skip over successes and errors with no path set.
-				continue
-			}
-			prefix := getFilterPrefix(res)
-			errMap, ok := exceptions[prefix]
-			if !ok {
-				errMap = make(map[string]mapset.Set[string])
-				exceptions[prefix] = errMap
-			}
-
-			errName := types.KnownErrorName(res.Error.Error)
-			if set, ok := errMap[errName]; ok {
-				set.Add(res.Path)
-			} else {
-				errMap[errName] = mapset.NewSet(res.Path)
-			}
-		}
-	}
+	exceptions, _ := collectExceptions(results)
 
-	for prefix, errMap := range exceptions {
-		for errName, set := range errMap {
+	for _, prefix := range sortedKeys(exceptions) {
+		errMap := exceptions[prefix]
+		for _, errName := range sortedKeys(errMap) {
+			set := errMap[errName]
 			if prefix != "" {
 				fmt.Printf("[[%s.ignore]]\n", prefix)
 			} else {
@@ -185,11 +200,30 @@ func getImage(res *types.ScanResult) string {
 	return ""
 }
 
+// getRPM returns the full NVRA of the rpm the result belongs to, if known,
+// or just its name.
+func getRPM(res *types.ScanResult) string {
+	if res.NVRA != "" {
+		return res.NVRA
+	}
+	return res.RPM
+}
+
+// getError returns the error of the result, noting the waiver which no
+// longer applies to it, if any.
+func getError(res *types.ScanResult) string {
+	msg := res.Error.GetError().Error()
+	if w := res.ExpiredWaiver; w != nil {
+		msg += " (waiver " + w.Describe() + ")"
+	}
+	return msg
+}
+
 func renderReport(results []*types.ScanResults) (failures table.Writer, warnings table.Writer, successes table.Writer) {
 	var failureTableRows, warningTableRows, successTableRows []table.Row
 
-	failureRowHeader := table.Row{colTitleOperatorName, colTitleTagName, colTitleRPMName, colTitleExeName, colTitlePassedFailed, colTitleImage}
-	successRowHeader := table.Row{colTitleOperatorName, colTitleTagName, colTitleExeName, colTitleImage}
+	failureRowHeader := table.Row{colTitleOperatorName, colTitleTagName, colTitleRPMName, colTitleExeName, colTitlePassedFailed, colTitleImage, colTitleOS}
+	successRowHeader := table.Row{colTitleOperatorName, colTitleTagName, colTitleExeName, colTitleImage, colTitleOS}
 
 	for _, result := range results {
 		for _, res := range result.Items {
@@ -198,11 +232,11 @@ func renderReport(results []*types.ScanResults) (failures table.Writer, warnings
 			image := getImage(res)
 
 			if res.IsLevel(types.Error) {
-				failureTableRows = append(failureTableRows, table.Row{component, tag, res.RPM, res.Path, res.Error.GetError(), image})
+				failureTableRows = append(failureTableRows, table.Row{component, tag, getRPM(res), res.Path, getError(res), image, res.OS})
 			} else if res.IsLevel(types.Warning) {
-				warningTableRows = append(warningTableRows, table.Row{component, tag, res.RPM, res.Path, res.Error.GetError(), image})
+				warningTableRows = append(warningTableRows, table.Row{component, tag, getRPM(res), res.Path, getError(res), image, res.OS})
 			} else {
-				successTableRows = append(successTableRows, table.Row{component, tag, res.Path, image})
+				successTableRows = append(successTableRows, table.Row{component, tag, res.Path, image, res.OS})
 			}
 		}
 	}
@@ -226,3 +260,25 @@ func renderReport(results []*types.ScanResults) (failures table.Writer, warnings
 	stw.SetIndexColumn(1)
 	return ftw, wtw, stw
 }
+
+// renderExceptions renders config exceptions, with their ticket, in the
+// output format of the report.
+func renderExceptions(cfg *types.Config, uses []types.ExceptionUse) string {
+	tw := table.NewWriter()
+	tw.SuppressEmptyColumns()
+	tw.AppendHeader(table.Row{colTitleSection, colTitleEntry, colTitleTicket, colTitleReason, colTitleExpires, colTitlePassedFailed, colTitleMatched, colTitleSource})
+	for _, u := range uses {
+		tw.AppendRow(table.Row{u.Section, u.Entry, u.Ticket, u.Reason, u.Expires.String(), u.Status(), strings.Join(u.Paths, "\n"), u.Source})
+	}
+	tw.SetIndexColumn(1)
+
+	switch cfg.OutputFormat {
+	case "csv":
+		return tw.RenderCSV()
+	case "markdown":
+		return tw.RenderMarkdown()
+	case "html":
+		return tw.RenderHTML()
+	}
+	return tw.Render()
+}
@@ -0,0 +1,278 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+const (
+	colTitleError       = "Error"
+	colTitleDescription = "Description"
+	colTitleRemediation = "Remediation"
+	colTitleValidator   = "Reported By"
+	colTitleGoVersions  = "Go Versions"
+
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	toolURI      = "https://github.com/openshift/check-payload"
+)
+
+// isStructuredFormat tells if the report is a json or sarif document,
+// rather than tables.
+func isStructuredFormat(format string) bool {
+	return format == "json" || format == "sarif"
+}
+
+// reportResult is a scan result in the json report.
+type reportResult struct {
+	Status    string `json:"status"` // "failed", "warning" or "success".
+	Component string `json:"component,omitempty"`
+	Tag       string `json:"tag,omitempty"`
+	Image     string `json:"image,omitempty"`
+	RPM       string `json:"rpm,omitempty"`
+	Path      string `json:"path"`
+	OS        string `json:"os,omitempty"`
+	Error     string `json:"error,omitempty"`
+	ErrorName string `json:"error_name,omitempty"`
+}
+
+// jsonReport is the json report: the results, and the explanations of the
+// errors found (see the explain command).
+type jsonReport struct {
+	Status           string               `json:"status"`
+	Results          []reportResult       `json:"results"`
+	Errors           []types.ErrorInfo    `json:"errors"`
+	Waivers          []types.ExceptionUse `json:"waivers,omitempty"`
+	UnusedExceptions []types.ExceptionUse `json:"unused_exceptions,omitempty"`
+}
+
+func resultStatus(res *types.ScanResult) string {
+	switch {
+	case res.IsLevel(types.Error):
+		return "failed"
+	case res.IsLevel(types.Warning):
+		return "warning"
+	}
+	return "success"
+}
+
+// foundErrors returns the explanations of the known errors of the failed
+// results and warnings, sorted by name.
+func foundErrors(results []*types.ScanResults) []types.ErrorInfo {
+	seen := make(map[string]bool)
+	for _, result := range results {
+		for _, res := range result.Items {
+			if res.Error == nil || res.IsSuccess() {
+				continue
+			}
+			if name := types.KnownErrorName(res.Error.Error); name != "" {
+				seen[name] = true
+			}
+		}
+	}
+	infos := make([]types.ErrorInfo, 0, len(seen))
+	for _, name := range sortedKeys(seen) {
+		if info, ok := types.ExplainError(name); ok {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+func renderJSONReport(cfg *types.Config, results []*types.ScanResults) ([]byte, error) {
+	report := jsonReport{Status: "success", Results: []reportResult{}, Errors: foundErrors(results)}
+	if IsFailed(results) {
+		report.Status = "failed"
+	} else if IsWarnings(results) {
+		report.Status = "warning"
+	}
+	for _, result := range results {
+		for _, res := range result.Items {
+			r := reportResult{
+				Status:    resultStatus(res),
+				Component: getComponent(res),
+				Tag:       getTag(res),
+				Image:     getImage(res),
+				RPM:       getRPM(res),
+				Path:      res.Path,
+				OS:        res.OS,
+			}
+			if r.Status == "success" {
+				if !cfg.Verbose {
+					continue
+				}
+			} else {
+				r.Error = getError(res)
+				r.ErrorName = types.KnownErrorName(res.Error.Error)
+			}
+			report.Results = append(report.Results, r)
+		}
+	}
+	if cfg.ReportWaivers {
+		report.Waivers = cfg.AppliedWaivers()
+	}
+	if cfg.ReportUnusedExceptions {
+		report.UnusedExceptions = cfg.UnusedExceptions()
+	}
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// The subset of SARIF 2.1.0 used for the sarif report.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string         `json:"id"`
+		ShortDescription sarifMessage   `json:"shortDescription"`
+		FullDescription  sarifMessage   `json:"fullDescription"`
+		Help             sarifMessage   `json:"help"`
+		Properties       map[string]any `json:"properties,omitempty"`
+	}
+	sarifMessage struct {
+		Text     string `json:"text"`
+		Markdown string `json:"markdown,omitempty"`
+	}
+	sarifResult struct {
+		RuleID     string            `json:"ruleId,omitempty"`
+		Level      string            `json:"level"`
+		Message    sarifMessage      `json:"message"`
+		Locations  []sarifLocation   `json:"locations,omitempty"`
+		Properties map[string]string `json:"properties,omitempty"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+)
+
+// sarifRuleFor returns the SARIF rule of a known error, with its
+// remediation as help.
+func sarifRuleFor(info types.ErrorInfo) sarifRule {
+	var md strings.Builder
+	md.WriteString(info.Description + "\n\nLikely causes:\n\n")
+	for _, c := range info.Causes {
+		md.WriteString("- " + c + "\n")
+	}
+	md.WriteString("\nRemediation:\n\n")
+	for _, r := range info.Remediation {
+		md.WriteString("- " + r + "\n")
+	}
+	rule := sarifRule{
+		ID:               info.Name,
+		ShortDescription: sarifMessage{Text: info.Description},
+		FullDescription:  sarifMessage{Text: info.Description + " Reported by " + info.Validator + "."},
+		Help:             sarifMessage{Text: strings.Join(info.Remediation, "\n"), Markdown: md.String()},
+		Properties:       map[string]any{"validator": info.Validator},
+	}
+	if info.GoVersions != "" {
+		rule.Properties["go_versions"] = info.GoVersions
+	}
+	return rule
+}
+
+// renderSARIFReport renders the failed results and warnings as a SARIF
+// log, with a rule per known error found. The locations are the paths in
+// the images, made relative.
+func renderSARIFReport(results []*types.ScanResults) ([]byte, error) {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "check-payload", InformationURI: toolURI, Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+	for _, info := range foundErrors(results) {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRuleFor(info))
+	}
+	for _, result := range results {
+		for _, res := range result.Items {
+			level := "error"
+			switch {
+			case res.IsLevel(types.Error):
+			case res.IsLevel(types.Warning):
+				level = "warning"
+			default:
+				continue
+			}
+			r := sarifResult{
+				RuleID:  types.KnownErrorName(res.Error.Error),
+				Level:   level,
+				Message: sarifMessage{Text: getError(res)},
+			}
+			if res.Path != "" {
+				r.Locations = []sarifLocation{{sarifPhysicalLocation{sarifArtifactLocation{strings.TrimPrefix(res.Path, "/")}}}}
+			}
+			props := map[string]string{"component": getComponent(res), "tag": getTag(res), "image": getImage(res), "rpm": getRPM(res), "os": res.OS}
+			for k, v := range props {
+				if v == "" {
+					delete(props, k)
+				}
+			}
+			if len(props) > 0 {
+				r.Properties = props
+			}
+			run.Results = append(run.Results, r)
+		}
+	}
+	return json.MarshalIndent(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}}, "", "  ")
+}
+
+// printStructuredReport prints the json or sarif report, and writes it to
+// the output file, if any.
+func printStructuredReport(cfg *types.Config, results []*types.ScanResults) {
+	var data []byte
+	var err error
+	if cfg.OutputFormat == "sarif" {
+		data, err = renderSARIFReport(results)
+	} else {
+		data, err = renderJSONReport(cfg, results)
+	}
+	if err != nil { // Should never happen.
+		klog.Errorf("could not render %s report: %v", cfg.OutputFormat, err)
+		return
+	}
+	fmt.Println(string(data))
+
+	if cfg.OutputFile != "" {
+		if err := os.WriteFile(cfg.OutputFile, data, 0o777); err != nil {
+			klog.Errorf("could not write file: %v", err)
+		}
+	}
+}
+
+// renderRemediation renders the explanations of the errors found, with
+// their remediation, as an html table.
+func renderRemediation(results []*types.ScanResults) string {
+	tw := table.NewWriter()
+	tw.SuppressEmptyColumns()
+	tw.AppendHeader(table.Row{colTitleError, colTitleDescription, colTitleRemediation, colTitleValidator, colTitleGoVersions})
+	for _, info := range foundErrors(results) {
+		tw.AppendRow(table.Row{info.Name, info.Description, strings.Join(info.Remediation, "\n"), info.Validator, info.GoVersions})
+	}
+	tw.SetIndexColumn(1)
+	return tw.RenderHTML()
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -13,6 +14,7 @@ import (
 	"sync"
 
 	"github.com/openshift/check-payload/internal/podman"
+	"github.com/openshift/check-payload/internal/rpm"
 	"github.com/openshift/check-payload/internal/types"
 	"github.com/openshift/check-payload/internal/validations"
 
@@ -23,6 +25,16 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// defaultJavaDisabledAlgorithms are the algorithms java should be disabling
+// in FIPS mode, unless configured otherwise.
+var defaultJavaDisabledAlgorithms = []string{
+	"DH keySize < 2048", "TLSv1.1", "TLSv1", "SSLv3", "SSLv2",
+	"TLS_RSA_WITH_AES_256_CBC_SHA256", "TLS_RSA_WITH_AES_256_CBC_SHA", "TLS_RSA_WITH_AES_128_CBC_SHA256",
+	"TLS_RSA_WITH_AES_128_CBC_SHA", "TLS_RSA_WITH_AES_256_GCM_SHA384", "TLS_RSA_WITH_AES_128_GCM_SHA256", "DHE_DSS",
+	"RSA_EXPORT", "DHE_DSS_EXPORT", "DHE_RSA_EXPORT", "DH_DSS_EXPORT", "DH_RSA_EXPORT", "DH_anon", "ECDH_anon",
+	"DH_RSA", "DH_DSS", "ECDH", "3DES_EDE_CBC", "DES_CBC", "RC4_40", "RC4_128", "DES40_CBC", "RC2", "HmacMD5",
+}
+
 type Request struct {
 	Tag *v1.TagReference
 }
@@ -308,22 +320,6 @@ func validateTag(ctx context.Context, tag *v1.TagReference, cfg *types.Config) *
 		return rpmRootScan(ctx, cfg, mountPath)
 	}
 
-	if cfg.Java {
-		disabledAlgorithms := []string{
-			"DH keySize < 2048", "TLSv1.1", "TLSv1", "SSLv3", "SSLv2",
-			"TLS_RSA_WITH_AES_256_CBC_SHA256", "TLS_RSA_WITH_AES_256_CBC_SHA", "TLS_RSA_WITH_AES_128_CBC_SHA256",
-			"TLS_RSA_WITH_AES_128_CBC_SHA", "TLS_RSA_WITH_AES_256_GCM_SHA384", "TLS_RSA_WITH_AES_128_GCM_SHA256", "DHE_DSS",
-			"RSA_EXPORT", "DHE_DSS_EXPORT", "DHE_RSA_EXPORT", "DH_DSS_EXPORT", "DH_RSA_EXPORT", "DH_anon", "ECDH_anon",
-			"DH_RSA", "DH_DSS", "ECDH", "3DES_EDE_CBC", "DES_CBC", "RC4_40", "RC4_128", "DES40_CBC", "RC2", "HmacMD5",
-		}
-		if len(cfg.JavaDisabledAlgorithms) > 0 {
-			disabledAlgorithms = cfg.JavaDisabledAlgorithms
-		}
-		if err := podman.ScanJava(ctx, image, disabledAlgorithms); err != nil {
-			return types.NewScanResults().Append(types.NewScanResult().SetTag(tag).SetError(err))
-		}
-	}
-
 	return walkDirScan(ctx, cfg, tag, component, mountPath)
 }
 
@@ -371,23 +367,80 @@ type imagePhase func(ctx context.Context, cfg *types.Config, tag *v1.TagReferenc
 
 func walkDirScan(ctx context.Context, cfg *types.Config, tag *v1.TagReference, component *types.OpenshiftComponent, mountPath string) *types.ScanResults {
 	results := types.NewScanResults()
+	// The package index of the image is built on first use, and shared
+	// by all the phases.
+	defer rpm.ForgetIndex(mountPath)
+	osInfo := validations.DetectOS(mountPath)
+	results.OS = &osInfo
+	ctx = cfg.WithRules(ctx, tag, component, osInfo.String())
+	ctx = cfg.WithExceptions(ctx)
 	for _, phase := range []imagePhase{
-		validateOSPhase,
+		validateJavaRuntimesPhase,
 		scanBinariesPhase,
+		scanJavaArchivesPhase,
+		scanPythonPackagesPhase,
+		validateOSPhase,
+		validateOpensslConfigPhase,
 		validateModuleArtifactsPhase,
 	} {
 		phase(ctx, cfg, tag, component, mountPath, results)
 	}
+	// The rules may depend on the OS of the results.
+	results.SetOSName(results.OS.String())
+	cfg.ApplySeverity(results)
+	cfg.ApplyRules(results)
 	logPipelineSummary(tag, component, results)
 	return results
 }
 
-func validateOSPhase(_ context.Context, cfg *types.Config, tag *v1.TagReference, component *types.OpenshiftComponent, mountPath string, results *types.ScanResults) {
+// validateJavaRuntimesPhase validates every Java runtime installed in the
+// image, reporting each one separately.
+func validateJavaRuntimesPhase(ctx context.Context, cfg *types.Config, tag *v1.TagReference, component *types.OpenshiftComponent, mountPath string, results *types.ScanResults) {
+	if !cfg.Java || tag == nil || tag.From == nil {
+		return
+	}
+	image := tag.From.Name
+	disabledAlgorithms := defaultJavaDisabledAlgorithms
+	if len(cfg.JavaDisabledAlgorithms) > 0 {
+		disabledAlgorithms = cfg.JavaDisabledAlgorithms
+	}
+
+	jInfo, err := podman.InspectJava(ctx, image)
+	if err != nil {
+		results.Append(types.NewScanResult().SetTag(tag).SetComponent(component).SetError(err))
+		return
+	}
+	runtimes := validations.FindJavaRuntimes(mountPath, jInfo)
+	if len(runtimes) == 0 {
+		results.Append(types.NewScanResult().SetTag(tag).SetComponent(component).SetError(errors.New("no java runtime found in image")))
+		return
+	}
+	for _, rt := range runtimes {
+		klog.InfoS("validating java runtime", "image", image, "home", rt.Home, "java", rt.Java, "entrypoint", rt.Entrypoint)
+		res := types.NewScanResult().SetPath(rt.Home).SetTag(tag).SetComponent(component)
+		if err := podman.ValidateJavaRuntime(ctx, image, jInfo, rt, disabledAlgorithms); err != nil {
+			what := "java runtime"
+			if rt.Entrypoint {
+				what = "entrypoint java runtime"
+			}
+			res.SetError(fmt.Errorf("%s %s: %w", what, rt.Java, err))
+			klog.InfoS("java runtime validation failed", "image", image, "java", rt.Java, "error", err)
+		}
+		results.Append(res)
+	}
+}
+
+// validateOSPhase checks the OS of the image. It runs after the scans, as
+// whether an image with no OS is fine depends on the crypto modules used.
+// The OS detected by walkDirScan is on the results, and the OS validated
+// replaces it.
+func validateOSPhase(ctx context.Context, cfg *types.Config, tag *v1.TagReference, component *types.OpenshiftComponent, mountPath string, results *types.ScanResults) {
 	certifiedDistributions := cfg.GetCertifiedDistributions()
-	if len(certifiedDistributions) == 0 || cfg.ShouldIgnoreOSValidation(tag, component, types.ErrOSNotCertified) {
+	if len(certifiedDistributions) == 0 || cfg.ShouldIgnoreOSValidation(ctx, tag, component, types.ErrOSNotCertified) {
 		return
 	}
-	osInfo := validations.ValidateOS(cfg, mountPath)
+	osInfo := validations.ValidateOS(cfg, *results.OS, modulesInUse(results))
+	results.OS = &osInfo
 	results.Append(types.NewScanResult().SetOS(osInfo).SetComponent(component).SetTag(tag))
 }
 
@@ -399,17 +452,7 @@ func scanBinariesPhase(ctx context.Context, cfg *types.Config, tag *v1.TagRefere
 		}
 	}
 
-	errIgnoreLists := []types.ErrIgnoreList{cfg.ErrIgnores}
-	if tag != nil {
-		if i, ok := cfg.TagIgnores[tag.Name]; ok {
-			errIgnoreLists = append(errIgnoreLists, i.ErrIgnores)
-		}
-	}
-	if component != nil {
-		if i, ok := cfg.PayloadIgnores[component.Component]; ok {
-			errIgnoreLists = append(errIgnoreLists, i.ErrIgnores)
-		}
-	}
+	errIgnoreLists := getErrIgnoreLists(cfg, tag, component)
 
 	var scanned, skipped int
 	if err := filepath.WalkDir(mountPath, func(path string, file fs.DirEntry, err error) error {
@@ -430,14 +473,21 @@ func scanBinariesPhase(ctx context.Context, cfg *types.Config, tag *v1.TagRefere
 		if err != nil {
 			return err
 		}
-		if fi.Mode().Perm()&0o111 == 0 {
+		// Native node.js addons are shared objects, not necessarily executable.
+		addon := validations.IsNodeAddon(innerPath)
+		if !addon && fi.Mode().Perm()&0o111 == 0 {
 			return nil
 		}
 		if cfg.IgnoreFileWithTag(innerPath, tag) || cfg.IgnoreFileWithComponent(innerPath, component) {
 			return nil
 		}
 		klog.V(1).InfoS("scanning path", "path", path)
-		res := validations.ScanBinary(ctx, mountPath, innerPath, cfg.RPMIgnores, errIgnoreLists...)
+		var res *types.ScanResult
+		if addon {
+			res = validations.ScanNodeAddon(ctx, mountPath, innerPath, cfg.RPMIgnores, errIgnoreLists...)
+		} else {
+			res = validations.ScanBinary(ctx, mountPath, innerPath, cfg.RPMIgnores, errIgnoreLists...)
+		}
 		if res.Skip {
 			skipped++
 			return nil
@@ -468,31 +518,170 @@ func scanBinariesPhase(ctx context.Context, cfg *types.Config, tag *v1.TagRefere
 	klog.V(1).InfoS("binary scan complete", "scanned", scanned, "skipped", skipped, "mountPath", mountPath)
 }
 
-func validateModuleArtifactsPhase(ctx context.Context, cfg *types.Config, tag *v1.TagReference, component *types.OpenshiftComponent, mountPath string, results *types.ScanResults) {
-	if !cfg.UseFIPSModuleValidation() {
+func scanJavaArchivesPhase(ctx context.Context, cfg *types.Config, tag *v1.TagReference, component *types.OpenshiftComponent, mountPath string, results *types.ScanResults) {
+	if !cfg.ScanJavaArchives {
 		return
 	}
-	modulesInUseSet := make(map[string]bool)
+	errIgnoreLists := getErrIgnoreLists(cfg, tag, component)
+
+	var scanned int
+	if err := filepath.WalkDir(mountPath, func(path string, file fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		innerPath := stripMountPath(mountPath, path)
+		if file.IsDir() {
+			if cfg.IgnoreDirWithComponent(innerPath, component) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !file.Type().IsRegular() || !validations.IsJavaArchive(innerPath) {
+			return nil
+		}
+		if cfg.IgnoreFileWithTag(innerPath, tag) || cfg.IgnoreFileWithComponent(innerPath, component) {
+			return nil
+		}
+		klog.V(1).InfoS("scanning java archive", "path", path)
+		res := validations.ScanJavaArchive(ctx, mountPath, innerPath, cfg.RPMIgnores, errIgnoreLists...)
+		if res.Skip {
+			return nil
+		}
+		if !res.IsSuccess() && res.RPM != "" && cfg.IgnoreFileByRpm(innerPath, res.RPM) {
+			return nil
+		}
+		res.SetTag(tag).SetComponent(component)
+		scanned++
+		if !res.IsSuccess() {
+			klog.InfoS("java archive scan "+res.Status(),
+				"image", getImage(res),
+				"path", innerPath,
+				"error", res.Error.Error,
+				"component", getComponent(res),
+				"rpm", res.RPM)
+		}
+		results.Append(res)
+		return nil
+	}); err != nil {
+		results.Append(types.NewScanResult().SetError(err))
+	}
+	klog.V(1).InfoS("java archive scan complete", "scanned", scanned, "mountPath", mountPath)
+}
+
+// scanPythonPackagesPhase checks python packages, as well as the python
+// interpreter extension modules, for bundled copies of OpenSSL.
+func scanPythonPackagesPhase(ctx context.Context, cfg *types.Config, tag *v1.TagReference, component *types.OpenshiftComponent, mountPath string, results *types.ScanResults) {
+	if !cfg.ScanPythonPackages {
+		return
+	}
+	errIgnoreLists := getErrIgnoreLists(cfg, tag, component)
+
+	var scanned int
+	if err := filepath.WalkDir(mountPath, func(path string, file fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !file.IsDir() {
+			return nil
+		}
+		innerPath := stripMountPath(mountPath, path)
+		if cfg.IgnoreDirWithComponent(innerPath, component) {
+			return filepath.SkipDir
+		}
+		if !validations.IsPythonLibDir(innerPath) {
+			return nil
+		}
+		klog.V(1).InfoS("scanning python packages", "path", path)
+		scanned++
+		for _, res := range validations.ScanPythonLibDir(ctx, mountPath, innerPath, cfg.RPMIgnores, errIgnoreLists...) {
+			if cfg.IgnoreFileWithTag(res.Path, tag) || cfg.IgnoreFileWithComponent(res.Path, component) {
+				continue
+			}
+			if !res.IsSuccess() && res.RPM != "" && cfg.IgnoreFileByRpm(res.Path, res.RPM) {
+				continue
+			}
+			res.SetTag(tag).SetComponent(component)
+			if !res.IsSuccess() {
+				klog.InfoS("python package scan "+res.Status(),
+					"image", getImage(res),
+					"path", res.Path,
+					"error", res.Error.Error,
+					"component", getComponent(res),
+					"rpm", res.RPM)
+			}
+			results.Append(res)
+		}
+		// All packages in the directory have been checked.
+		return filepath.SkipDir
+	}); err != nil {
+		results.Append(types.NewScanResult().SetError(err))
+	}
+	klog.V(1).InfoS("python package scan complete", "directories", scanned, "mountPath", mountPath)
+}
+
+// getErrIgnoreLists returns the error ignore lists applicable to the
+// given tag and component, including the global one.
+func getErrIgnoreLists(cfg *types.Config, tag *v1.TagReference, component *types.OpenshiftComponent) []types.ErrIgnoreList {
+	errIgnoreLists := []types.ErrIgnoreList{cfg.ErrIgnores}
+	if tag != nil {
+		if i, ok := cfg.TagIgnores[tag.Name]; ok {
+			errIgnoreLists = append(errIgnoreLists, i.ErrIgnores)
+		}
+	}
+	if component != nil {
+		if i, ok := cfg.PayloadIgnores[component.Component]; ok {
+			errIgnoreLists = append(errIgnoreLists, i.ErrIgnores)
+		}
+	}
+	return errIgnoreLists
+}
+
+// validateOpensslConfigPhase checks the image OpenSSL configuration, if
+// any of the scanned binaries use OpenSSL.
+func validateOpensslConfigPhase(ctx context.Context, cfg *types.Config, tag *v1.TagReference, component *types.OpenshiftComponent, mountPath string, results *types.ScanResults) {
+	usesOpenssl := false
 	for _, item := range results.Items {
 		for _, m := range item.ModulesUsed {
-			modulesInUseSet[m] = true
+			if m == "openssl" {
+				usesOpenssl = true
+			}
 		}
 	}
-	if len(modulesInUseSet) == 0 {
-		klog.V(1).InfoS("fips module validation skipped, no crypto modules detected", "mountPath", mountPath)
+	if !usesOpenssl {
 		return
 	}
-	var modulesInUse []string
-	for m := range modulesInUseSet {
-		modulesInUse = append(modulesInUse, m)
+
+	var fipsPaths []string
+	for _, m := range cfg.GetFIPSCertifiedModules() {
+		if m.Module == "openssl" {
+			fipsPaths = append(fipsPaths, m.CertifiedArtifactPaths...)
+		}
+	}
+	for _, res := range validations.ScanOpensslConfig(ctx, mountPath, fipsPaths, cfg.RPMIgnores, getErrIgnoreLists(cfg, tag, component)...) {
+		if cfg.IgnoreFileWithTag(res.Path, tag) || cfg.IgnoreFileWithComponent(res.Path, component) {
+			continue
+		}
+		if res.RPM != "" && cfg.IgnoreFileByRpm(res.Path, res.RPM) {
+			continue
+		}
+		klog.InfoS("openssl configuration check "+res.Status(), "path", res.Path, "error", res.Error.Error, "mountPath", mountPath)
+		results.Append(res.SetTag(tag).SetComponent(component))
 	}
-	klog.V(1).InfoS("fips module validation", "modulesDetected", modulesInUse, "mountPath", mountPath)
+}
+
+func validateModuleArtifactsPhase(ctx context.Context, cfg *types.Config, tag *v1.TagReference, component *types.OpenshiftComponent, mountPath string, results *types.ScanResults) {
+	if !cfg.UseFIPSModuleValidation() {
+		return
+	}
+	modules := modulesInUse(results)
+	if len(modules) == 0 {
+		klog.V(1).InfoS("fips module validation skipped, no crypto modules detected", "mountPath", mountPath)
+		return
+	}
+	klog.V(1).InfoS("fips module validation", "modulesDetected", modules, "mountPath", mountPath)
 
-	for _, module := range modulesInUse {
+	for _, module := range modules {
 		if ve := validations.ValidateModule(ctx, cfg, mountPath, module); ve != nil {
-			if cfg.Java {
-				ve.SetWarning()
-			}
 			klog.InfoS("fips module validation failed", "module", module, "error", ve.Error, "mountPath", mountPath)
 			results.Append(types.NewScanResult().SetValidationError(ve).SetComponent(component).SetTag(tag))
 		} else {
@@ -501,6 +690,21 @@ func validateModuleArtifactsPhase(ctx context.Context, cfg *types.Config, tag *v
 	}
 }
 
+// modulesInUse returns the crypto modules used by any of the results.
+func modulesInUse(results *types.ScanResults) []string {
+	set := make(map[string]bool)
+	var modules []string
+	for _, item := range results.Items {
+		for _, m := range item.ModulesUsed {
+			if !set[m] {
+				set[m] = true
+				modules = append(modules, m)
+			}
+		}
+	}
+	return modules
+}
+
 func logPipelineSummary(tag *v1.TagReference, component *types.OpenshiftComponent, results *types.ScanResults) {
 	var succeeded, failed, warnings int
 	modules := make(map[string]bool)
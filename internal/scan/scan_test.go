@@ -2,6 +2,11 @@ package scan
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,7 +24,7 @@ var (
 			PayloadIgnores:         make(map[string]types.IgnoreLists),
 			TagIgnores:             make(map[string]types.IgnoreLists),
 			RPMIgnores:             make(map[string]types.IgnoreLists),
-			CertifiedDistributions: []string{"Red Hat Enterprise Linux release 9.2 (Plow)", "Red Hat Enterprise Linux CoreOS release 4.12"},
+			CertifiedDistributions: []types.CertifiedDistribution{{Release: "Red Hat Enterprise Linux release 9.2 (Plow)"}, {Release: "Red Hat Enterprise Linux CoreOS release 4.12"}},
 		},
 	}
 	moduleConfig94 = &types.Config{
@@ -31,7 +36,7 @@ var (
 			PayloadIgnores:         make(map[string]types.IgnoreLists),
 			TagIgnores:             make(map[string]types.IgnoreLists),
 			RPMIgnores:             make(map[string]types.IgnoreLists),
-			CertifiedDistributions: []string{"Red Hat Enterprise Linux release 9.4 (Plow)"},
+			CertifiedDistributions: []types.CertifiedDistribution{{Release: "Red Hat Enterprise Linux release 9.4 (Plow)"}},
 			FIPSCertifiedModules: []types.FipsModule{
 				{
 					Module:            "openssl",
@@ -60,7 +65,7 @@ var (
 			PayloadIgnores:         make(map[string]types.IgnoreLists),
 			TagIgnores:             make(map[string]types.IgnoreLists),
 			RPMIgnores:             make(map[string]types.IgnoreLists),
-			CertifiedDistributions: []string{"Red Hat Enterprise Linux release 9.6 (Plow)"},
+			CertifiedDistributions: []types.CertifiedDistribution{{Release: "Red Hat Enterprise Linux release 9.6 (Plow)"}},
 			FIPSCertifiedModules: []types.FipsModule{
 				{
 					Module:            "openssl",
@@ -80,6 +85,18 @@ var (
 			},
 		},
 	}
+	osReleaseConfig = &types.Config{
+		OutputFormat: "table",
+		Parallelism:  1,
+		TimeLimit:    30 * time.Second,
+		Verbose:      true,
+		ConfigFile: types.ConfigFile{
+			PayloadIgnores:         make(map[string]types.IgnoreLists),
+			TagIgnores:             make(map[string]types.IgnoreLists),
+			RPMIgnores:             make(map[string]types.IgnoreLists),
+			CertifiedDistributions: []types.CertifiedDistribution{{ID: "rhel", Versions: []string{"9.2", "9.4"}}},
+		},
+	}
 	ignoredOsConfig = &types.Config{
 		OutputFormat: "table",
 		Parallelism:  1,
@@ -101,7 +118,7 @@ var (
 				},
 			},
 			RPMIgnores:             make(map[string]types.IgnoreLists),
-			CertifiedDistributions: []string{"Red Hat Enterprise Linux release 12388.3 (Plow)"},
+			CertifiedDistributions: []types.CertifiedDistribution{{Release: "Red Hat Enterprise Linux release 12388.3 (Plow)"}},
 		},
 	}
 )
@@ -120,6 +137,9 @@ func TestRunLocalScan(t *testing.T) {
 		{"BadMockUnsupportedOperatingSystem", "../../test/resources/mock_unsupported_os", baseConfig, false},
 		{"UnsupportedOperatingSystemIgnored", "../../test/resources/mock_unsupported_os", ignoredOsConfig, true},
 		{"SymlinkedOsRelease", "../../test/resources/mock_os_symlinked", baseConfig, true},
+		{"OsReleaseCertified", "../../test/resources/mock_os_release", osReleaseConfig, true},
+		{"OsReleaseNoRedhatRelease", "../../test/resources/mock_os_release", baseConfig, false},
+		{"OsReleaseNotCertified", "../../test/resources/mock_unsupported_os", osReleaseConfig, false},
 		{"ModuleModeRHEL94WithProvider", "../../test/resources/mock_unpacked_dir_9_4", moduleConfig94, true},
 		{"PIE_Go126_s390x", "../../test/resources/mock_unpacked_dir_pie_s390x", baseConfig, true},
 		{"NativeFIPSBinary", "../../test/resources/mock_native_fips", nativeFIPSConfig, true},
@@ -143,6 +163,90 @@ func TestRunLocalScan(t *testing.T) {
 	}
 }
 
+// TestRunLocalScanScratch checks that a scratch image with only native FIPS
+// Go binaries passes, and is reported as such.
+func TestRunLocalScanScratch(t *testing.T) {
+	root := t.TempDir()
+	bin, err := os.ReadFile("../../test/resources/mock_native_fips/usr/bin/go-native-fips-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "usr/bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "usr/bin/app"), bin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	results := RunLocalScan(context.Background(), nativeFIPSConfig, root)
+	if IsFailed(results) || IsWarnings(results) {
+		t.Fatalf("expected the scan to pass, got %+v", results[0].Items)
+	}
+	var osChecked bool
+	for _, res := range results[0].Items {
+		if res.OS != string(types.ImageClassScratch) {
+			t.Errorf("%s: got OS %q, want %q", res.Path, res.OS, types.ImageClassScratch)
+		}
+		if res.Path == "" {
+			osChecked = true
+		}
+	}
+	if !osChecked {
+		t.Error("no OS validation result")
+	}
+	// The OS validated is the one kept on the results.
+	if info := results[0].OS; info == nil || info.Class != types.ImageClassScratch || !info.Certified {
+		t.Errorf("got results OS %+v, want the validated scratch OS", info)
+	}
+}
+
+// TestRunLocalScanRulesOnOS checks that the rules can match the OS of the
+// image the errors are found in.
+func TestRunLocalScanRulesOnOS(t *testing.T) {
+	root := t.TempDir()
+	for src, dst := range map[string]string{
+		"libcrypto.so":   "usr/lib/bad.node",
+		"redhat-release": "etc/redhat-release",
+	} {
+		data, err := os.ReadFile(filepath.Join("../../test/resources", src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(dst)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, dst), data, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		release string
+		pass    bool
+	}{
+		{"9.2", true},
+		{"9.4", false},
+	} {
+		t.Run(tc.release, func(t *testing.T) {
+			cfgFile, err := types.DecodeConfig([]byte(`
+[[rule]]
+name = "rhel ` + tc.release + `"
+when = "error == 'ErrNodeBundledOpenSSL' && starts_with(os, 'Red Hat Enterprise Linux release ` + tc.release + `')"
+action = "ignore"
+`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfgFile.CertifiedDistributions = baseConfig.CertifiedDistributions
+			cfg := &types.Config{Parallelism: 1, TimeLimit: 30 * time.Second, ConfigFile: *cfgFile}
+			results := RunLocalScan(context.Background(), cfg, root)
+			if passed := !IsFailed(results); passed != tc.pass {
+				t.Errorf("got pass = %t, want %t: %+v", passed, tc.pass, results[0].Items)
+			}
+		})
+	}
+}
+
 func TestShouldSkipOSValidation(t *testing.T) {
 	testCases := []struct {
 		name      string
@@ -291,14 +395,176 @@ func TestShouldSkipOSValidation(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "tag ignore with expired waiver should not skip",
+			config: &types.Config{
+				ConfigFile: types.ConfigFile{
+					TagIgnores: map[string]types.IgnoreLists{
+						"rhel-coreos": {
+							ErrIgnores: types.ErrIgnoreList{{
+								Error:  types.KnownError{Err: types.ErrOSNotCertified},
+								Tags:   []string{"rhel-coreos"},
+								Waiver: types.Waiver{Ticket: "OCPBUGS-1", Expires: types.NewDate(time.Now().AddDate(0, 0, -1))},
+							}},
+						},
+					},
+				},
+			},
+			tag: &v1.TagReference{
+				Name: "rhel-coreos",
+			},
+			expected: false,
+		},
+		{
+			name: "tag ignore expiring today should skip",
+			config: &types.Config{
+				ConfigFile: types.ConfigFile{
+					TagIgnores: map[string]types.IgnoreLists{
+						"rhel-coreos": {
+							ErrIgnores: types.ErrIgnoreList{{
+								Error:  types.KnownError{Err: types.ErrOSNotCertified},
+								Tags:   []string{"rhel-coreos"},
+								Waiver: types.Waiver{Ticket: "OCPBUGS-1", Expires: types.NewDate(time.Now())},
+							}},
+						},
+					},
+				},
+			},
+			tag: &v1.TagReference{
+				Name: "rhel-coreos",
+			},
+			expected: true,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result := tc.config.ShouldIgnoreOSValidation(tc.tag, tc.component, types.ErrOSNotCertified)
+			result := tc.config.ShouldIgnoreOSValidation(context.Background(), tc.tag, tc.component, types.ErrOSNotCertified)
 			if result != tc.expected {
 				t.Errorf("shouldSkipOSValidation() = %v, expected %v", result, tc.expected)
 			}
 		})
 	}
 }
+
+func TestWriteExceptions(t *testing.T) {
+	comp := &types.OpenshiftComponent{Component: "foo"}
+	fail := func(path string, err error) *types.ScanResult {
+		return types.NewScanResult().SetComponent(comp).SetPath(path).SetError(err)
+	}
+	results := []*types.ScanResults{{Items: []*types.ScanResult{
+		fail("/usr/bin/b", types.ErrNotDynLinked),
+		fail("/usr/bin/a", types.ErrNotDynLinked),
+		fail("/usr/lib/plugins/p1", types.ErrNotDynLinked),
+		fail("/usr/lib/plugins/p2", types.ErrNotDynLinked),
+		fail("/usr/lib/plugins/p3", types.ErrNotDynLinked),
+		fail("/usr/libexec/p4", types.ErrGoMissingTag),
+		fail("/usr/lib64/libfoo.so", types.ErrLibcryptoSoMissing).SetRPM("foo-libs"),
+		// Only 3 out of 5 files in /usr/bin fail, so they are not grouped.
+		fail("/usr/bin/c", types.ErrNotDynLinked),
+		types.NewScanResult().SetComponent(comp).SetPath("/usr/bin/ok1"),
+		types.NewScanResult().SetComponent(comp).SetPath("/usr/bin/ok2"),
+	}}}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "exceptions.toml")
+	existing := `# Kept as is.
+[[payload.foo.ignore]]
+error   = "ErrGoMissingTag"
+files   = [ "/usr/libexec/p4" ]  # Aligned.
+`
+	if err := os.WriteFile(file, []byte(existing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The second run has no new exceptions, and leaves the file as is.
+	for i := 0; i < 2; i++ {
+		if err := WriteExceptions(file, results); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := existing + `
+[[payload.foo.ignore]]
+error = "ErrNotDynLinked"
+files = ["/usr/bin/a", "/usr/bin/b", "/usr/bin/c"]
+dirs = ["/usr/lib/plugins"]
+reason = "TODO"
+ticket = "TODO"
+
+[[rpm.foo-libs.ignore]]
+error = "ErrLibcryptoSoMissing"
+files = ["/usr/lib64/libfoo.so"]
+reason = "TODO"
+ticket = "TODO"
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// With no errors found, no file is written.
+	none := filepath.Join(dir, "none.toml")
+	if err := WriteExceptions(none, []*types.ScanResults{{Items: results[0].Items[8:]}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(none); !os.IsNotExist(err) {
+		t.Errorf("%s: expected no file, got %v", none, err)
+	}
+
+	// Only toml files are appended to.
+	jsonFile := filepath.Join(dir, "exceptions.json")
+	if err := os.WriteFile(jsonFile, []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteExceptions(jsonFile, results); err == nil {
+		t.Errorf("%s: expected an error", jsonFile)
+	}
+}
+
+func TestStructuredReports(t *testing.T) {
+	comp := &types.OpenshiftComponent{Component: "foo"}
+	results := []*types.ScanResults{{Items: []*types.ScanResult{
+		types.NewScanResult().SetComponent(comp).SetPath("/usr/bin/a").SetError(types.ErrGoNoCgoInit),
+		types.NewScanResult().SetComponent(comp).SetPath("/usr/bin/b").SetValidationError(types.NewValidationError(types.ErrGoNoTags).SetWarning()),
+		types.NewScanResult().SetComponent(comp).SetPath("/usr/bin/ok"),
+	}}}
+	info, _ := types.ExplainError("ErrGoNoCgoInit")
+
+	data, err := renderJSONReport(&types.Config{}, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report jsonReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != "failed" || len(report.Results) != 2 || report.Results[0].ErrorName != "ErrGoNoCgoInit" || report.Results[1].Status != "warning" {
+		t.Errorf("unexpected json report: %s", data)
+	}
+	if len(report.Errors) != 2 || !reflect.DeepEqual(report.Errors[0], info) || report.Errors[1].Name != "ErrGoNoTags" {
+		t.Errorf("unexpected json report errors: %+v", report.Errors)
+	}
+
+	data, err = renderSARIFReport(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatal(err)
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != "ErrGoNoCgoInit" || run.Tool.Driver.Rules[0].Help.Text != strings.Join(info.Remediation, "\n") {
+		t.Errorf("unexpected sarif rules: %+v", run.Tool.Driver.Rules)
+	}
+	if len(run.Results) != 2 || run.Results[0].Level != "error" || run.Results[1].Level != "warning" ||
+		run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI != "usr/bin/a" || run.Results[0].Properties["component"] != "foo" {
+		t.Errorf("unexpected sarif results: %+v", run.Results)
+	}
+
+	if html := renderRemediation(results); !strings.Contains(html, info.Remediation[0]) {
+		t.Errorf("remediation missing from html report:\n%s", html)
+	}
+}
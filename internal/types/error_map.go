@@ -6,6 +6,8 @@ var KnownErrors = map[string]error {
 	"ErrCertifiedDistributionsEmpty": ErrCertifiedDistributionsEmpty,
 	"ErrDistributionFileMissing": ErrDistributionFileMissing,
 	"ErrFipsArtifactMissing": ErrFipsArtifactMissing,
+	"ErrFipsArtifactTampered": ErrFipsArtifactTampered,
+	"ErrFipsArtifactUntrusted": ErrFipsArtifactUntrusted,
 	"ErrFipsArtifactVersionHigh": ErrFipsArtifactVersionHigh,
 	"ErrFipsArtifactVersionLow": ErrFipsArtifactVersionLow,
 	"ErrGoFIPSNotCertified": ErrGoFIPSNotCertified,
@@ -17,9 +19,13 @@ var KnownErrors = map[string]error {
 	"ErrGoNoTags": ErrGoNoTags,
 	"ErrGoNotCgoEnabled": ErrGoNotCgoEnabled,
 	"ErrGoNotGoExperiment": ErrGoNotGoExperiment,
+	"ErrJavaCryptoProvider": ErrJavaCryptoProvider,
 	"ErrLibcryptoMany": ErrLibcryptoMany,
 	"ErrLibcryptoMissing": ErrLibcryptoMissing,
 	"ErrLibcryptoSoMissing": ErrLibcryptoSoMissing,
+	"ErrNodeBundledOpenSSL": ErrNodeBundledOpenSSL,
 	"ErrNotDynLinked": ErrNotDynLinked,
 	"ErrOSNotCertified": ErrOSNotCertified,
+	"ErrOpenSSLFIPSMisconfigured": ErrOpenSSLFIPSMisconfigured,
+	"ErrPythonBundledOpenSSL": ErrPythonBundledOpenSSL,
 }
@@ -24,6 +24,12 @@ var (
 	ErrFipsArtifactMissing         = errors.New("required FIPS certified artifact not found")
 	ErrFipsArtifactVersionLow      = errors.New("FIPS certified artifact version below required minimum")
 	ErrFipsArtifactVersionHigh     = errors.New("FIPS certified artifact version above certified maximum")
+	ErrFipsArtifactTampered        = errors.New("FIPS certified artifact does not match its RPM")
+	ErrFipsArtifactUntrusted       = errors.New("FIPS certified artifact is not from a trusted vendor or signing key")
 	ErrGoFIPSNotEnabled            = errors.New("go binary does not set GODEBUG fips140={auto,on,only}")
 	ErrGoFIPSNotCertified          = errors.New("go binary not built with GOFIPS140 FIPS module")
+	ErrJavaCryptoProvider          = errors.New("java archive bundles a non-FIPS crypto provider")
+	ErrPythonBundledOpenSSL        = errors.New("python package bundles its own OpenSSL")
+	ErrNodeBundledOpenSSL          = errors.New("node.js binary or addon bundles its own OpenSSL")
+	ErrOpenSSLFIPSMisconfigured    = errors.New("openssl configuration does not enable FIPS")
 )
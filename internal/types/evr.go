@@ -0,0 +1,146 @@
+package types
+
+import (
+	"strings"
+)
+
+// evr is an rpm [epoch:]version[-release].
+type evr struct {
+	epoch   string // Empty if not given.
+	version string
+	release string // Empty if not given.
+}
+
+func parseEVR(s string) evr {
+	var e evr
+	if i := strings.IndexByte(s, ':'); i != -1 {
+		e.epoch, s = s[:i], s[i+1:]
+	}
+	if i := strings.LastIndexByte(s, '-'); i != -1 {
+		e.version, e.release = s[:i], s[i+1:]
+	} else {
+		e.version = s
+	}
+	return e
+}
+
+// compareEVR compares installed against bound, like rpm does. Only the parts
+// given in bound are compared, so that e.g. "1:3.0.7-27.el9" matches a
+// bound of "3.0.7".
+func compareEVR(installed, bound string) int {
+	i, b := parseEVR(installed), parseEVR(bound)
+	if b.epoch != "" {
+		ie := i.epoch
+		if ie == "" {
+			ie = "0"
+		}
+		if c := rpmvercmp(ie, b.epoch); c != 0 {
+			return c
+		}
+	}
+	if c := rpmvercmp(i.version, b.version); c != 0 || b.release == "" {
+		return c
+	}
+	return rpmvercmp(i.release, b.release)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// rpmvercmp compares two version (or release) strings the same way as rpm,
+// returning -1, 0 or 1. Versions are compared segment by segment, numeric
+// segments being newer than alphabetic ones; "~" sorts before anything,
+// even the end of the string, and "^" sorts after the end of the string but
+// before anything else.
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	for len(a) > 0 || len(b) > 0 {
+		a = strings.TrimLeftFunc(a, isSeparator)
+		b = strings.TrimLeftFunc(b, isSeparator)
+
+		// Tilde sorts before everything else.
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		// Caret sorts after the end of the string, but before anything else.
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			if a == "" {
+				return -1
+			}
+			if b == "" {
+				return 1
+			}
+			if !strings.HasPrefix(a, "^") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "^") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+
+		isNum := isDigit(a[0])
+		span := isAlpha
+		if isNum {
+			span = isDigit
+		}
+		sa, sb := segment(a, span), segment(b, span)
+		a, b = a[len(sa):], b[len(sb):]
+		if sb == "" {
+			// Segments of different types: numeric is newer.
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+		if isNum {
+			sa, sb = strings.TrimLeft(sa, "0"), strings.TrimLeft(sb, "0")
+			if len(sa) != len(sb) {
+				if len(sa) > len(sb) {
+					return 1
+				}
+				return -1
+			}
+		}
+		if c := strings.Compare(sa, sb); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+func isSeparator(r rune) bool {
+	return r > 0x7f || !(isDigit(byte(r)) || isAlpha(byte(r)) || r == '~' || r == '^')
+}
+
+func segment(s string, span func(byte) bool) string {
+	i := 0
+	for i < len(s) && span(s[i]) {
+		i++
+	}
+	return s[:i]
+}
@@ -2,6 +2,8 @@ package types
 
 import (
 	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
@@ -9,29 +11,52 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
-var leadingSemverRE = regexp.MustCompile(`^v?(\d+\.\d+\.\d+)`)
+var (
+	leadingSemverRE = regexp.MustCompile(`^v?(\d+\.\d+\.\d+)`)
+	semverBoundRE   = regexp.MustCompile(`^v?\d+\.\d+\.\d+$`)
+)
 
 // VersionInRange checks installed version against optional min/max bounds.
+// Bounds in the x.y.z form are compared with the leading x.y.z of the
+// installed version only (so "1.1.1k-7.el8" is within "1.1.1"). Any other
+// bound is taken as an rpm [epoch:]version[-release], and compared with the
+// installed [epoch:]version-release like rpm does; the epoch and release
+// are only compared if the bound has them.
 func VersionInRange(installed, minVersion, maxVersion string) (atLeast, atMost bool) {
-	sv := leadingSemverRE.FindString(installed)
-	if sv == "" {
+	if installed == "" {
 		return false, false
 	}
-	v, err := semver.NewVersion(sv)
-	if err != nil {
-		return false, false
+	atLeast = minVersion == "" || versionCompare(installed, minVersion, ">=")
+	atMost = maxVersion == "" || versionCompare(installed, maxVersion, "<=")
+	return atLeast, atMost
+}
+
+// versionCompare tells if installed op bound is true, op being ">=" or "<=".
+func versionCompare(installed, bound, op string) bool {
+	if semverBoundRE.MatchString(bound) {
+		sv := leadingSemverRE.FindString(stripEpoch(installed))
+		if sv == "" {
+			return false
+		}
+		v, err := semver.NewVersion(sv)
+		if err != nil {
+			return false
+		}
+		c, err := semver.NewConstraint(op + " " + bound)
+		return err == nil && c.Check(v)
 	}
-	atLeast = true
-	if minVersion != "" {
-		c, err := semver.NewConstraint(">= " + minVersion)
-		atLeast = err == nil && c.Check(v)
+	c := compareEVR(installed, bound)
+	if op == ">=" {
+		return c >= 0
 	}
-	atMost = true
-	if maxVersion != "" {
-		c, err := semver.NewConstraint("<= " + maxVersion)
-		atMost = err == nil && c.Check(v)
+	return c <= 0
+}
+
+func stripEpoch(v string) string {
+	if i := strings.IndexByte(v, ':'); i != -1 {
+		return v[i+1:]
 	}
-	return atLeast, atMost
+	return v
 }
 
 type Config struct {
@@ -48,48 +73,81 @@ type Config struct {
 	OutputFormat            string        `json:"output_format"`
 	Parallelism             int           `json:"parallelism"`
 	Java                    bool          `json:"java"`
+	ScanJavaArchives        bool          `json:"scan_java_archives"`
+	ScanPythonPackages      bool          `json:"scan_python_packages"`
 	PrintExceptions         bool          `json:"print_exceptions"`
+	ReportWaivers           bool          `json:"report_waivers"`
+	ReportUnusedExceptions  bool          `json:"report_unused_exceptions"`
+	FailOnUnusedExceptions  bool          `json:"fail_on_unused_exceptions"`
 	PullSecret              string        `json:"pull_secret"`
 	TimeLimit               time.Duration `json:"time_limit"`
 	Verbose                 bool          `json:"verbose"`
 	UseRPMScan              bool          `json:"use_rpm_scan"`
 
 	ConfigFile
+
+	// The uses of the exceptions during the scans, see Config.WithExceptions.
+	usesOnce sync.Once
+	uses     *exceptionUses
 }
 
 // ConfigFile is a part of Config. It contains fields that can be set via a
-// configuration files.
+// configuration files, in toml, json or yaml (see DecodeConfigFile).
 type ConfigFile struct {
-	FilterFiles            []string `json:"filter_files" toml:"filter_files"`
-	FilterDirs             []string `json:"filter_dirs" toml:"filter_dirs"`
-	FilterImages           []string `json:"filter_images" toml:"filter_images"`
-	JavaDisabledAlgorithms []string `json:"java_fips_disabled_algorithms" toml:"java_fips_disabled_algorithms"`
-	CertifiedDistributions []string `json:"certified_distributions" toml:"certified_distributions"`
+	// Extends is the version of the embedded config this one is based on,
+	// and Include the config files added to it, relative to this one.
+	// Both are resolved when loading the config (see releases.ResolveConfig).
+	Extends string   `json:"extends,omitempty" toml:"extends,omitempty"`
+	Include []string `json:"include,omitempty" toml:"include,omitempty"`
+
+	FilterFiles            []string                `json:"filter_files,omitempty" toml:"filter_files,omitempty"`
+	FilterDirs             []string                `json:"filter_dirs,omitempty" toml:"filter_dirs,omitempty"`
+	FilterImages           []string                `json:"filter_images,omitempty" toml:"filter_images,omitempty"`
+	JavaDisabledAlgorithms []string                `json:"java_fips_disabled_algorithms,omitempty" toml:"java_fips_disabled_algorithms,omitempty"`
+	CertifiedDistributions []CertifiedDistribution `json:"certified_distributions,omitempty" toml:"certified_distributions,omitempty"`
+
+	FIPSCertifiedModules []FipsModule `json:"fips_certified_modules,omitempty" toml:"fips_certified_modules,omitempty"`
+
+	// Severity overrides the level (error or warning) of the results with
+	// the errors it lists; see Config.SeverityOf.
+	Severity map[string]ErrorLevel `json:"severity,omitempty" toml:"severity,omitempty"`
 
-	FIPSCertifiedModules []FipsModule `json:"fips_certified_modules" toml:"fips_certified_modules"`
+	PayloadIgnores map[string]IgnoreLists `json:"payload,omitempty" toml:"payload,omitempty"`
+	TagIgnores     map[string]IgnoreLists `json:"tag,omitempty" toml:"tag,omitempty"`
+	RPMIgnores     map[string]IgnoreLists `json:"rpm,omitempty" toml:"rpm,omitempty"`
+	ErrIgnores     ErrIgnoreList          `json:"ignore,omitempty" toml:"ignore,omitempty"`
 
-	PayloadIgnores map[string]IgnoreLists `toml:"payload"`
-	TagIgnores     map[string]IgnoreLists `toml:"tag"`
-	RPMIgnores     map[string]IgnoreLists `toml:"rpm"`
-	ErrIgnores     ErrIgnoreList          `json:"ignore" toml:"ignore"`
+	Rules []Rule `json:"rule,omitempty" toml:"rule,omitempty"`
+
+	// The files the exception entries come from, see RecordSources.
+	sources map[exceptionKey]string
 }
 
 type ErrIgnore struct {
-	Error KnownError `toml:"error"`
-	Files []string   `toml:"files"`
-	Dirs  []string   `toml:"dirs"`
+	Error KnownError `json:"error" toml:"error"`
+	Files []string   `json:"files,omitempty" toml:"files,omitempty"`
+	Dirs  []string   `json:"dirs,omitempty" toml:"dirs,omitempty"`
 	// `Tags` is only useful for ignoring certified distributions by
 	// component tag. It is not factored into consideration when evaluating
 	// binaries, which should continue using `Files` and `Dirs`.
-	Tags []string `toml:"tags"`
+	Tags []string `json:"tags,omitempty" toml:"tags,omitempty"`
+
+	Waiver
+
+	section string // The section it is listed in, see ConfigFile.setSections.
 }
 
 type ErrIgnoreList []ErrIgnore
 
 type IgnoreLists struct {
-	FilterFiles []string      `json:"filter_files" toml:"filter_files"`
-	FilterDirs  []string      `json:"filter_dirs" toml:"filter_dirs"`
-	ErrIgnores  ErrIgnoreList `json:"ignore" toml:"ignore"`
+	FilterFiles []string      `json:"filter_files,omitempty" toml:"filter_files,omitempty"`
+	FilterDirs  []string      `json:"filter_dirs,omitempty" toml:"filter_dirs,omitempty"`
+	ErrIgnores  ErrIgnoreList `json:"ignore,omitempty" toml:"ignore,omitempty"`
+
+	Severity map[string]ErrorLevel `json:"severity,omitempty" toml:"severity,omitempty"`
+
+	// Waiver applies to FilterFiles and FilterDirs; ErrIgnores have their own.
+	Waiver
 }
 
 type ArtifactPod struct {
@@ -99,13 +157,17 @@ type ArtifactPod struct {
 
 // FipsModule maps a crypto stack to its FIPS-certified artifact and version requirements.
 // ArtifactSource: "image" (default) = RPM/file in container, "binary" = embedded in binary.
+// CertifiedArtifactVendor and CertifiedArtifactSigningKeys (hex key IDs or
+// fingerprints), if set, must match the RPM header Vendor and signature.
 type FipsModule struct {
-	Module                      string   `json:"module" toml:"module"`
-	ArtifactSource              string   `json:"artifact_source,omitempty" toml:"artifact_source"`
-	CertifiedArtifact           string   `json:"certified_artifact,omitempty" toml:"certified_artifact"`
-	CertifiedArtifactMinVersion string   `json:"certified_artifact_min_version,omitempty" toml:"certified_artifact_min_version"`
-	CertifiedArtifactMaxVersion string   `json:"certified_artifact_max_version,omitempty" toml:"certified_artifact_max_version"`
-	CertifiedArtifactPaths      []string `json:"certified_artifact_paths,omitempty" toml:"certified_artifact_paths"`
+	Module                       string   `json:"module" toml:"module"`
+	ArtifactSource               string   `json:"artifact_source,omitempty" toml:"artifact_source,omitempty"`
+	CertifiedArtifact            string   `json:"certified_artifact,omitempty" toml:"certified_artifact,omitempty"`
+	CertifiedArtifactMinVersion  string   `json:"certified_artifact_min_version,omitempty" toml:"certified_artifact_min_version,omitempty"`
+	CertifiedArtifactMaxVersion  string   `json:"certified_artifact_max_version,omitempty" toml:"certified_artifact_max_version,omitempty"`
+	CertifiedArtifactPaths       []string `json:"certified_artifact_paths,omitempty" toml:"certified_artifact_paths,omitempty"`
+	CertifiedArtifactVendor      string   `json:"certified_artifact_vendor,omitempty" toml:"certified_artifact_vendor,omitempty"`
+	CertifiedArtifactSigningKeys []string `json:"certified_artifact_signing_keys,omitempty" toml:"certified_artifact_signing_keys,omitempty"`
 }
 
 func (m FipsModule) IsBinarySource() bool {
@@ -113,17 +175,32 @@ func (m FipsModule) IsBinarySource() bool {
 }
 
 type ScanResult struct {
-	Component   *OpenshiftComponent
-	Tag         *v1.TagReference
-	RPM         string
-	Path        string
-	Skip        bool
-	Error       *ValidationError
-	ModulesUsed []string
+	Component *OpenshiftComponent
+	Tag       *v1.TagReference
+	RPM       string
+	NVRA      string // Full Name-Version-Release.Arch of the RPM.
+	Path      string
+	OS        string // The OS detected in the image, if known.
+	Skip      bool
+	Error     *ValidationError
+	// ExpiredWaiver is the waiver which would have ignored Error, had it
+	// not expired.
+	ExpiredWaiver *Waiver
+	ModulesUsed   []string
+	// GoVersion and GoBuildSettings are set for Go binaries.
+	GoVersion       string
+	GoBuildSettings map[string]string
+	// What the checks of a Go binary found: whether it uses no crypto
+	// package, or the native FIPS module, and the golang-fips symbols it
+	// has.
+	GoNoCrypto    bool
+	GoNativeFIPS  bool
+	GoFIPSSymbols []string
 }
 
 type ScanResults struct {
 	Items []*ScanResult
+	OS    *OSInfo // The OS of the image, once detected.
 }
 
 type OpenshiftComponent struct {
@@ -136,13 +213,68 @@ type OpenshiftComponent struct {
 type JavaComponent struct {
 	Entrypoint []string
 	Cmd        []string
+	Env        []string
 	WorkingDir string
 }
 
+// JavaRuntime is a Java runtime (JDK or JRE) found in an image.
+type JavaRuntime struct {
+	Home       string // Java home directory inside the image.
+	Java       string // Path to the java executable inside the image.
+	Javac      string // Path to javac inside the image, if it is a JDK.
+	Entrypoint bool   // Whether the runtime is used by the image entrypoint.
+}
+
+// OSInfo is the operating system detected in an image, and whether it is
+// certified.
 type OSInfo struct {
 	Certified bool
 	Error     *ValidationError
-	Path      string
+	Path      string // The distribution file the OS was detected from.
+
+	ID        string // os-release ID.
+	VersionID string // os-release VERSION_ID.
+	Name      string // os-release PRETTY_NAME.
+	CPE       string // os-release CPE_NAME, or /etc/system-release-cpe.
+	Release   string // The first line of /etc/redhat-release.
+
+	Class ImageClass
+}
+
+// ImageClass is the kind of base image an image is built from.
+type ImageClass string
+
+const (
+	ImageClassRHEL       ImageClass = "rhel"        // Full RHEL, with dnf or yum.
+	ImageClassUBI        ImageClass = "ubi"         // UBI standard, with the UBI repos.
+	ImageClassUBIMinimal ImageClass = "ubi-minimal" // With microdnf only.
+	ImageClassUBIMicro   ImageClass = "ubi-micro"   // With an rpmdb, but no package manager.
+	ImageClassScratch    ImageClass = "scratch"     // No rpmdb: a scratch or static image.
+)
+
+// String returns a short description of the OS and image class, such as
+// "rhel 9.4 (ubi-minimal)".
+func (i *OSInfo) String() string {
+	var os string
+	switch {
+	case i.ID != "" && i.VersionID != "":
+		os = i.ID + " " + i.VersionID
+	case i.Release != "":
+		os = i.Release
+	case i.Name != "":
+		os = i.Name
+	case i.ID != "":
+		os = i.ID
+	default:
+		os = i.CPE
+	}
+	switch {
+	case i.Class == "":
+		return os
+	case os == "":
+		return string(i.Class)
+	}
+	return os + " (" + string(i.Class) + ")"
 }
 
 type ValidationError struct {
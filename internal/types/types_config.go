@@ -1,8 +1,7 @@
 package types
 
 import (
-	"errors"
-	"strings"
+	"context"
 
 	imagev1 "github.com/openshift/api/image/v1"
 	"k8s.io/klog/v2"
@@ -12,14 +11,11 @@ func (c *Config) Log() {
 	klog.V(1).Infof("using config %+v", c)
 }
 
-// isMatch tells if path equals to one of the entries.
-func isMatch(path string, entries []string) bool {
-	for _, f := range entries {
-		if f == path {
-			return true
-		}
-	}
-	return false
+// isMatch tells if path equals to, or matches, one of the entries of the
+// filter list of the section, unless the waiver of the list has expired.
+// The match is recorded for the waivers report.
+func (c *Config) isMatch(section, path string, entries []string, w Waiver) bool {
+	return c.matchList(section, path, entries, w, matchPath)
 }
 
 func (c *Config) isFileIgnoredByComponent(path string, component *OpenshiftComponent) bool {
@@ -27,7 +23,7 @@ func (c *Config) isFileIgnoredByComponent(path string, component *OpenshiftCompo
 		return false
 	}
 	if op, ok := c.PayloadIgnores[component.Component]; ok {
-		return isMatch(path, op.FilterFiles)
+		return c.isMatch(filterSection("payload", component.Component, "filter_files"), path, op.FilterFiles, op.Waiver)
 	}
 	return false
 }
@@ -37,7 +33,7 @@ func (c *Config) isDirIgnoredByComponent(path string, component *OpenshiftCompon
 		return false
 	}
 	if op, ok := c.PayloadIgnores[component.Component]; ok {
-		return isMatch(path, op.FilterDirs)
+		return c.isMatch(filterSection("payload", component.Component, "filter_dirs"), path, op.FilterDirs, op.Waiver)
 	}
 	return false
 }
@@ -47,20 +43,20 @@ func (c *Config) isFileIgnoredByTag(path string, tag *imagev1.TagReference) bool
 		return false
 	}
 	if op, ok := c.TagIgnores[tag.Name]; ok {
-		return isMatch(path, op.FilterFiles)
+		return c.isMatch(filterSection("tag", tag.Name, "filter_files"), path, op.FilterFiles, op.Waiver)
 	}
 	return false
 }
 
 func (c *Config) isFileIgnoredByRPM(path string, rpm string) bool {
 	if op, ok := c.RPMIgnores[rpm]; ok {
-		return isMatch(path, op.FilterFiles)
+		return c.isMatch(filterSection("rpm", rpm, "filter_files"), path, op.FilterFiles, op.Waiver)
 	}
 	return false
 }
 
 func (c *Config) IgnoreFile(path string) bool {
-	return isMatch(path, c.FilterFiles)
+	return c.isMatch(filterSection("", "", "filter_files"), path, c.FilterFiles, Waiver{})
 }
 
 func (c *Config) IgnoreFileWithComponent(path string, component *OpenshiftComponent) bool {
@@ -68,7 +64,7 @@ func (c *Config) IgnoreFileWithComponent(path string, component *OpenshiftCompon
 }
 
 func (c *Config) IgnoreDir(path string) bool {
-	return isMatch(path, c.FilterDirs)
+	return c.isMatch(filterSection("", "", "filter_dirs"), path, c.FilterDirs, Waiver{})
 }
 
 func (c *Config) IgnoreFileWithTag(path string, tag *imagev1.TagReference) bool {
@@ -85,61 +81,27 @@ func (c *Config) IgnoreDirWithComponent(path string, component *OpenshiftCompone
 
 // IgnoreDirPrefix is similar to IgnoreDir. The difference is, this method
 // performs a a prefix match, meaning that "/a/b/c" path supplied will
-// return true if c.FilterDirs contains "/a" or "/a/b".
+// return true if c.FilterDirs contains "/a" or "/a/b" (or "/a/*").
 // This method should be used from code that receives the list of files
 // (such as rpm -ql input), rather than traverses a file tree.
 func (c *Config) IgnoreDirPrefix(path string) bool {
-	for _, dir := range c.FilterDirs {
-		if strings.HasPrefix(path, dir+"/") {
-			return true
-		}
-	}
-	return false
+	return c.matchList(filterSection("", "", "filter_dirs"), path, c.FilterDirs, Waiver{}, matchParentDir)
 }
 
-// Ignore checks if the particular error err is to be ignored for a specified file.
-func (i ErrIgnoreList) Ignore(file string, err error) bool {
-	if len(i) == 0 {
-		return false
-	}
-
-	for _, ie := range i {
-		if !errors.Is(err, ie.Error.Err) {
-			continue
-		}
-		for _, d := range ie.Dirs {
-			if strings.HasPrefix(file, d+"/") {
-				return true
-			}
-		}
-		for _, f := range ie.Files {
-			if file == f {
-				return true
-			}
-		}
-	}
-
-	return false
+// Ignore checks if the particular error err is to be ignored for a specified
+// file, by an entry which has not expired.
+func (i ErrIgnoreList) Ignore(ctx context.Context, file string, err error) bool {
+	ie := i.Match(ctx, file, err)
+	return ie != nil && !ie.Expired()
 }
 
-func (i ErrIgnoreList) IgnoreTag(tag string, err error) bool {
-	if len(i) == 0 {
-		return false
-	}
-	for _, ie := range i {
-		if !errors.Is(err, ie.Error.Err) {
-			continue
-		}
-		for _, t := range ie.Tags {
-			if t == tag {
-				return true
-			}
-		}
-	}
-	return false
+// IgnoreTag is like Ignore, for the tags of the entries.
+func (i ErrIgnoreList) IgnoreTag(ctx context.Context, tag string, err error) bool {
+	ie := i.MatchTag(ctx, tag, err)
+	return ie != nil && !ie.Expired()
 }
 
-func (c *Config) GetCertifiedDistributions() []string {
+func (c *Config) GetCertifiedDistributions() []CertifiedDistribution {
 	return c.ConfigFile.CertifiedDistributions
 }
 
@@ -151,7 +113,7 @@ func (c *Config) UseFIPSModuleValidation() bool {
 	return len(c.GetFIPSCertifiedModules()) > 0
 }
 
-func (c *Config) ShouldIgnoreOSValidation(tag *imagev1.TagReference, component *OpenshiftComponent, osError error) bool {
+func (c *Config) ShouldIgnoreOSValidation(ctx context.Context, tag *imagev1.TagReference, component *OpenshiftComponent, osError error) bool {
 	if tag == nil {
 		return false
 	}
@@ -159,7 +121,7 @@ func (c *Config) ShouldIgnoreOSValidation(tag *imagev1.TagReference, component *
 	// Check component-based ignores first
 	if component != nil {
 		if i, ok := c.PayloadIgnores[component.Component]; ok {
-			if i.ErrIgnores.IgnoreTag(tag.Name, osError) {
+			if i.ErrIgnores.IgnoreTag(ctx, tag.Name, osError) {
 				return true
 			}
 		}
@@ -167,7 +129,7 @@ func (c *Config) ShouldIgnoreOSValidation(tag *imagev1.TagReference, component *
 
 	// Check tag-based ignores (for images without component metadata like rhel-coreos)
 	if i, ok := c.TagIgnores[tag.Name]; ok {
-		if i.ErrIgnores.IgnoreTag(tag.Name, osError) {
+		if i.ErrIgnores.IgnoreTag(ctx, tag.Name, osError) {
 			return true
 		}
 	}
@@ -3,14 +3,31 @@ package types
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
 
+	"github.com/BurntSushi/toml"
 	"go.uber.org/multierr"
 )
 
+// DecodeConfig decodes a toml configuration, rejecting unknown keys.
+func DecodeConfig(data []byte) (*ConfigFile, error) {
+	c := &ConfigFile{}
+	res, err := toml.Decode(string(data), c)
+	if err != nil {
+		return nil, err
+	}
+	if un := res.Undecoded(); len(un) != 0 {
+		return nil, fmt.Errorf("unknown keys in config: %+v", un)
+	}
+	return c, nil
+}
+
 // Validate validates the configuration. Currently it checks that
-// all the file and directory paths are absolute and clean, and that
-// there are no overlaps between each entry files and dirs.
+// all the file and directory paths are absolute and clean, that
+// there are no overlaps between each entry files and dirs, that the
+// severity tables only list known errors, that the rules are complete, and
+// that no waivers have expired.
 // It returns errors and warnings; errors are considered fatal,
 // while warnings are more like FYI.
 func (c *ConfigFile) Validate() (err, warn error) {
@@ -19,6 +36,7 @@ func (c *ConfigFile) Validate() (err, warn error) {
 	validateOverlaps("filter_", &warn, c.FilterFiles, c.FilterDirs)
 
 	validateFIPSCertifiedModules(&err, c.FIPSCertifiedModules)
+	validateSeverity("[severity]", &err, c.Severity)
 
 	validateIgnoreLists("payload", &err, &warn, c.PayloadIgnores)
 	validateIgnoreLists("tag", &err, &warn, c.TagIgnores)
@@ -26,6 +44,8 @@ func (c *ConfigFile) Validate() (err, warn error) {
 
 	validateErrIgnores("[[ignore]]", &err, &warn, c.ErrIgnores)
 
+	validateRules(&err, &warn, c.Rules)
+
 	return
 }
 
@@ -87,12 +107,49 @@ func validateFIPSCertifiedModules(perr *error, modules []FipsModule) {
 		if m.ArtifactSource != "binary" && m.CertifiedArtifact == "" {
 			multierr.AppendInto(perr, &errInvalidFIPSModule{Index: i, Field: "certified_artifact"})
 		}
+		for _, key := range m.CertifiedArtifactSigningKeys {
+			if NormalizeKeyID(key) == "" {
+				multierr.AppendInto(perr, &errBadSigningKey{Index: i, Key: key})
+			}
+		}
+	}
+}
+
+type errBadSigningKey struct {
+	Index int
+	Key   string
+}
+
+func (e *errBadSigningKey) Error() string {
+	return fmt.Sprintf("fips_certified_modules[%d] has invalid certified_artifact_signing_keys entry %q (must be a hex key ID or fingerprint)", e.Index, e.Key)
+}
+
+var keyIDRE = regexp.MustCompile(`^(?:[0-9a-f]{8}|[0-9a-f]{16}|[0-9a-f]{40})$`)
+
+// NormalizeKeyID returns an OpenPGP key ID or fingerprint in lowercase hex,
+// without any "0x" prefix or spaces, or an empty string if it is invalid.
+func NormalizeKeyID(key string) string {
+	key = strings.ToLower(strings.ReplaceAll(key, " ", ""))
+	key = strings.TrimPrefix(key, "0x")
+	if !keyIDRE.MatchString(key) {
+		return ""
 	}
+	return key
 }
 
-// validateFileList checks that the paths in the list are clean and absolute.
+// validateFileList checks that the paths in the list are clean and absolute,
+// and that the patterns are valid.
 func validateFileList(listname string, perr *error, list []string) {
 	for _, f := range list {
+		if isPattern(f) {
+			if err := validatePattern(f); err != nil {
+				multierr.AppendInto(perr, &errBadPattern{listname, f, err})
+				continue
+			}
+			if strings.HasPrefix(f, regexpPrefix) {
+				continue
+			}
+		}
 		cf := filepath.Clean(f)
 		if f != cf {
 			multierr.AppendInto(perr, &errBadPath{listname, f, cf})
@@ -109,6 +166,8 @@ func validateIgnoreLists(listname string, perr, pwarn *error, list map[string]Ig
 		validateFileList(prefix+"].filter_files", perr, v.FilterFiles)
 		validateFileList(prefix+"].filter_dirs", perr, v.FilterDirs)
 		validateOverlaps(prefix+"].filter_", pwarn, v.FilterFiles, v.FilterDirs)
+		validateWaiver(prefix+"]", pwarn, v.Waiver)
+		validateSeverity(prefix+".severity]", perr, v.Severity)
 		validateErrIgnores("["+prefix+".ignore]]", perr, pwarn, v.ErrIgnores)
 	}
 }
@@ -127,17 +186,35 @@ func validateErrIgnores(section string, perr, pwarn *error, l ErrIgnoreList) {
 		validateFileList(prefix+".files", perr, v.Files)
 		validateFileList(prefix+".dirs", perr, v.Dirs)
 		validateOverlaps(prefix+".", pwarn, v.Files, v.Dirs)
+		validateWaiver(prefix, pwarn, v.Waiver)
+	}
+}
+
+type errExpired struct {
+	Listname string
+	Waiver   Waiver
+}
+
+func (e *errExpired) Error() string {
+	return `config entry ` + e.Listname + ` has expired (` + e.Waiver.Describe() + `), and is no longer applied`
+}
+
+// validateWaiver warns about expired waivers.
+func validateWaiver(listname string, pwarn *error, w Waiver) {
+	if w.Expired() {
+		multierr.AppendInto(pwarn, &errExpired{listname, w})
 	}
 }
 
 func validateOverlaps(listname string, perr *error, files, dirs []string) {
-	// First, check that dirs do not overlap.
+	// First, check that dirs do not overlap. A dir matched by a dir
+	// pattern is redundant, too.
 	for i := range dirs {
 		for j := range dirs {
-			if i == j {
+			if i == j || dirs[i] == dirs[j] {
 				continue
 			}
-			if strings.HasPrefix(dirs[i], dirs[j]+"/") {
+			if coveredByDir(dirs[i], dirs[j]) || (!isPattern(dirs[i]) && isPattern(dirs[j]) && matchPath(dirs[j], dirs[i])) {
 				multierr.AppendInto(perr, &errOverlap{listname + "dirs", dirs[i], dirs[j]})
 			}
 		}
@@ -145,7 +222,7 @@ func validateOverlaps(listname string, perr *error, files, dirs []string) {
 	// Now, check that files do not overlap with any dirs.
 	for i := range files {
 		for j := range dirs {
-			if strings.HasPrefix(files[i], dirs[j]+"/") {
+			if coveredByDir(files[i], dirs[j]) {
 				multierr.AppendInto(perr, &errOverlap{listname + "files", files[i], dirs[j]})
 			}
 		}
@@ -158,9 +235,11 @@ func (c *ConfigFile) Add(add *ConfigFile) error {
 	c.FilterFiles = appendUniq("filter_files", &err, c.FilterFiles, add.FilterFiles)
 	c.FilterDirs = appendUniq("filter_dirs", &err, c.FilterDirs, add.FilterDirs)
 	c.FilterImages = appendUniq("filter_images", &err, c.FilterImages, add.FilterImages)
-	c.CertifiedDistributions = appendUniq("certified_distributions", &err, c.CertifiedDistributions, add.CertifiedDistributions)
+	c.JavaDisabledAlgorithms = appendUniq("java_fips_disabled_algorithms", &err, c.JavaDisabledAlgorithms, add.JavaDisabledAlgorithms)
+	c.CertifiedDistributions = appendUniqDistributions("certified_distributions", &err, c.CertifiedDistributions, add.CertifiedDistributions)
 
 	c.FIPSCertifiedModules = mergeFIPSModules(c.FIPSCertifiedModules, add.FIPSCertifiedModules)
+	c.Severity = mergeSeverity(c.Severity, add.Severity)
 
 	c.PayloadIgnores = mergeLists("payload", &err, c.PayloadIgnores, add.PayloadIgnores)
 	c.TagIgnores = mergeLists("tag", &err, c.TagIgnores, add.TagIgnores)
@@ -168,11 +247,16 @@ func (c *ConfigFile) Add(add *ConfigFile) error {
 
 	c.ErrIgnores = mergeErrIgnoreLists("[[ignore]]", &err, c.ErrIgnores, add.ErrIgnores)
 
+	c.Rules = mergeRules(&err, c.Rules, add.Rules)
+	c.sources = mergeSources(c.sources, add.sources)
+
 	return err
 }
 
+// fipsModuleKey identifies a FIPS module entry: the same module may be
+// certified with several artifacts, or versions of the same artifact.
 func fipsModuleKey(m FipsModule) string {
-	return m.Module + "\x00" + m.CertifiedArtifact
+	return fmt.Sprintf("%#v", m)
 }
 
 func mergeFIPSModules(main, add []FipsModule) []FipsModule {
@@ -201,6 +285,17 @@ func (e *errDup) Error() string {
 	return "main config " + e.Listname + " already contains " + e.Dup
 }
 
+type errWaiverConflict struct {
+	Listname string
+	Main     Waiver
+	Add      Waiver
+}
+
+func (e *errWaiverConflict) Error() string {
+	return fmt.Sprintf("main config %s already has reason=%q ticket=%q expires=%q, ignoring reason=%q ticket=%q expires=%q",
+		e.Listname, e.Main.Reason, e.Main.Ticket, e.Main.Expires, e.Add.Reason, e.Add.Ticket, e.Add.Expires)
+}
+
 func contains(list []string, elem string) bool {
 	for _, e := range list {
 		if e == elem {
@@ -233,6 +328,10 @@ func mergeLists(name string, perr *error, main, add map[string]IgnoreLists) map[
 			l.FilterFiles = appendUniq(keyname+"].filter_files", perr, l.FilterFiles, v.FilterFiles)
 			l.FilterDirs = appendUniq(keyname+"].filter_dirs", perr, l.FilterDirs, v.FilterDirs)
 			l.ErrIgnores = mergeErrIgnoreLists("["+keyname+".ignore]]", perr, l.ErrIgnores, v.ErrIgnores)
+			l.Severity = mergeSeverity(l.Severity, v.Severity)
+			if v.Waiver != (Waiver{}) && v.Waiver != l.Waiver {
+				multierr.AppendInto(perr, &errWaiverConflict{keyname + "]", l.Waiver, v.Waiver})
+			}
 			main[k] = l
 		} else {
 			main[k] = v
@@ -247,10 +346,10 @@ func mergeErrIgnoreLists(name string, perr *error, main, add ErrIgnoreList) ErrI
 	}
 
 	for _, a := range add {
-		// See if the error is already in the list.
+		// See if the error is already in the list, with the same waiver.
 		var found *ErrIgnore
 		for i := range main {
-			if main[i].Error.Str == a.Error.Str {
+			if main[i].Error.Str == a.Error.Str && main[i].Waiver == a.Waiver {
 				found = &main[i]
 				break
 			}
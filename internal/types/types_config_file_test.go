@@ -1,7 +1,10 @@
 package types_test
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/stretchr/testify/assert"
@@ -142,6 +145,15 @@ certified_distributions = [ "Red Hat Enterprise Linux release 9.4 (Plow)" ]
 `
 	cd1cd2 = `
 certified_distributions = [ "Red Hat Enterprise Linux release 9.2 (Plow)", "Red Hat Enterprise Linux release 9.4 (Plow)" ]
+`
+	cd3 = `
+certified_distributions = [ { id = "rhel", versions = [ "9.2", "9.4" ] } ]
+`
+	cd1cd3 = `
+certified_distributions = [
+  "Red Hat Enterprise Linux release 9.2 (Plow)",
+  { id = "rhel", versions = [ "9.2", "9.4" ] },
+]
 `
 	fips1 = `
 fips_certified_modules = [
@@ -158,6 +170,33 @@ fips_certified_modules = [
   { module = "openssl", certified_artifact = "openssl-fips-provider", certified_artifact_min_version = "3.0.7" },
   { module = "go", certified_artifact = "go-std" },
 ]
+`
+	wv1 = `
+[[ignore]]
+error = "ErrLibcryptoSoMissing"
+files = [ "/usr/bin/ovnkube-trace" ]
+reason = "statically linked, see the ticket"
+ticket = "OCPBUGS-1234"
+expires = 2999-12-31
+`
+	wv2 = `
+[[ignore]]
+error = "ErrLibcryptoSoMissing"
+files = [ "/usr/bin/other" ]
+ticket = "OCPBUGS-5678"
+`
+	wv1wv2 = `
+[[ignore]]
+error = "ErrLibcryptoSoMissing"
+files = [ "/usr/bin/ovnkube-trace" ]
+reason = "statically linked, see the ticket"
+ticket = "OCPBUGS-1234"
+expires = 2999-12-31
+
+[[ignore]]
+error = "ErrLibcryptoSoMissing"
+files = [ "/usr/bin/other" ]
+ticket = "OCPBUGS-5678"
 `
 )
 
@@ -199,6 +238,25 @@ func TestFIPSValidation(t *testing.T) {
 		}
 	})
 
+	t.Run("signing keys", func(t *testing.T) {
+		cfg := &types.ConfigFile{
+			FIPSCertifiedModules: []types.FipsModule{
+				{
+					Module:                       "openssl",
+					CertifiedArtifact:            "openssl-libs",
+					CertifiedArtifactSigningKeys: []string{"fd431d51", "0x199E2F91FD431D51", "567E 347A D004 4ADE 55BA 8A5F 199E 2F91 FD43 1D51"},
+				},
+			},
+		}
+		if err, _ := cfg.Validate(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		cfg.FIPSCertifiedModules[0].CertifiedArtifactSigningKeys = []string{"Red Hat release key"}
+		if err, _ := cfg.Validate(); err == nil {
+			t.Error("expected error for invalid signing key")
+		}
+	})
+
 	t.Run("max version parses and validates", func(t *testing.T) {
 		cfg := &types.ConfigFile{
 			FIPSCertifiedModules: []types.FipsModule{
@@ -287,12 +345,31 @@ func TestConfigMerge(t *testing.T) {
 			add:      cd2,
 			expected: cd1cd2,
 		},
+		{
+			name:     "cd1 + cd3",
+			main:     cd1,
+			add:      cd3,
+			expected: cd1cd3,
+		},
+		{
+			name:     "cd3 + cd3",
+			main:     cd3,
+			add:      cd3,
+			expected: cd3,
+			expWarns: true,
+		},
 		{
 			name:     "fips1 + fips2 merges modules",
 			main:     fips1,
 			add:      fips2,
 			expected: fips1fips2,
 		},
+		{
+			name:     "wv1 + wv2 keeps waivers apart",
+			main:     wv1,
+			add:      wv2,
+			expected: wv1wv2,
+		},
 		{
 			name:     "fips1 + fips1 deduplicates",
 			main:     fips1,
@@ -316,3 +393,142 @@ func TestConfigMerge(t *testing.T) {
 		})
 	}
 }
+
+func TestCertifiedDistributions(t *testing.T) {
+	for _, src := range []string{
+		`certified_distributions = [ 9 ]`,
+		`certified_distributions = [ { versions = [ "9.4" ] } ]`,
+		`certified_distributions = [ { id = "rhel", versions = [ 9.4 ] } ]`,
+		`certified_distributions = [ { id = "rhel", version = "9.4" } ]`,
+	} {
+		var cfg types.ConfigFile
+		if _, err := toml.Decode(src, &cfg); err == nil {
+			t.Errorf("%s: expected an error", src)
+		}
+	}
+
+	rhel94 := &types.OSInfo{
+		ID:        "rhel",
+		VersionID: "9.4",
+		CPE:       "cpe:/o:redhat:enterprise_linux:9::baseos",
+		Release:   "Red Hat Enterprise Linux release 9.4 (Plow)",
+	}
+	rhel940 := &types.OSInfo{ID: "rhel", VersionID: "9.40", Release: "Red Hat Enterprise Linux release 9.40 (Plow)"}
+	testCases := []struct {
+		name string
+		d    types.CertifiedDistribution
+		os   *types.OSInfo
+		want bool
+	}{
+		{name: "id and version", d: types.CertifiedDistribution{ID: "rhel", Versions: []string{"9.2", "9.4"}}, os: rhel94, want: true},
+		{name: "id and longer version", d: types.CertifiedDistribution{ID: "rhel", Versions: []string{"9.2", "9.4"}}, os: rhel940},
+		{name: "id only", d: types.CertifiedDistribution{ID: "rhel"}, os: rhel940, want: true},
+		{name: "other id", d: types.CertifiedDistribution{ID: "centos"}, os: rhel94},
+		{name: "release", d: types.CertifiedDistribution{Release: "Red Hat Enterprise Linux release 9.4"}, os: rhel94, want: true},
+		{name: "release prefix of longer version", d: types.CertifiedDistribution{Release: "Red Hat Enterprise Linux release 9.4"}, os: rhel940},
+		{name: "cpe", d: types.CertifiedDistribution{Release: "cpe:/o:redhat:enterprise_linux:9::baseos"}, os: rhel94, want: true},
+		{name: "cpe prefix", d: types.CertifiedDistribution{Release: "cpe:/o:redhat:enterprise_linux:9"}, os: rhel94},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.d.Matches(tc.os); got != tc.want {
+				t.Errorf("%v.Matches(%+v): got %v, want %v", tc.d, tc.os, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWaivers(t *testing.T) {
+	for _, src := range []string{
+		"[[ignore]]\nerror = \"ErrNotDynLinked\"\nfiles = [ \"/a\" ]\nexpires = \"next year\"",
+		"[[ignore]]\nerror = \"ErrNotDynLinked\"\nfiles = [ \"/a\" ]\nexpires = 20261231",
+	} {
+		var cfg types.ConfigFile
+		if _, err := toml.Decode(src, &cfg); err == nil {
+			t.Errorf("%s: expected an error", src)
+		}
+	}
+
+	cfg, err := types.DecodeConfig([]byte(wv1 + `
+[[ignore]]
+error = "ErrNotDynLinked"
+files = [ "/usr/bin/expired" ]
+ticket = "OCPBUGS-1"
+expires = "2020-01-31"
+
+[rpm.foo]
+filter_files = [ "/usr/bin/foo" ]
+ticket = "OCPBUGS-2"
+expires = 2020-01-31
+`))
+	require.NoError(t, err)
+	assert.Equal(t, types.Waiver{
+		Reason:  "statically linked, see the ticket",
+		Ticket:  "OCPBUGS-1234",
+		Expires: types.NewDate(time.Date(2999, 12, 31, 0, 0, 0, 0, time.UTC)),
+	}, cfg.ErrIgnores[0].Waiver)
+	assert.False(t, cfg.ErrIgnores[0].Expired())
+	assert.True(t, cfg.ErrIgnores[1].Expired())
+
+	// Expired waivers are reported by Validate.
+	err, warn := cfg.Validate()
+	require.NoError(t, err)
+	assert.ErrorContains(t, warn, "[[ignore]].error=ErrNotDynLinked has expired (ticket OCPBUGS-1, expired on 2020-01-31)")
+	assert.ErrorContains(t, warn, "[rpm.foo] has expired (ticket OCPBUGS-2, expired on 2020-01-31)")
+
+	// Expired waivers do not apply.
+	c := &types.Config{ConfigFile: *cfg}
+	ctx := c.WithExceptions(context.Background())
+	assert.True(t, c.ErrIgnores.Ignore(ctx, "/usr/bin/ovnkube-trace", types.ErrLibcryptoSoMissing))
+	assert.False(t, c.ErrIgnores.Ignore(ctx, "/usr/bin/expired", types.ErrNotDynLinked))
+	assert.Equal(t, &c.ErrIgnores[1], c.ErrIgnores.Match(ctx, "/usr/bin/expired", types.ErrNotDynLinked))
+	assert.False(t, c.IgnoreFileByRpm("/usr/bin/foo", "foo"))
+
+	assert.Equal(t, []types.ExceptionUse{
+		{Section: "[[ignore]].error=ErrLibcryptoSoMissing.files", Entry: "/usr/bin/ovnkube-trace", Waiver: cfg.ErrIgnores[0].Waiver, Paths: []string{"/usr/bin/ovnkube-trace"}},
+		{Section: "[[ignore]].error=ErrNotDynLinked.files", Entry: "/usr/bin/expired", Waiver: cfg.ErrIgnores[1].Waiver, Paths: []string{"/usr/bin/expired"}},
+		{Section: "[rpm.foo].filter_files", Entry: "/usr/bin/foo", Waiver: cfg.RPMIgnores["foo"].Waiver, Paths: []string{"/usr/bin/foo"}},
+	}, c.AppliedWaivers())
+
+	// Waivers are written back as they were read.
+	var sb strings.Builder
+	require.NoError(t, toml.NewEncoder(&sb).Encode(decode(t, wv1)))
+	assert.Contains(t, sb.String(), `ticket = "OCPBUGS-1234"`)
+	assert.Contains(t, sb.String(), "expires = 2999-12-31\n")
+	assert.Equal(t, decode(t, wv1), decode(t, sb.String()))
+}
+
+func TestUnusedExceptions(t *testing.T) {
+	cfg := decode(t, `
+filter_files = [ "/usr/bin/used", "/usr/bin/gone" ]
+filter_dirs = [ "/usr/share/*" ]
+
+[[ignore]]
+error = "ErrNotDynLinked"
+files = [ "/usr/bin/static", "/usr/bin/gone" ]
+dirs = [ "/opt/static" ]
+
+[payload.gone-component]
+filter_files = [ "/usr/bin/gone" ]
+
+[tag.rhel-coreos]
+[[tag.rhel-coreos.ignore]]
+error = "ErrOSNotCertified"
+tags = [ "rhel-coreos" ]
+`)
+	c := &types.Config{ConfigFile: *cfg}
+	ctx := c.WithExceptions(context.Background())
+	assert.True(t, c.IgnoreFile("/usr/bin/used"))
+	assert.True(t, c.IgnoreDirPrefix("/usr/share/doc/README"))
+	assert.True(t, c.ErrIgnores.Ignore(ctx, "/opt/static/bin/a", types.ErrNotDynLinked))
+	assert.True(t, c.ErrIgnores.Ignore(ctx, "/usr/bin/static", types.ErrNotDynLinked))
+	assert.False(t, c.ErrIgnores.Ignore(ctx, "/usr/bin/gone", types.ErrGoMissingTag))
+	assert.True(t, c.TagIgnores["rhel-coreos"].ErrIgnores.IgnoreTag(ctx, "rhel-coreos", types.ErrOSNotCertified))
+
+	assert.Equal(t, []types.ExceptionUse{
+		{Section: "filter_files", Entry: "/usr/bin/gone", Paths: []string{}},
+		{Section: "[[ignore]].error=ErrNotDynLinked.files", Entry: "/usr/bin/gone", Paths: []string{}},
+		{Section: "[payload.gone-component].filter_files", Entry: "/usr/bin/gone", Paths: []string{}},
+	}, c.UnusedExceptions())
+	assert.Equal(t, "unused", c.UnusedExceptions()[0].Status())
+}
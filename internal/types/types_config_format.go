@@ -0,0 +1,106 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"sigs.k8s.io/yaml"
+)
+
+// The config file formats.
+const (
+	FormatTOML = "toml"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// yamlKeyRe matches the first line of a yaml config: a document start, or
+// a key followed by a colon (toml keys are followed by an equal sign).
+var yamlKeyRe = regexp.MustCompile(`^(---|[\w"'-]+\s*:)`)
+
+// ConfigFormat returns the format of the config file name, from its
+// extension (.toml, .json, .yaml or .yml) or, if it has another one, from
+// the first line of data which is not blank nor a comment.
+func ConfigFormat(name string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".toml":
+		return FormatTOML
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		switch {
+		case line[0] == '{':
+			return FormatJSON
+		case yamlKeyRe.MatchString(line):
+			return FormatYAML
+		}
+		return FormatTOML
+	}
+	return FormatTOML
+}
+
+// DecodeConfigFile decodes the config data of file name, in the format
+// ConfigFormat returns for it, rejecting unknown keys.
+func DecodeConfigFile(name string, data []byte) (*ConfigFile, error) {
+	switch ConfigFormat(name, data) {
+	case FormatJSON:
+		return decodeJSONConfig(data)
+	case FormatYAML:
+		j, err := yaml.YAMLToJSONStrict(data)
+		if err != nil {
+			return nil, err
+		}
+		return decodeJSONConfig(j)
+	}
+	return DecodeConfig(data)
+}
+
+func decodeJSONConfig(data []byte) (*ConfigFile, error) {
+	c := &ConfigFile{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the config at offset %d", dec.InputOffset())
+	}
+	return c, nil
+}
+
+// EncodeConfigFile encodes the config in the format ConfigFormat returns for
+// file name.
+func EncodeConfigFile(name string, c *ConfigFile) ([]byte, error) {
+	var buf bytes.Buffer
+	format := ConfigFormat(name, nil)
+	if format == FormatTOML {
+		enc := toml.NewEncoder(&buf)
+		enc.Indent = ""
+		if err := enc.Encode(c); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	if format == FormatYAML {
+		return yaml.JSONToYAML(buf.Bytes())
+	}
+	return buf.Bytes(), nil
+}
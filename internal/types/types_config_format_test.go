@@ -0,0 +1,116 @@
+package types_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+func TestConfigFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name, data, want string
+	}{
+		{"a.toml", "", types.FormatTOML},
+		{"a.JSON", "", types.FormatJSON},
+		{"a.yml", "", types.FormatYAML},
+		{"a.yaml", "filter_files = []", types.FormatYAML},
+		{"a", "", types.FormatTOML},
+		{"a", "# comment\n\nfilter_files = [\"/a\"]\n", types.FormatTOML},
+		{"a", "[[ignore]]\n", types.FormatTOML},
+		{"a", "\n  {\"filter_files\": []}", types.FormatJSON},
+		{"a", "# comment\nfilter_files:\n- /a\n", types.FormatYAML},
+		{"a", "---\n", types.FormatYAML},
+	} {
+		assert.Equal(t, tc.want, types.ConfigFormat(tc.name, []byte(tc.data)), "%s %q", tc.name, tc.data)
+	}
+}
+
+func TestDecodeConfigFile(t *testing.T) {
+	want := decode(t, `
+filter_files = ["/usr/bin/a"]
+certified_distributions = ["Red Hat Enterprise Linux release 9.4 (Plow)", { id = "rhel", versions = ["9.6"] }]
+
+[payload.foo]
+filter_dirs = ["/opt"]
+ticket = "OCPBUGS-1"
+
+[[payload.foo.ignore]]
+error = "ErrNotDynLinked"
+files = ["/usr/bin/b"]
+
+[[ignore]]
+error = "ErrGoNotCgoEnabled"
+dirs = ["/usr/libexec"]
+expires = 2999-12-31
+`)
+
+	for name, src := range map[string]string{
+		"config.json": `{
+  "filter_files": ["/usr/bin/a"],
+  "certified_distributions": ["Red Hat Enterprise Linux release 9.4 (Plow)", {"id": "rhel", "versions": ["9.6"]}],
+  "payload": {
+    "foo": {
+      "filter_dirs": ["/opt"],
+      "ticket": "OCPBUGS-1",
+      "ignore": [{"error": "ErrNotDynLinked", "files": ["/usr/bin/b"]}]
+    }
+  },
+  "ignore": [{"error": "ErrGoNotCgoEnabled", "dirs": ["/usr/libexec"], "expires": "2999-12-31"}]
+}`,
+		"config.yaml": `
+filter_files: [/usr/bin/a]
+certified_distributions:
+- Red Hat Enterprise Linux release 9.4 (Plow)
+- id: rhel
+  versions: ["9.6"]
+payload:
+  foo:
+    filter_dirs: [/opt]
+    ticket: OCPBUGS-1
+    ignore:
+    - error: ErrNotDynLinked
+      files: [/usr/bin/b]
+ignore:
+- error: ErrGoNotCgoEnabled
+  dirs: [/usr/libexec]
+  expires: 2999-12-31
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := types.DecodeConfigFile(name, []byte(src))
+			require.NoError(t, err)
+			assert.Equal(t, want, cfg)
+		})
+	}
+
+	for name, src := range map[string]string{
+		"a.json": `{"filter_file": ["/a"]}`,
+		"a.yaml": "ignore:\n- error: ErrNotDynLinked\n  file: [/a]\n",
+	} {
+		_, err := types.DecodeConfigFile(name, []byte(src))
+		assert.ErrorContains(t, err, "unknown field", name)
+	}
+	_, err := types.DecodeConfigFile("a.json", []byte(`{"ignore": [{"error": "ErrFoo", "files": ["/a"]}]}`))
+	assert.ErrorContains(t, err, `error="ErrFoo" is not recognized`)
+	_, err = types.DecodeConfigFile("a.json", []byte(`{} {}`))
+	assert.Error(t, err)
+}
+
+func TestEncodeConfigFile(t *testing.T) {
+	data, err := os.ReadFile("../../config.toml")
+	require.NoError(t, err)
+	want, err := types.DecodeConfig(data)
+	require.NoError(t, err)
+
+	for _, name := range []string{"config.toml", "config.json", "config.yaml"} {
+		data, err := types.EncodeConfigFile(name, want)
+		require.NoError(t, err)
+		cfg, err := types.DecodeConfigFile(name, data)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, cfg, name)
+	}
+}
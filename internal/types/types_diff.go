@@ -0,0 +1,178 @@
+package types
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+)
+
+// ConfigChange is the difference between two configs in one of their lists.
+type ConfigChange struct {
+	Section string   `json:"section"`         // Such as payload.foo; empty for the top level.
+	Error   string   `json:"error,omitempty"` // Set for the lists of ignore entries.
+	List    string   `json:"list"`            // Such as filter_files, or files for ignore entries.
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// String returns the name of the list, as used in the config validation
+// errors, such as [[payload.foo.ignore]].error=ErrNotDynLinked.files.
+func (c ConfigChange) String() string {
+	if c.Error == "" {
+		if c.Section == "" {
+			return c.List
+		}
+		return "[" + c.Section + "]." + c.List
+	}
+	table := "ignore"
+	if c.Section != "" {
+		table = c.Section + ".ignore"
+	}
+	return "[[" + table + "]].error=" + c.Error + "." + c.List
+}
+
+var (
+	diffSections = []string{"", "payload", "tag", "rpm"}
+	diffLists    = []string{
+		"filter_files", "filter_dirs", "filter_images", "java_fips_disabled_algorithms",
+		"certified_distributions", "fips_certified_modules", "severity", "rule",
+		"files", "dirs", "tags",
+	}
+)
+
+// The indexes of the lists in diffLists.
+const (
+	diffFilterFiles = iota
+	diffFilterDirs
+	diffFilterImages
+	diffJavaDisabledAlgorithms
+	diffCertifiedDistributions
+	diffFIPSModules
+	diffSeverity
+	diffRules
+	diffFiles
+	diffDirs
+	diffTags
+)
+
+// diffKey identifies a list of a config. The fields are indexes in
+// diffSections and diffLists, so that keys sort in the config order.
+type diffKey struct {
+	section int
+	name    string
+	errName string
+	list    int
+}
+
+func (k diffKey) compare(o diffKey) int {
+	return cmp.Or(
+		cmp.Compare(k.section, o.section),
+		cmp.Compare(k.name, o.name),
+		cmp.Compare(k.errName, o.errName),
+		cmp.Compare(k.list, o.list))
+}
+
+// diffJSON returns v as compact json, for the entries of the lists which
+// are not strings.
+func diffJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// severityEntries returns the entries of a severity table, such as
+// ErrNotDynLinked = warning.
+func severityEntries(severity map[string]ErrorLevel) []string {
+	var res []string
+	for name, level := range severity {
+		res = append(res, name+" = "+level.String())
+	}
+	return res
+}
+
+// diffEntries returns all the lists of the config. Entries with the same
+// error in the same section are merged, whatever their waivers. The entries
+// of the lists which are not strings, such as the fips_certified_modules,
+// are written as json.
+func (c *ConfigFile) diffEntries() map[diffKey][]string {
+	res := make(map[diffKey][]string)
+	add := func(k diffKey, l []string) {
+		if len(l) > 0 {
+			res[k] = append(res[k], l...)
+		}
+	}
+	addErrIgnores := func(section int, name string, l ErrIgnoreList) {
+		for _, ie := range l {
+			add(diffKey{section, name, ie.Error.Str, diffFiles}, ie.Files)
+			add(diffKey{section, name, ie.Error.Str, diffDirs}, ie.Dirs)
+			add(diffKey{section, name, ie.Error.Str, diffTags}, ie.Tags)
+		}
+	}
+
+	add(diffKey{list: diffFilterFiles}, c.FilterFiles)
+	add(diffKey{list: diffFilterDirs}, c.FilterDirs)
+	add(diffKey{list: diffFilterImages}, c.FilterImages)
+	add(diffKey{list: diffJavaDisabledAlgorithms}, c.JavaDisabledAlgorithms)
+	for _, d := range c.CertifiedDistributions {
+		add(diffKey{list: diffCertifiedDistributions}, []string{d.String()})
+	}
+	for _, m := range c.FIPSCertifiedModules {
+		add(diffKey{list: diffFIPSModules}, []string{diffJSON(m)})
+	}
+	add(diffKey{list: diffSeverity}, severityEntries(c.Severity))
+	for _, r := range c.Rules {
+		add(diffKey{list: diffRules}, []string{diffJSON(r)})
+	}
+	addErrIgnores(0, "", c.ErrIgnores)
+	for section, lists := range []map[string]IgnoreLists{nil, c.PayloadIgnores, c.TagIgnores, c.RPMIgnores} {
+		for name, l := range lists {
+			add(diffKey{section, name, "", diffFilterFiles}, l.FilterFiles)
+			add(diffKey{section, name, "", diffFilterDirs}, l.FilterDirs)
+			add(diffKey{section, name, "", diffSeverity}, severityEntries(l.Severity))
+			addErrIgnores(section, name, l.ErrIgnores)
+		}
+	}
+	return res
+}
+
+// missing returns the sorted entries of list which are not in other.
+func missing(list, other []string) []string {
+	var res []string
+	for _, e := range list {
+		if !slices.Contains(other, e) {
+			res = append(res, e)
+		}
+	}
+	slices.Sort(res)
+	return slices.Compact(res)
+}
+
+// DiffConfigs returns the entries added to and removed from the lists of
+// the old config in the new one, per section, error, and list, in the
+// config order.
+func DiffConfigs(oldCfg, newCfg *ConfigFile) []ConfigChange {
+	o, n := oldCfg.diffEntries(), newCfg.diffEntries()
+	keys := make([]diffKey, 0, len(o)+len(n))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	for k := range n {
+		if _, ok := o[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, diffKey.compare)
+
+	var res []ConfigChange
+	for _, k := range keys {
+		added, removed := missing(n[k], o[k]), missing(o[k], n[k])
+		if len(added)+len(removed) == 0 {
+			continue
+		}
+		c := ConfigChange{Error: k.errName, List: diffLists[k.list], Added: added, Removed: removed}
+		if k.section > 0 {
+			c.Section = diffSections[k.section] + "." + k.name
+		}
+		res = append(res, c)
+	}
+	return res
+}
@@ -0,0 +1,124 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	oldCfg := decode(t, `
+filter_files = ["/usr/bin/a", "/usr/bin/b"]
+
+[payload.foo]
+filter_dirs = ["/opt"]
+
+[[payload.foo.ignore]]
+error = "ErrNotDynLinked"
+files = ["/usr/bin/c"]
+
+[[rpm.bar.ignore]]
+error = "ErrGoNotCgoEnabled"
+files = ["/usr/bin/d"]
+
+[[ignore]]
+error = "ErrNotDynLinked"
+files = ["/usr/bin/e"]
+`)
+	newCfg := decode(t, `
+filter_files = ["/usr/bin/b", "/usr/bin/a2"]
+
+[payload.foo]
+filter_dirs = ["/opt"]
+
+[[payload.foo.ignore]]
+error = "ErrNotDynLinked"
+files = ["/usr/bin/c"]
+dirs = ["/usr/libexec"]
+
+[[payload.foo.ignore]]
+error = "ErrGoNotCgoEnabled"
+files = ["/usr/bin/f"]
+
+[[ignore]]
+error = "ErrNotDynLinked"
+files = ["/usr/bin/e"]
+reason = "statically linked"
+`)
+
+	want := []types.ConfigChange{
+		{List: "filter_files", Added: []string{"/usr/bin/a2"}, Removed: []string{"/usr/bin/a"}},
+		{Section: "payload.foo", Error: "ErrGoNotCgoEnabled", List: "files", Added: []string{"/usr/bin/f"}},
+		{Section: "payload.foo", Error: "ErrNotDynLinked", List: "dirs", Added: []string{"/usr/libexec"}},
+		{Section: "rpm.bar", Error: "ErrGoNotCgoEnabled", List: "files", Removed: []string{"/usr/bin/d"}},
+	}
+	assert.Equal(t, want, types.DiffConfigs(oldCfg, newCfg))
+	assert.Empty(t, types.DiffConfigs(newCfg, newCfg))
+
+	names := []string{"filter_files", "[[payload.foo.ignore]].error=ErrGoNotCgoEnabled.files"}
+	for i, name := range names {
+		assert.Equal(t, name, want[i].String())
+	}
+	assert.Equal(t, "[payload.foo].filter_dirs", types.ConfigChange{Section: "payload.foo", List: "filter_dirs"}.String())
+	assert.Equal(t, "[[ignore]].error=ErrNotDynLinked.files", types.ConfigChange{Error: "ErrNotDynLinked", List: "files"}.String())
+}
+
+func TestDiffConfigsTables(t *testing.T) {
+	oldCfg := decode(t, `
+certified_distributions = ["Red Hat Enterprise Linux release 9.4 (Plow)"]
+
+[severity]
+ErrGoNoTags = "warning"
+
+[payload.foo.severity]
+ErrNotDynLinked = "warning"
+
+[[fips_certified_modules]]
+module = "openssl"
+certified_artifact = "openssl-libs"
+certified_artifact_min_version = "3.0.7"
+
+[[rule]]
+name = "a"
+when = "error == 'ErrNotDynLinked'"
+action = "warning"
+`)
+	newCfg := decode(t, `
+certified_distributions = [{ id = "rhel", versions = ["9.4"] }]
+
+[severity]
+ErrGoNoTags = "error"
+
+[payload.foo.severity]
+ErrNotDynLinked = "warning"
+
+[[fips_certified_modules]]
+module = "openssl"
+certified_artifact = "openssl-libs"
+certified_artifact_min_version = "3.0.7"
+
+[[fips_certified_modules]]
+module = "openssl"
+certified_artifact = "openssl-libs"
+certified_artifact_min_version = "1.1.1"
+
+[[rule]]
+name = "a"
+when = "error == 'ErrNotDynLinked'"
+action = "ignore"
+`)
+
+	want := []types.ConfigChange{
+		{List: "certified_distributions", Added: []string{`{ id = "rhel", versions = ["9.4"] }`}, Removed: []string{"Red Hat Enterprise Linux release 9.4 (Plow)"}},
+		{List: "fips_certified_modules", Added: []string{`{"module":"openssl","certified_artifact":"openssl-libs","certified_artifact_min_version":"1.1.1"}`}},
+		{List: "severity", Added: []string{"ErrGoNoTags = error"}, Removed: []string{"ErrGoNoTags = warning"}},
+		{List: "rule", Added: []string{`{"name":"a","when":"error == 'ErrNotDynLinked'","action":"ignore"}`}, Removed: []string{`{"name":"a","when":"error == 'ErrNotDynLinked'","action":"warning"}`}},
+	}
+	assert.Equal(t, want, types.DiffConfigs(oldCfg, newCfg))
+
+	newCfg.PayloadIgnores["foo"].Severity["ErrNotDynLinked"] = types.Error
+	assert.Equal(t, types.ConfigChange{Section: "payload.foo", List: "severity", Added: []string{"ErrNotDynLinked = error"}, Removed: []string{"ErrNotDynLinked = warning"}},
+		types.DiffConfigs(oldCfg, newCfg)[4])
+}
@@ -0,0 +1,159 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+// CertifiedDistribution is a certified_distributions entry. It is either a
+// string, matched against /etc/redhat-release (or, if it starts with
+// "cpe:", against the OS CPE name), or a table matched against the
+// os-release ID and VERSION_ID, such as:
+//
+//	{ id = "rhel", versions = ["9.2", "9.4"] }
+//
+// An entry with no versions matches any version of the distribution.
+type CertifiedDistribution struct {
+	Release  string
+	ID       string
+	Versions []string
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (d *CertifiedDistribution) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case string:
+		*d = CertifiedDistribution{Release: v}
+		return nil
+	case map[string]any:
+		*d = CertifiedDistribution{}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			switch k {
+			case "id":
+				id, ok := v[k].(string)
+				if !ok {
+					return errors.New("certified_distributions: id must be a string")
+				}
+				d.ID = id
+			case "versions":
+				list, ok := v[k].([]any)
+				if !ok {
+					return errors.New("certified_distributions: versions must be a list of strings")
+				}
+				for _, e := range list {
+					s, ok := e.(string)
+					if !ok {
+						return errors.New("certified_distributions: versions must be a list of strings")
+					}
+					d.Versions = append(d.Versions, s)
+				}
+			default:
+				return fmt.Errorf("certified_distributions: unknown key %q", k)
+			}
+		}
+		if d.ID == "" {
+			return errors.New("certified_distributions: id is not set")
+		}
+		return nil
+	}
+	return fmt.Errorf("certified_distributions: entry must be a string or a table, not %T", v)
+}
+
+// UnmarshalJSON is used when parsing json and yaml config; the entries are
+// the same as in toml.
+func (d *CertifiedDistribution) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return d.UnmarshalTOML(v)
+}
+
+// MarshalJSON is used when writing the configuration as json.
+func (d CertifiedDistribution) MarshalJSON() ([]byte, error) {
+	if d.ID == "" {
+		return json.Marshal(d.Release)
+	}
+	return json.Marshal(struct {
+		ID       string   `json:"id"`
+		Versions []string `json:"versions,omitempty"`
+	}{d.ID, d.Versions})
+}
+
+// MarshalTOML implements toml.Marshaler.
+func (d CertifiedDistribution) MarshalTOML() ([]byte, error) {
+	if d.ID == "" {
+		return []byte(strconv.Quote(d.Release)), nil
+	}
+	return []byte(d.String()), nil
+}
+
+// String is used when printing the current configuration.
+func (d CertifiedDistribution) String() string {
+	if d.ID == "" {
+		return d.Release
+	}
+	s := "{ id = " + strconv.Quote(d.ID)
+	if len(d.Versions) > 0 {
+		vs := make([]string, len(d.Versions))
+		for i, v := range d.Versions {
+			vs[i] = strconv.Quote(v)
+		}
+		s += ", versions = [" + strings.Join(vs, ", ") + "]"
+	}
+	return s + " }"
+}
+
+// IsCPE tells whether the entry is a CPE name.
+func (d CertifiedDistribution) IsCPE() bool {
+	return d.ID == "" && strings.HasPrefix(d.Release, "cpe:")
+}
+
+// Matches tells whether the detected OS is the certified distribution.
+// Versions are compared exactly, so "9.4" does not match "9.40". A release
+// string matches the beginning of the release file, up to a version
+// boundary.
+func (d CertifiedDistribution) Matches(info *OSInfo) bool {
+	switch {
+	case d.ID != "":
+		if info.ID != d.ID {
+			return false
+		}
+		return len(d.Versions) == 0 || contains(d.Versions, info.VersionID)
+	case d.IsCPE():
+		return info.CPE != "" && info.CPE == d.Release
+	}
+	if d.Release == "" || !strings.HasPrefix(info.Release, d.Release) {
+		return false
+	}
+	rest := info.Release[len(d.Release):]
+	return rest == "" || rest[0] < '0' || rest[0] > '9'
+}
+
+func appendUniqDistributions(listname string, perr *error, main, add []CertifiedDistribution) []CertifiedDistribution {
+	seen := make(map[string]bool, len(main))
+	for _, d := range main {
+		seen[d.String()] = true
+	}
+	for _, d := range add {
+		key := d.String()
+		if seen[key] {
+			multierr.AppendInto(perr, &errDup{listname, key})
+			continue
+		}
+		seen[key] = true
+		main = append(main, d)
+	}
+	return main
+}
@@ -0,0 +1,338 @@
+package types
+
+import "sort"
+
+// ErrorInfo documents a known error: what it means, why it is usually
+// found, and how to fix it. It is used by the explain command and in the
+// json, sarif and html reports.
+type ErrorInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Causes      []string `json:"causes"`
+	Remediation []string `json:"remediation"`
+	Validator   string   `json:"validator"`             // The check which reports it.
+	GoVersions  string   `json:"go_versions,omitempty"` // For the errors of Go executables.
+}
+
+// goLegacyVersions are the Go versions the golang-fips (OpenSSL) checks
+// apply to: from 1.26 on, binaries with the native FIPS module enabled are
+// checked for it instead, and from 1.27 on all of them are.
+const goLegacyVersions = ">= 1.18, < 1.27 (without the native FIPS module)"
+
+// errorInfo has the ErrorInfo of all the KnownErrors, but for their names.
+var errorInfo = map[string]ErrorInfo{
+	"ErrCertifiedDistributionsEmpty": {
+		Description: "The config has no certified_distributions, so the OS of the images can't be checked.",
+		Causes: []string{
+			"check-payload was run without -V, with a config which does not set certified_distributions.",
+		},
+		Remediation: []string{
+			"Run with -V <version> to add the embedded config of the OpenShift release.",
+			"Or set certified_distributions in the config.",
+		},
+		Validator: "ValidateOS (image OS check)",
+	},
+	"ErrDistributionFileMissing": {
+		Description: "The image has no /etc/redhat-release or /etc/os-release, so its OS can't be identified.",
+		Causes: []string{
+			"The image is built FROM scratch or a distroless base.",
+			"The release files were removed to slim the image down.",
+		},
+		Remediation: []string{
+			"Base the image on a certified RHEL or UBI image.",
+			"For scratch images, make sure the binaries do no crypto, or ship the FIPS certified artifacts of the modules they use.",
+		},
+		Validator: "DetectOS, ValidateOS (image OS check)",
+	},
+	"ErrFipsArtifactMissing": {
+		Description: "A crypto module is used in the image, but the FIPS certified artifact for it (see fips_certified_modules) is not found.",
+		Causes: []string{
+			"The package providing the artifact (such as openssl-libs for the OpenSSL FIPS provider) is not installed in the final image.",
+			"A minimal base image (ubi-micro, scratch) without the module's libraries.",
+		},
+		Remediation: []string{
+			"Install the package providing the certified artifact in the final stage of the Dockerfile, e.g. `RUN microdnf install -y openssl-libs`.",
+			"If the artifact is elsewhere, fix certified_artifact_paths in fips_certified_modules.",
+		},
+		Validator: "ValidateModule, CheckArtifact (FIPS module artifact check)",
+	},
+	"ErrFipsArtifactTampered": {
+		Description: "The FIPS certified artifact differs from the one its RPM installed.",
+		Causes: []string{
+			"The file was overwritten or patched after the package was installed.",
+			"The file was copied from another image or build stage.",
+		},
+		Remediation: []string{
+			"Reinstall the package instead of copying or modifying its files, and check with `rpm -V <package>`.",
+		},
+		Validator: "CheckArtifact (FIPS module artifact check)",
+	},
+	"ErrFipsArtifactUntrusted": {
+		Description: "The RPM of the FIPS certified artifact is not from the certified vendor, or not signed with a trusted key.",
+		Causes: []string{
+			"The package was installed from a third-party repository, or built locally.",
+			"The signing key is missing from certified_artifact_signing_keys.",
+		},
+		Remediation: []string{
+			"Install the package from the Red Hat repositories.",
+			"If the key is legitimate, add it to certified_artifact_signing_keys.",
+		},
+		Validator: "CheckArtifact (FIPS module artifact check)",
+	},
+	"ErrFipsArtifactVersionHigh": {
+		Description: "The FIPS certified artifact is newer than the latest certified version.",
+		Causes: []string{
+			"The base image or package was updated to a version which is not certified yet.",
+		},
+		Remediation: []string{
+			"Pin the package, or base image, to a certified version.",
+			"Once the new version is certified, raise certified_artifact_max_version.",
+		},
+		Validator: "CheckArtifact (FIPS module artifact check)",
+	},
+	"ErrFipsArtifactVersionLow": {
+		Description: "The FIPS certified artifact is older than the minimum certified version.",
+		Causes: []string{
+			"An outdated base image, or a package pinned to an old version.",
+		},
+		Remediation: []string{
+			"Update the base image, or the package (e.g. `dnf update openssl-libs`).",
+		},
+		Validator: "CheckArtifact (FIPS module artifact check)",
+	},
+	"ErrGoFIPSNotCertified": {
+		Description: "The Go binary enables the native FIPS 140 mode, but is not built with a certified Go FIPS module.",
+		Causes: []string{
+			"GOFIPS140 was not set when building, so the latest (uncertified) module is used.",
+		},
+		Remediation: []string{
+			"Build with GOFIPS140 set to a certified module version, e.g. `GOFIPS140=v1.0.0 go build`.",
+			"Or build with the Red Hat Go toolset, which sets it.",
+		},
+		Validator:  "validateGoNativeFIPS (Go executables)",
+		GoVersions: ">= 1.26",
+	},
+	"ErrGoFIPSNotEnabled": {
+		Description: "The Go binary does not enable the native FIPS 140 mode (fips140=auto, on or only in its default GODEBUG).",
+		Causes: []string{
+			"From Go 1.27 on, and in Go 1.26 when a fips140 GODEBUG is set, binaries must use the native FIPS module.",
+			"The main package, or go.mod, sets no fips140 GODEBUG.",
+		},
+		Remediation: []string{
+			"Add `//go:debug fips140=auto` to the main package, or `godebug fips140=auto` to go.mod.",
+			"Or build with the Red Hat Go toolset, which sets fips140=on.",
+		},
+		Validator:  "validateGoNativeFIPS (Go executables)",
+		GoVersions: ">= 1.26",
+	},
+	"ErrGoInvalidTag": {
+		Description: "The Go binary is built with a tag which disables OpenSSL: no_openssl.",
+		Causes: []string{
+			"`-tags no_openssl` in the build flags, the Makefile or GOFLAGS.",
+		},
+		Remediation: []string{
+			"Remove no_openssl from the build tags, and build with `-tags strictfipsruntime`.",
+		},
+		Validator:  "validateGoTagsAndExperiment (Go executables)",
+		GoVersions: goLegacyVersions,
+	},
+	"ErrGoMissingSymbols": {
+		Description: "The Go binary uses crypto, but does not have the golang-fips symbols loading OpenSSL.",
+		Causes: []string{
+			"The binary is built with the upstream Go toolchain instead of the Red Hat Go toolset.",
+			"The binary is downloaded prebuilt in the Dockerfile, rather than built from source.",
+		},
+		Remediation: []string{
+			"Build with the Red Hat Go toolset (e.g. an OpenShift golang builder image), with CGO_ENABLED=1 and `-tags strictfipsruntime`.",
+		},
+		Validator:  "validateGoSymbols (Go executables)",
+		GoVersions: goLegacyVersions,
+	},
+	"ErrGoMissingTag": {
+		Description: "The Go binary is built without the strictfipsruntime tag, and its GOEXPERIMENT does not have strictfipsruntime either.",
+		Causes: []string{
+			"The build command sets other tags, but not strictfipsruntime.",
+		},
+		Remediation: []string{
+			"Add strictfipsruntime to the build tags, e.g. `GOFLAGS=-tags=strictfipsruntime`.",
+			"Or set GOEXPERIMENT=strictfipsruntime when building.",
+		},
+		Validator:  "validateGoTagsAndExperiment (Go executables)",
+		GoVersions: goLegacyVersions,
+	},
+	"ErrGoNoCgoInit": {
+		Description: "The Go binary has no cgo runtime (x_cgo_init or _cgo_topofstack), so it can't load OpenSSL.",
+		Causes: []string{
+			"CGO_ENABLED=0 in the Dockerfile, the Makefile or the environment of the build.",
+			"No C compiler in the builder image, which makes go build disable cgo.",
+			"Cross-compiling, which disables cgo by default.",
+		},
+		Remediation: []string{
+			"Set CGO_ENABLED=1 when building, e.g. `ENV CGO_ENABLED=1` in the builder stage.",
+			"Install gcc in the builder image, and do not link statically (no `-extldflags -static`).",
+		},
+		Validator:  "validateGoCGOInit (Go executables)",
+		GoVersions: goLegacyVersions,
+	},
+	"ErrGoNoTags": {
+		Description: "The Go binary is built without any build tags, so without strictfipsruntime, and its GOEXPERIMENT does not have strictfipsruntime either.",
+		Causes: []string{
+			"The build command sets no -tags, and GOEXPERIMENT is set for other experiments.",
+		},
+		Remediation: []string{
+			"Build with `-tags strictfipsruntime`, or GOEXPERIMENT=strictfipsruntime.",
+		},
+		Validator:  "validateGoTagsAndExperiment (Go executables)",
+		GoVersions: goLegacyVersions,
+	},
+	"ErrGoNotCgoEnabled": {
+		Description: "The Go binary build info has CGO_ENABLED other than 1, so it can't use OpenSSL.",
+		Causes: []string{
+			"CGO_ENABLED=0 in the Dockerfile, the Makefile or the environment of the build.",
+			"No C compiler in the builder image.",
+		},
+		Remediation: []string{
+			"Set CGO_ENABLED=1 when building, and install gcc in the builder image.",
+		},
+		Validator:  "validateGoCgo (Go executables)",
+		GoVersions: goLegacyVersions,
+	},
+	"ErrGoNotGoExperiment": {
+		Description: "The Go binary sets GOEXPERIMENT, but without strictfipsruntime.",
+		Causes: []string{
+			"GOEXPERIMENT is set for other experiments, overriding strictfipsruntime.",
+		},
+		Remediation: []string{
+			"Add strictfipsruntime to GOEXPERIMENT, e.g. `GOEXPERIMENT=strictfipsruntime,<others>`.",
+			"Or build with `-tags strictfipsruntime`.",
+		},
+		Validator:  "validateGoTagsAndExperiment (Go executables)",
+		GoVersions: goLegacyVersions,
+	},
+	"ErrJavaCryptoProvider": {
+		Description: "A java archive bundles a crypto provider which is not FIPS validated, such as BouncyCastle bcprov.",
+		Causes: []string{
+			"A dependency pulls in bcprov, and it is packaged in the fat jar, war or ear.",
+		},
+		Remediation: []string{
+			"Use the JDK providers, which use the FIPS validated NSS in FIPS mode, or bc-fips instead of bcprov.",
+			"Exclude the provider from the archive, e.g. with a Maven <exclusion>.",
+		},
+		Validator: "ScanJavaArchive (java archives, with --scan-java-archives)",
+	},
+	"ErrLibcryptoMany": {
+		Description: "The Go binary references several different libcrypto versions.",
+		Causes: []string{
+			"Several golang-fips OpenSSL bindings are vendored, built for different OpenSSL versions.",
+		},
+		Remediation: []string{
+			"Build with a single version of the golang-fips bindings, with the Red Hat Go toolset matching the OpenSSL of the base image.",
+		},
+		Validator:  "validateGoOpenssl (Go executables)",
+		GoVersions: "< 1.22",
+	},
+	"ErrLibcryptoMissing": {
+		Description: "The Go binary does not reference any libcrypto library.",
+		Causes: []string{
+			"The binary is built without the golang-fips OpenSSL bindings.",
+		},
+		Remediation: []string{
+			"Build with the Red Hat Go toolset, with CGO_ENABLED=1 and `-tags strictfipsruntime`.",
+		},
+		Validator:  "validateGoOpenssl (Go executables)",
+		GoVersions: "< 1.22",
+	},
+	"ErrLibcryptoSoMissing": {
+		Description: "The libcrypto library the Go binary loads is not in /usr/lib64 in the image.",
+		Causes: []string{
+			"The final image has no openssl-libs (ubi-micro, scratch, distroless).",
+			"The builder and runtime images have different RHEL versions, e.g. a RHEL 8 builder (libcrypto.so.1.1) with a RHEL 9 base (libcrypto.so.3).",
+		},
+		Remediation: []string{
+			"Install openssl-libs in the final stage, e.g. `RUN microdnf install -y openssl-libs`.",
+			"Use builder and base images of the same RHEL version.",
+		},
+		Validator:  "validateGoOpenssl (Go executables)",
+		GoVersions: "< 1.22",
+	},
+	"ErrNodeBundledOpenSSL": {
+		Description: "A node.js binary or native addon has its own OpenSSL linked in, instead of the system one.",
+		Causes: []string{
+			"node.js is installed from the upstream tarballs, built with the bundled OpenSSL.",
+			"A native addon (.node) is prebuilt with OpenSSL statically linked.",
+		},
+		Remediation: []string{
+			"Use the RHEL nodejs packages or UBI nodejs images, built with the shared system OpenSSL.",
+			"Rebuild native addons from source against the OpenSSL headers of the system (`npm rebuild --build-from-source`).",
+		},
+		Validator: "validateNodeOpenssl, ScanNodeAddon (node.js binaries and addons)",
+	},
+	"ErrNotDynLinked": {
+		Description: "The executable is statically linked, so it can't use the system OpenSSL.",
+		Causes: []string{
+			"A Go binary using crypto is built with CGO_ENABLED=0, or with `-extldflags -static`.",
+			"A C/C++ program is linked statically, e.g. against musl.",
+			"A prebuilt upstream binary is downloaded in the Dockerfile.",
+		},
+		Remediation: []string{
+			"Link dynamically against the system libraries; for Go, build with CGO_ENABLED=1 and the Red Hat Go toolset.",
+			"Build the binary from source instead of downloading it.",
+			"If the binary does no crypto, add an [[ignore]] entry with a ticket to the config.",
+		},
+		Validator: "validateNotStatic (executables), validateGoStatic (Go executables)",
+	},
+	"ErrOSNotCertified": {
+		Description: "The OS of the image is not one of the certified_distributions.",
+		Causes: []string{
+			"The image is based on another distribution (Alpine, Debian, Fedora, CentOS Stream...).",
+			"The image is based on a RHEL release which is not FIPS certified.",
+			"check-payload was run without -V, so with the default certified_distributions.",
+		},
+		Remediation: []string{
+			"Rebase the image on a certified RHEL or UBI release.",
+			"Run with -V <version> to use the certified distributions of the OpenShift release.",
+		},
+		Validator: "validateOSPhase (image OS check)",
+	},
+	"ErrOpenSSLFIPSMisconfigured": {
+		Description: "The OpenSSL config of the image (/etc/pki/tls/openssl.cnf) does not enable FIPS.",
+		Causes: []string{
+			"openssl.cnf is replaced, or edited, so that it no longer includes the crypto policies.",
+			"default_properties disable FIPS (fips=no), or the fips provider section is not activated.",
+		},
+		Remediation: []string{
+			"Keep the openssl.cnf of the openssl package, which includes /etc/crypto-policies/back-ends/opensslcnf.config.",
+			"Do not set fips=no in default_properties.",
+		},
+		Validator: "ScanOpensslConfig (OpenSSL config check)",
+	},
+	"ErrPythonBundledOpenSSL": {
+		Description: "A python package bundles its own OpenSSL libraries, instead of using the system one.",
+		Causes: []string{
+			"Binary wheels from PyPI (such as cryptography or psycopg2-binary) ship their own libssl and libcrypto.",
+		},
+		Remediation: []string{
+			"Install the RHEL python packages (e.g. python3-cryptography) instead of the wheels.",
+			"Or build the wheels from source against the system OpenSSL, e.g. `pip install --no-binary cryptography cryptography`.",
+		},
+		Validator: "ScanPythonLibDir (python packages, with --scan-python-packages)",
+	},
+}
+
+// ExplainError returns the ErrorInfo of the known error name, and whether
+// there is one.
+func ExplainError(name string) (ErrorInfo, bool) {
+	info, ok := errorInfo[name]
+	info.Name = name
+	return info, ok
+}
+
+// KnownErrorNames returns the names of the KnownErrors, sorted.
+func KnownErrorNames() []string {
+	names := make([]string, 0, len(KnownErrors))
+	for name := range KnownErrors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
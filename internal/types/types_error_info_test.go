@@ -0,0 +1,27 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+func TestExplainError(t *testing.T) {
+	names := types.KnownErrorNames()
+	assert.Len(t, names, len(types.KnownErrors))
+	for _, name := range names {
+		info, ok := types.ExplainError(name)
+		if assert.True(t, ok, name) {
+			assert.Equal(t, name, info.Name)
+			assert.NotEmpty(t, info.Description, name)
+			assert.NotEmpty(t, info.Causes, name)
+			assert.NotEmpty(t, info.Remediation, name)
+			assert.NotEmpty(t, info.Validator, name)
+		}
+	}
+
+	_, ok := types.ExplainError("ErrFoo")
+	assert.False(t, ok)
+}
@@ -0,0 +1,307 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// ExceptionUse is an entry of the config exceptions (a path of the filter
+// lists, or a file, dir or tag of an ignore entry), and what it matched
+// during the scans.
+type ExceptionUse struct {
+	Section string `json:"section"` // Such as [[rpm.foo.ignore]].error=ErrNotDynLinked.files.
+	Entry   string `json:"entry"`
+	Waiver
+	Paths  []string `json:"matched"`          // The files, directories or tags it matched.
+	Source string   `json:"source,omitempty"` // The config file and line it comes from, if known.
+}
+
+// Status tells whether the entry was applied, has expired, or was not used.
+func (u ExceptionUse) Status() string {
+	switch {
+	case u.Expired():
+		return "expired"
+	case len(u.Paths) == 0:
+		return "unused"
+	}
+	return "applied"
+}
+
+// exceptionKey identifies an entry of the config exceptions by the section
+// it is listed in (see exceptionLists), and its value. Ignore entries of the
+// same section and error are told apart by their waiver. Unlike addresses,
+// the keys are preserved by copying and merging configs.
+type exceptionKey struct {
+	section string
+	waiver  Waiver
+	entry   string
+}
+
+// exceptionUses records, for the entries of a config, the paths they
+// matched during the scans (see Config.WithExceptions).
+type exceptionUses struct {
+	sync.Mutex
+	paths map[exceptionKey]map[string]bool
+}
+
+type exceptionUsesKey struct{}
+
+// exceptionUses returns the recorder of the uses of the exceptions of the
+// config. On first use, the ignore entries are told which section they are
+// listed in, so that ErrIgnoreList.Match can record their uses.
+func (c *Config) exceptionUses() *exceptionUses {
+	c.usesOnce.Do(func() {
+		c.uses = &exceptionUses{paths: make(map[exceptionKey]map[string]bool)}
+		c.ConfigFile.setSections()
+	})
+	return c.uses
+}
+
+// WithExceptions returns a context for scanning with the config, with which
+// the matches of the ignore lists of the config are recorded for the
+// exceptions reports.
+func (c *Config) WithExceptions(ctx context.Context) context.Context {
+	return context.WithValue(ctx, exceptionUsesKey{}, c.exceptionUses())
+}
+
+// record records that the config entry matched the path. If the waiver has
+// expired, a warning is logged once per path. Nothing is recorded if u is
+// nil.
+func (u *exceptionUses) record(key exceptionKey, what string, w Waiver, path string) {
+	if u == nil {
+		return
+	}
+	u.Lock()
+	paths, ok := u.paths[key]
+	if !ok {
+		paths = make(map[string]bool)
+		u.paths[key] = paths
+	}
+	seen := paths[path]
+	paths[path] = true
+	u.Unlock()
+
+	if !seen && w.Expired() {
+		klog.Warningf("exception %s (%s) is not applied to %s", what, w.Describe(), path)
+	}
+}
+
+// matched returns the sorted list of paths the entry matched.
+func (u *exceptionUses) matched(key exceptionKey) []string {
+	u.Lock()
+	defer u.Unlock()
+	paths := make([]string, 0, len(u.paths[key]))
+	for p := range u.paths[key] {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// matchList tells if path matches an entry of the list of the section,
+// using match, and records the use. It returns false if the waiver of the
+// list has expired.
+func (c *Config) matchList(section, path string, list []string, w Waiver, match func(entry, path string) bool) bool {
+	for _, e := range list {
+		if match(e, path) {
+			c.exceptionUses().record(exceptionKey{section: section, entry: e}, e, w, path)
+			return !w.Expired()
+		}
+	}
+	return false
+}
+
+// Match returns the entry of the list which matches the error found in
+// file, if any, preferring the ones which have not expired. The match is
+// recorded for the exceptions reports, if ctx has a recorder (see
+// Config.WithExceptions).
+func (i ErrIgnoreList) Match(ctx context.Context, file string, err error) *ErrIgnore {
+	return i.match(ctx, file, err, func(ie *ErrIgnore) (string, string) {
+		for _, d := range ie.Dirs {
+			if matchParentDir(d, file) {
+				return "dirs", d
+			}
+		}
+		for _, f := range ie.Files {
+			if matchPath(f, file) {
+				return "files", f
+			}
+		}
+		return "", ""
+	})
+}
+
+// MatchTag is like Match, for the tags of the entries.
+func (i ErrIgnoreList) MatchTag(ctx context.Context, tag string, err error) *ErrIgnore {
+	return i.match(ctx, tag, err, func(ie *ErrIgnore) (string, string) {
+		for _, t := range ie.Tags {
+			if t == tag {
+				return "tags", t
+			}
+		}
+		return "", ""
+	})
+}
+
+// match returns the entry which matches path, using match, which returns
+// the name of the list of the entry which matched, and its value.
+func (i ErrIgnoreList) match(ctx context.Context, path string, err error, match func(*ErrIgnore) (string, string)) *ErrIgnore {
+	uses, _ := ctx.Value(exceptionUsesKey{}).(*exceptionUses)
+	var expired *ErrIgnore
+	var expiredKey exceptionKey
+	for n := range i {
+		ie := &i[n]
+		if !errors.Is(err, ie.Error.Err) {
+			continue
+		}
+		list, entry := match(ie)
+		if list == "" {
+			continue
+		}
+		key := exceptionKey{ie.section + ".error=" + ie.Error.Str + "." + list, ie.Waiver, entry}
+		if !ie.Expired() {
+			uses.record(key, "error="+ie.Error.Str, ie.Waiver, path)
+			return ie
+		}
+		if expired == nil {
+			expired, expiredKey = ie, key
+		}
+	}
+	if expired != nil {
+		uses.record(expiredKey, "error="+expired.Error.Str, expired.Waiver, path)
+	}
+	return expired
+}
+
+// exceptionList is a list of entries of the config exceptions.
+type exceptionList struct {
+	section string
+	entries []string
+	waiver  Waiver
+	global  bool // The top-level filter lists, which are not waivers.
+	ignore  bool // The lists of an ignore entry, told apart by their waiver.
+}
+
+// key returns the key of the entry of the list.
+func (l exceptionList) key(entry string) exceptionKey {
+	if l.ignore {
+		return exceptionKey{l.section, l.waiver, entry}
+	}
+	return exceptionKey{section: l.section, entry: entry}
+}
+
+// ignoreSection returns the section name of the ignore lists of the
+// section, such as [[rpm.foo.ignore]].
+func ignoreSection(section, name string) string {
+	if section == "" {
+		return "[[ignore]]"
+	}
+	return "[[" + section + "." + name + ".ignore]]"
+}
+
+// filterSection returns the section name of the filter list of the
+// section, such as [rpm.foo].filter_files.
+func filterSection(section, name, list string) string {
+	if section == "" {
+		return list
+	}
+	return "[" + section + "." + name + "]." + list
+}
+
+// exceptionSections are the sections of the config with exception lists.
+func (c *ConfigFile) exceptionSections() []struct {
+	name  string
+	lists map[string]IgnoreLists
+} {
+	return []struct {
+		name  string
+		lists map[string]IgnoreLists
+	}{{"payload", c.PayloadIgnores}, {"tag", c.TagIgnores}, {"rpm", c.RPMIgnores}}
+}
+
+// setSections sets the section of the ignore entries of the config.
+func (c *ConfigFile) setSections() {
+	set := func(section string, l ErrIgnoreList) {
+		for n := range l {
+			l[n].section = section
+		}
+	}
+	set(ignoreSection("", ""), c.ErrIgnores)
+	for _, s := range c.exceptionSections() {
+		for k, l := range s.lists {
+			set(ignoreSection(s.name, k), l.ErrIgnores)
+		}
+	}
+}
+
+// exceptionLists returns all the exception lists of the config, in order.
+func (c *ConfigFile) exceptionLists() []exceptionList {
+	lists := []exceptionList{
+		{section: filterSection("", "", "filter_files"), entries: c.FilterFiles, global: true},
+		{section: filterSection("", "", "filter_dirs"), entries: c.FilterDirs, global: true},
+	}
+	addErrIgnores := func(section string, l ErrIgnoreList) {
+		for _, ie := range l {
+			prefix := section + ".error=" + ie.Error.Str
+			lists = append(lists,
+				exceptionList{section: prefix + ".files", entries: ie.Files, waiver: ie.Waiver, ignore: true},
+				exceptionList{section: prefix + ".dirs", entries: ie.Dirs, waiver: ie.Waiver, ignore: true},
+				exceptionList{section: prefix + ".tags", entries: ie.Tags, waiver: ie.Waiver, ignore: true})
+		}
+	}
+
+	addErrIgnores(ignoreSection("", ""), c.ErrIgnores)
+	for _, s := range c.exceptionSections() {
+		keys := make([]string, 0, len(s.lists))
+		for k := range s.lists {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			l := s.lists[k]
+			lists = append(lists,
+				exceptionList{section: filterSection(s.name, k, "filter_files"), entries: l.FilterFiles, waiver: l.Waiver},
+				exceptionList{section: filterSection(s.name, k, "filter_dirs"), entries: l.FilterDirs, waiver: l.Waiver})
+			addErrIgnores(ignoreSection(s.name, k), l.ErrIgnores)
+		}
+	}
+	return lists
+}
+
+// AppliedWaivers returns the exceptions of the config which matched
+// anything during the scans, whether they were applied or have expired.
+// The top-level filter lists are not included.
+func (c *Config) AppliedWaivers() []ExceptionUse {
+	uses := c.exceptionUses()
+	var res []ExceptionUse
+	for _, l := range c.exceptionLists() {
+		if l.global {
+			continue
+		}
+		for _, e := range l.entries {
+			if paths := uses.matched(l.key(e)); len(paths) > 0 {
+				res = append(res, ExceptionUse{l.section, e, l.waiver, paths, c.sources[l.key(e)]})
+			}
+		}
+	}
+	return res
+}
+
+// UnusedExceptions returns the exceptions of the config which suppressed
+// nothing during the scans, because they matched nothing, or have expired.
+func (c *Config) UnusedExceptions() []ExceptionUse {
+	uses := c.exceptionUses()
+	var res []ExceptionUse
+	for _, l := range c.exceptionLists() {
+		for _, e := range l.entries {
+			if paths := uses.matched(l.key(e)); len(paths) == 0 || l.waiver.Expired() {
+				res = append(res, ExceptionUse{l.section, e, l.waiver, paths, c.sources[l.key(e)]})
+			}
+		}
+	}
+	return res
+}
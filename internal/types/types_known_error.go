@@ -22,6 +22,11 @@ func (e *KnownError) UnmarshalText(text []byte) error {
 	return fmt.Errorf("error=%q is not recognized in config", str)
 }
 
+// MarshalText is used when writing the configuration as toml.
+func (e KnownError) MarshalText() ([]byte, error) {
+	return []byte(e.Str), nil
+}
+
 // String is used when printing the current configuration.
 func (e KnownError) String() string {
 	return e.Str
@@ -0,0 +1,560 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/multierr"
+)
+
+// LintIssue is an issue found in a config by LintConfig.
+type LintIssue struct {
+	Line    int // The line of the issue in the config, or 0 if unknown.
+	Warning bool
+	Msg     string
+}
+
+func (i LintIssue) Level() string {
+	if i.Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// LintConfig checks the config source data, and returns all the issues
+// found, sorted by line, as well as the decoded config, or nil if it can't
+// be decoded. In addition to the checks of Validate, it reports unknown
+// keys, unknown error names, duplicate entries, and, if base is set, the
+// entries base already has.
+func LintConfig(data []byte, base *ConfigFile) (*ConfigFile, []LintIssue) {
+	var issues []LintIssue
+	add := func(line int, warning bool, format string, args ...any) {
+		issues = append(issues, LintIssue{line, warning, fmt.Sprintf(format, args...)})
+	}
+
+	var raw map[string]any
+	if _, err := toml.Decode(string(data), &raw); err != nil {
+		return nil, []LintIssue{decodeIssue(err)}
+	}
+	src := parseTOMLSource(data)
+
+	// Unknown error names make decoding fail, so report them all first.
+	for _, e := range src.entries {
+		if e.key != "error" || e.value == "" || (e.table != "ignore" && !strings.HasSuffix(e.table, ".ignore")) {
+			continue
+		}
+		if _, ok := KnownErrors[e.value]; !ok {
+			add(e.line, false, "[[%s]] has unknown error=%q", e.table, e.value)
+		}
+	}
+	if len(issues) > 0 {
+		return nil, issues
+	}
+
+	cfg := &ConfigFile{}
+	md, err := toml.Decode(string(data), cfg)
+	if err != nil {
+		return nil, []LintIssue{decodeIssue(err)}
+	}
+	for _, k := range md.Undecoded() {
+		add(src.line(strings.Join(k[:len(k)-1], "."), "", k[len(k)-1], ""), false, "unknown key %s", k)
+	}
+
+	lintDecoded(cfg, base, src, add)
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return cfg, issues
+}
+
+// LintConfigFile is like LintConfig, for the config data of file name, in
+// any of the formats of DecodeConfigFile. The issues found in json and yaml
+// configs have no line numbers.
+func LintConfigFile(name string, data []byte, base *ConfigFile) (*ConfigFile, []LintIssue) {
+	if ConfigFormat(name, data) == FormatTOML {
+		return LintConfig(data, base)
+	}
+	cfg, err := DecodeConfigFile(name, data)
+	if err != nil {
+		return nil, []LintIssue{{Msg: err.Error()}}
+	}
+	var issues []LintIssue
+	lintDecoded(cfg, base, &tomlSource{}, func(line int, warning bool, format string, args ...any) {
+		issues = append(issues, LintIssue{line, warning, fmt.Sprintf(format, args...)})
+	})
+	return cfg, issues
+}
+
+// lintDecoded reports the issues found in the decoded config cfg, with
+// their lines in src.
+func lintDecoded(cfg, base *ConfigFile, src *tomlSource, add func(int, bool, string, ...any)) {
+	err, warn := cfg.Validate()
+	for _, e := range multierr.Errors(err) {
+		add(src.locate(e), false, "%v", e)
+	}
+	for _, e := range multierr.Errors(warn) {
+		add(src.locate(e), true, "%v", e)
+	}
+
+	lintDuplicates(cfg, src, add)
+	if base != nil {
+		lintRedundant(cfg, base, src, add)
+	}
+}
+
+func decodeIssue(err error) LintIssue {
+	var perr toml.ParseError
+	if errors.As(err, &perr) {
+		return LintIssue{Line: perr.Position.Line, Msg: perr.Message}
+	}
+	return LintIssue{Msg: err.Error()}
+}
+
+// lintDuplicates reports the entries which are in a list more than once,
+// or in several lists of the same section, or of different kinds of
+// sections, such as the ignores of [payload.*] and [rpm.*] sections for the
+// same error. The same entry in several [payload.*] sections is fine, as
+// they are about different images.
+func lintDuplicates(cfg *ConfigFile, src *tomlSource, add func(int, bool, string, ...any)) {
+	type first struct {
+		list    int
+		section string
+		scope   string
+	}
+	kind := func(scope string) string {
+		k, _, _ := strings.Cut(scope, ".")
+		return k
+	}
+	seen := make(map[string]first)
+	occurrences := make(map[string]int)
+	for n, l := range cfg.exceptionLists() {
+		table, errName, key := parseListname(l.section)
+		for _, e := range l.entries {
+			loc := table + "\x00" + errName + "\x00" + key + "\x00" + e
+			line := src.nthLine(table, errName, key, e, occurrences[loc])
+			occurrences[loc]++
+
+			k := errName + "\x00" + e
+			f, ok := seen[k]
+			switch {
+			case !ok:
+				seen[k] = first{n, l.section, listScope(table)}
+			case f.list == n:
+				add(line, true, "config entry %s contains %q more than once", l.section, e)
+			case f.scope == listScope(table) || kind(f.scope) != kind(listScope(table)):
+				add(line, true, "config entry %s contains %q, which is also in %s", l.section, e, f.section)
+			}
+		}
+	}
+}
+
+// lintRedundant reports the entries which base already has.
+func lintRedundant(cfg, base *ConfigFile, src *tomlSource, add func(int, bool, string, ...any)) {
+	for _, l := range cfg.exceptionLists() {
+		table, errName, key := parseListname(l.section)
+		for _, e := range l.entries {
+			if section, by := base.covering(listScope(table), errName, key, e); section != "" {
+				add(src.line(table, errName, key, e), true, "config entry %s contains %q, already covered by %q in %s of the base config", l.section, e, by, section)
+			}
+		}
+	}
+}
+
+// listScope returns the [payload.*], [tag.*] or [rpm.*] section a table
+// is in, or an empty string for the top-level ones.
+func listScope(table string) string {
+	if table == "ignore" {
+		return ""
+	}
+	return strings.TrimSuffix(table, ".ignore")
+}
+
+// covering returns the section and entry of c which cover the entry of a
+// list key (such as files or filter_dirs) of an ignore for errName (or of
+// filters, if errName is empty) in scope, if any.
+func (c *ConfigFile) covering(scope, errName, key, entry string) (section, by string) {
+	if key == "tags" {
+		// Tags are not paths, and only apply to ignores.
+		find := func(l ErrIgnoreList) string {
+			for _, ie := range l {
+				if ie.Error.Str == errName && contains(ie.Tags, entry) {
+					return entry
+				}
+			}
+			return ""
+		}
+		if by := find(c.ErrIgnores); by != "" {
+			return "[[ignore]]", by
+		}
+		if l, ok := c.ignoreLists(scope); ok {
+			if by := find(l.ErrIgnores); by != "" {
+				return "[[" + scope + ".ignore]]", by
+			}
+		}
+		return "", ""
+	}
+
+	isDir := key == "dirs" || key == "filter_dirs"
+	if by := pathCovered(entry, isDir, c.FilterFiles, c.FilterDirs); by != "" {
+		return "the top-level filters", by
+	}
+	l, inScope := c.ignoreLists(scope)
+	if inScope {
+		if by := pathCovered(entry, isDir, l.FilterFiles, l.FilterDirs); by != "" {
+			return "[" + scope + "]", by
+		}
+	}
+	if errName == "" {
+		return "", ""
+	}
+	find := func(l ErrIgnoreList) string {
+		for _, ie := range l {
+			if ie.Error.Str != errName {
+				continue
+			}
+			if by := pathCovered(entry, isDir, ie.Files, ie.Dirs); by != "" {
+				return by
+			}
+		}
+		return ""
+	}
+	if by := find(c.ErrIgnores); by != "" {
+		return "[[ignore]]", by
+	}
+	if inScope {
+		if by := find(l.ErrIgnores); by != "" {
+			return "[[" + scope + ".ignore]]", by
+		}
+	}
+	return "", ""
+}
+
+// ignoreLists returns the [payload.*], [tag.*] or [rpm.*] section.
+func (c *ConfigFile) ignoreLists(scope string) (IgnoreLists, bool) {
+	kind, name, _ := strings.Cut(scope, ".")
+	var lists map[string]IgnoreLists
+	switch kind {
+	case "payload":
+		lists = c.PayloadIgnores
+	case "tag":
+		lists = c.TagIgnores
+	case "rpm":
+		lists = c.RPMIgnores
+	}
+	l, ok := lists[name]
+	return l, ok
+}
+
+// pathCovered returns the entry of files or dirs which covers the path
+// entry, if any.
+func pathCovered(entry string, isDir bool, files, dirs []string) string {
+	if !isDir {
+		for _, f := range files {
+			if f == entry || (!isPattern(entry) && matchPath(f, entry)) {
+				return f
+			}
+		}
+	}
+	for _, d := range dirs {
+		if d == entry || coveredByDir(entry, d) || (isDir && !isPattern(entry) && matchPath(d, entry)) {
+			return d
+		}
+	}
+	return ""
+}
+
+// Tidy removes the duplicate entries of the config, the paths covered by
+// dirs of the same list, and, if base is set, the entries base already
+// has. It then sorts the lists, and removes the sections left empty.
+func (c *ConfigFile) Tidy(base *ConfigFile) {
+	keep := func(scope, errName, key string, dirs []string) func(string) bool {
+		return func(e string) bool {
+			for _, d := range dirs {
+				if d != e && coveredByDir(e, d) {
+					return false
+				}
+			}
+			if base != nil {
+				section, _ := base.covering(scope, errName, key, e)
+				return section == ""
+			}
+			return true
+		}
+	}
+
+	c.FilterFiles = tidyList(c.FilterFiles, keep("", "", "filter_files", c.FilterDirs))
+	c.FilterDirs = tidyList(c.FilterDirs, keep("", "", "filter_dirs", c.FilterDirs))
+	c.FilterImages = tidyList(c.FilterImages, nil)
+	c.JavaDisabledAlgorithms = tidyList(c.JavaDisabledAlgorithms, nil)
+	c.ErrIgnores = tidyErrIgnores("", c.ErrIgnores, keep)
+
+	for _, s := range []struct {
+		name  string
+		lists *map[string]IgnoreLists
+	}{{"payload", &c.PayloadIgnores}, {"tag", &c.TagIgnores}, {"rpm", &c.RPMIgnores}} {
+		lists := *s.lists
+		for k, l := range lists {
+			scope := s.name + "." + k
+			l.FilterFiles = tidyList(l.FilterFiles, keep(scope, "", "filter_files", l.FilterDirs))
+			l.FilterDirs = tidyList(l.FilterDirs, keep(scope, "", "filter_dirs", l.FilterDirs))
+			l.ErrIgnores = tidyErrIgnores(scope, l.ErrIgnores, keep)
+			if len(l.FilterFiles)+len(l.FilterDirs)+len(l.ErrIgnores)+len(l.Severity) == 0 && l.Waiver == (Waiver{}) {
+				delete(lists, k)
+				continue
+			}
+			lists[k] = l
+		}
+		if len(lists) == 0 {
+			*s.lists = nil
+		}
+	}
+}
+
+func tidyErrIgnores(scope string, list ErrIgnoreList, keep func(scope, errName, key string, dirs []string) func(string) bool) ErrIgnoreList {
+	// Merge the entries for the same error and waiver.
+	var merged ErrIgnoreList
+	for _, ie := range list {
+		i := 0
+		for ; i < len(merged); i++ {
+			if merged[i].Error.Str == ie.Error.Str && merged[i].Waiver == ie.Waiver {
+				break
+			}
+		}
+		if i == len(merged) {
+			merged = append(merged, ErrIgnore{Error: ie.Error, Waiver: ie.Waiver})
+		}
+		m := &merged[i]
+		m.Files = append(m.Files, ie.Files...)
+		m.Dirs = append(m.Dirs, ie.Dirs...)
+		m.Tags = append(m.Tags, ie.Tags...)
+	}
+
+	res := merged[:0]
+	for _, ie := range merged {
+		dirs := ie.Dirs
+		ie.Files = tidyList(ie.Files, keep(scope, ie.Error.Str, "files", dirs))
+		ie.Dirs = tidyList(ie.Dirs, keep(scope, ie.Error.Str, "dirs", dirs))
+		ie.Tags = tidyList(ie.Tags, keep(scope, ie.Error.Str, "tags", nil))
+		if len(ie.Files)+len(ie.Dirs)+len(ie.Tags) > 0 {
+			res = append(res, ie)
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Error.Str < res[j].Error.Str })
+	return res
+}
+
+// tidyList returns the sorted list, without duplicates, and only with the
+// entries for which keep, if set, returns true.
+func tidyList(list []string, keep func(string) bool) []string {
+	var res []string
+	for _, e := range list {
+		if contains(res, e) || (keep != nil && !keep(e)) {
+			continue
+		}
+		res = append(res, e)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// parseListname splits a config entry list name, as used by Validate,
+// into its table, error name, and key. For example,
+// "[[rpm.foo.ignore]].error=ErrNotDynLinked.files" is split into
+// "rpm.foo.ignore", "ErrNotDynLinked" and "files".
+func parseListname(listname string) (table, errName, key string) {
+	switch {
+	case strings.HasPrefix(listname, "[["):
+		end := strings.Index(listname, "]]")
+		if end < 0 {
+			return "", "", listname
+		}
+		table = listname[2:end]
+		rest, ok := strings.CutPrefix(listname[end+2:], ".error=")
+		if !ok {
+			return table, "", ""
+		}
+		errName, key, _ = strings.Cut(rest, ".")
+		return table, errName, key
+	case strings.HasPrefix(listname, "["):
+		end := strings.Index(listname, "]")
+		if end < 0 {
+			return "", "", listname
+		}
+		return listname[1:end], "", strings.TrimPrefix(listname[end+1:], ".")
+	}
+	key, _, _ = strings.Cut(listname, "[")
+	return "", "", key
+}
+
+// locatable is implemented by the validation errors, to find the entry
+// they are about in the config source.
+type locatable interface {
+	location() (listname, value string)
+}
+
+func (e *errBadPath) location() (string, string)           { return e.Listname, e.Path }
+func (e *errNAbsPath) location() (string, string)          { return e.Listname, e.Path }
+func (e *errOverlap) location() (string, string)           { return e.Listname, e.Path }
+func (e *errEmpty) location() (string, string)             { return e.Listname, "" }
+func (e *errBadPattern) location() (string, string)        { return e.Listname, e.Pattern }
+func (e *errExpired) location() (string, string)           { return e.Listname + ".expires", "" }
+func (e *errInvalidFIPSModule) location() (string, string) { return "fips_certified_modules", "" }
+func (e *errBadSigningKey) location() (string, string)     { return "fips_certified_modules", e.Key }
+func (e *errUnknownSeverity) location() (string, string)   { return e.Listname + "." + e.ErrName, "" }
+
+// tomlSource maps the keys and string values of a toml source to their
+// lines, for the purpose of reporting issues.
+type tomlSource struct {
+	entries []tomlEntry
+	tables  []string       // Per block, the table.
+	headers []int          // Per block, the line of the table header.
+	errors  map[int]string // Per block, the error key value.
+}
+
+// tomlEntry is a key, or a string value of a key, of a toml source.
+type tomlEntry struct {
+	table string
+	block int // Each table header starts a new block.
+	key   string
+	value string // The string value, or an empty string for the key.
+	line  int
+}
+
+var (
+	tomlHeaderRE = regexp.MustCompile(`^\[\[?\s*([^\[\]]+?)\s*\]\]?$`)
+	tomlKeyRE    = regexp.MustCompile(`^([A-Za-z0-9_\-."' ]+?)\s*=\s*(.*)$`)
+	tomlStringRE = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'[^']*'`)
+)
+
+// parseTOMLSource parses a (valid) toml source. Multi-line strings are
+// not supported.
+func parseTOMLSource(data []byte) *tomlSource {
+	src := &tomlSource{tables: []string{""}, headers: []int{0}, errors: make(map[int]string)}
+	var table, key string
+	block, depth := 0, 0
+	for n, line := range strings.Split(string(data), "\n") {
+		ln := n + 1
+		line = strings.TrimSpace(stripTOMLComment(line))
+		if depth == 0 {
+			if m := tomlHeaderRE.FindStringSubmatch(line); m != nil {
+				table = normalizeTOMLKey(m[1])
+				block++
+				src.tables = append(src.tables, table)
+				src.headers = append(src.headers, ln)
+				continue
+			}
+			m := tomlKeyRE.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			key = normalizeTOMLKey(m[1])
+			src.entries = append(src.entries, tomlEntry{table, block, key, "", ln})
+			line = m[2]
+		}
+		for _, s := range tomlStringRE.FindAllString(line, -1) {
+			v := unquoteTOML(s)
+			src.entries = append(src.entries, tomlEntry{table, block, key, v, ln})
+			if key == "error" && depth == 0 {
+				src.errors[block] = v
+			}
+		}
+		rest := tomlStringRE.ReplaceAllString(line, "")
+		depth += strings.Count(rest, "[") + strings.Count(rest, "{") - strings.Count(rest, "]") - strings.Count(rest, "}")
+		if depth < 0 {
+			depth = 0
+		}
+	}
+	return src
+}
+
+func stripTOMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func normalizeTOMLKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, p := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(p), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
+
+func unquoteTOML(s string) string {
+	if s[0] == '\'' {
+		return s[1 : len(s)-1]
+	}
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return s[1 : len(s)-1]
+}
+
+// nthLine returns the line of the nth occurrence of the value of key in
+// table, in the blocks with the error errName, if set. If there is no such
+// value, it returns the line of the key, or of the table header, or 0.
+func (s *tomlSource) nthLine(table, errName, key, value string, nth int) int {
+	var keyLine, headerLine int
+	for b, t := range s.tables {
+		if t == table && (errName == "" || s.errors[b] == errName) {
+			headerLine = s.headers[b]
+			break
+		}
+	}
+	for _, e := range s.entries {
+		if e.table != table || e.key != key || (errName != "" && s.errors[e.block] != errName) {
+			continue
+		}
+		if e.value == "" {
+			if keyLine == 0 {
+				keyLine = e.line
+			}
+			continue
+		}
+		if e.value == value {
+			if nth == 0 {
+				return e.line
+			}
+			nth--
+		}
+	}
+	if keyLine != 0 {
+		return keyLine
+	}
+	return headerLine
+}
+
+func (s *tomlSource) line(table, errName, key, value string) int {
+	return s.nthLine(table, errName, key, value, 0)
+}
+
+// locate returns the line of the entry a validation error is about.
+func (s *tomlSource) locate(err error) int {
+	var l locatable
+	if !errors.As(err, &l) {
+		return 0
+	}
+	listname, value := l.location()
+	table, errName, key := parseListname(listname)
+	return s.line(table, errName, key, value)
+}
@@ -0,0 +1,131 @@
+package types_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+const lintSrc = `filter_files = ["/usr/bin/a", "/usr/bin/a"] # dup
+filter_dirs = ["/srv"]
+
+[payload.foo]
+filter_files = [
+  "/usr/bin/b",
+  "/opt/foo",
+]
+
+[[payload.foo.ignore]]
+error = "ErrNotDynLinked"
+files = ["/usr/bin/c", "/usr/lib/x/y"]
+dirs = ["/usr/lib/x"]
+
+[[rpm.bar.ignore]]
+error = "ErrNotDynLinked"
+files = ["/usr/bin/c"]
+
+[[ignore]]
+error = "ErrNotDynLinked"
+files = ["/usr/bin/static"]
+`
+
+func TestLintConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name, src string
+		want      []types.LintIssue
+	}{
+		{
+			name: "syntax",
+			src:  "filter_files = [\n  \"/a\"\n",
+			want: []types.LintIssue{{Line: 2, Msg: "expected a comma (',') or array terminator (']'), but got end of file"}},
+		},
+		{
+			name: "unknown errors",
+			src:  "[[ignore]]\nerror = \"ErrFoo\"\nfiles = [\"/a\"]\n\n[[rpm.x.ignore]]\nerror = \"ErrBar\"\nfiles = [\"/a\"]\n",
+			want: []types.LintIssue{
+				{Line: 2, Msg: `[[ignore]] has unknown error="ErrFoo"`},
+				{Line: 6, Msg: `[[rpm.x.ignore]] has unknown error="ErrBar"`},
+			},
+		},
+		{
+			name: "unknown keys and bad paths",
+			src:  "filter_dir = [\"/a\"]\n\n[[ignore]]\nerror = \"ErrNotDynLinked\"\nfile = [\"/a\"]\ndirs = [\"/b\", \"c/\"]\n",
+			want: []types.LintIssue{
+				{Line: 1, Msg: "unknown key filter_dir"},
+				{Line: 5, Msg: "unknown key ignore.file"},
+				{Line: 6, Msg: `config entry [[ignore]].error=ErrNotDynLinked.dirs contains unclean path "c/" (should have been "c")`},
+				{Line: 6, Msg: `config entry [[ignore]].error=ErrNotDynLinked.dirs contains non-absolute path "c/"`},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, issues := types.LintConfig([]byte(tc.src), nil)
+			assert.Equal(t, tc.want, issues)
+			if issues[0].Line != 1 {
+				assert.Nil(t, cfg)
+			}
+		})
+	}
+
+	base := decode(t, `
+filter_dirs = ["/opt"]
+
+[[ignore]]
+error = "ErrNotDynLinked"
+dirs = ["/usr/bin"]
+`)
+	cfg, issues := types.LintConfig([]byte(lintSrc), base)
+	require.NotNil(t, cfg)
+	assert.Equal(t, []types.LintIssue{
+		{Line: 1, Warning: true, Msg: `config entry filter_files contains "/usr/bin/a" more than once`},
+		{Line: 7, Warning: true, Msg: `config entry [payload.foo].filter_files contains "/opt/foo", already covered by "/opt" in the top-level filters of the base config`},
+		{Line: 12, Warning: true, Msg: `config entry [[payload.foo.ignore]].error=ErrNotDynLinked.files contains a redundant path "/usr/lib/x/y", overlapped by "/usr/lib/x"`},
+		{Line: 12, Warning: true, Msg: `config entry [[payload.foo.ignore]].error=ErrNotDynLinked.files contains "/usr/bin/c", already covered by "/usr/bin" in [[ignore]] of the base config`},
+		{Line: 17, Warning: true, Msg: `config entry [[rpm.bar.ignore]].error=ErrNotDynLinked.files contains "/usr/bin/c", which is also in [[payload.foo.ignore]].error=ErrNotDynLinked.files`},
+		{Line: 17, Warning: true, Msg: `config entry [[rpm.bar.ignore]].error=ErrNotDynLinked.files contains "/usr/bin/c", already covered by "/usr/bin" in [[ignore]] of the base config`},
+		{Line: 21, Warning: true, Msg: `config entry [[ignore]].error=ErrNotDynLinked.files contains "/usr/bin/static", already covered by "/usr/bin" in [[ignore]] of the base config`},
+	}, issues)
+
+	// Tidy fixes all of the above.
+	cfg.Tidy(base)
+	assert.Equal(t, decode(t, `
+filter_files = ["/usr/bin/a"]
+filter_dirs = ["/srv"]
+
+[payload.foo]
+filter_files = ["/usr/bin/b"]
+
+[[payload.foo.ignore]]
+error = "ErrNotDynLinked"
+dirs = ["/usr/lib/x"]
+`), cfg)
+}
+
+// TestLintFixRoundTrip checks that fixing the configs of the repo, and
+// writing them, keeps all their entries.
+func TestLintFixRoundTrip(t *testing.T) {
+	for _, file := range []string{"../../config.toml", "../../dist/releases/4.21/config.toml"} {
+		t.Run(file, func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+			cfg, _ := types.LintConfig(data, nil)
+			require.NotNil(t, cfg)
+			cfg.Tidy(nil)
+			fixed, err := types.EncodeConfigFile("config.toml", cfg)
+			require.NoError(t, err)
+
+			got, err := types.DecodeConfig(fixed)
+			require.NoError(t, err)
+			want, err := types.DecodeConfig(data)
+			require.NoError(t, err)
+			assert.Empty(t, types.DiffConfigs(want, got))
+			// Only the order and the duplicates of the entries may change.
+			want.Tidy(nil)
+			assert.Equal(t, want, got)
+		})
+	}
+}
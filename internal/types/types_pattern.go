@@ -0,0 +1,137 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Path entries in filter and ignore lists are either plain paths, globs, or
+// regular expressions:
+//
+//   - A glob has any of *, ?, [ or {, and is matched with doublestar. A *
+//     matches any part of a path element, ? any character but a /, [abc]
+//     and [!abc] a character class, and {a,b} any of the alternatives. A **
+//     element matches any number of elements, including none, as in
+//     /usr/src/*/rhel*/bin/**.
+//   - A regular expression is prefixed by "re:", and must match the whole
+//     path, as in re:/usr/lib/python3\.[0-9]+/.*\.so.
+//
+// Directory entries match the directory itself, and, when matching a file,
+// any of its parent directories.
+
+const regexpPrefix = "re:"
+
+var (
+	patternsMu sync.RWMutex
+	patterns   = make(map[string]*regexp.Regexp)
+)
+
+// isPattern tells if the path entry is a glob or a regular expression.
+func isPattern(entry string) bool {
+	return strings.HasPrefix(entry, regexpPrefix) || strings.ContainsAny(entry, "*?[{")
+}
+
+// compileRegexp returns the regular expression of a "re:" path entry.
+func compileRegexp(entry string) (*regexp.Regexp, error) {
+	patternsMu.RLock()
+	re, ok := patterns[entry]
+	patternsMu.RUnlock()
+	if ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile("^(?:" + entry[len(regexpPrefix):] + ")$")
+	if err != nil {
+		return nil, err
+	}
+	patternsMu.Lock()
+	patterns[entry] = re
+	patternsMu.Unlock()
+	return re, nil
+}
+
+// validatePattern checks that a path entry, which must be a pattern, is a
+// valid glob or regular expression.
+func validatePattern(entry string) error {
+	if strings.HasPrefix(entry, regexpPrefix) {
+		_, err := compileRegexp(entry)
+		return err
+	}
+	if !doublestar.ValidatePattern(entry) {
+		return doublestar.ErrBadPattern
+	}
+	return nil
+}
+
+// matchPath tells if path matches the entry.
+func matchPath(entry, path string) bool {
+	if !isPattern(entry) {
+		return entry == path
+	}
+	if !strings.HasPrefix(entry, regexpPrefix) {
+		// Invalid globs never match, and are reported by Validate.
+		ok, _ := doublestar.Match(entry, path)
+		return ok
+	}
+	re, err := compileRegexp(entry)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(path)
+}
+
+// matchParentDir tells if any of the parent directories of path matches the
+// directory entry.
+func matchParentDir(dir, path string) bool {
+	if !isPattern(dir) {
+		return strings.HasPrefix(path, dir+"/")
+	}
+	for i := len(path) - 1; i > 0; i-- {
+		if path[i] == '/' && matchPath(dir, path[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// patternDir returns the directory all the paths matched by a glob are
+// under, or an empty string if unknown.
+func patternDir(glob string) string {
+	if strings.HasPrefix(glob, regexpPrefix) {
+		return ""
+	}
+	i := strings.IndexAny(glob, "*?[{\\")
+	if i < 0 {
+		return glob
+	}
+	return glob[:strings.LastIndexByte(glob[:i], '/')+1]
+}
+
+// coveredByDir tells if all the paths matched by entry are under the
+// directory entry dir.
+func coveredByDir(entry, dir string) bool {
+	if !isPattern(entry) {
+		return matchParentDir(dir, entry)
+	}
+	p := patternDir(entry)
+	if p == "" {
+		return false
+	}
+	// All the paths matched by entry are under p.
+	p = strings.TrimSuffix(p, "/")
+	return p != "" && (matchPath(dir, p) || matchParentDir(dir, p))
+}
+
+type errBadPattern struct {
+	Listname string
+	Pattern  string
+	Err      error
+}
+
+func (e *errBadPattern) Error() string {
+	return fmt.Sprintf("config entry %s contains invalid pattern %q: %v", e.Listname, e.Pattern, e.Err)
+}
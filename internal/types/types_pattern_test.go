@@ -0,0 +1,87 @@
+package types
+
+import "testing"
+
+func TestMatchPath(t *testing.T) {
+	testCases := []struct {
+		entry, path string
+		want        bool
+	}{
+		{"/usr/bin/foo", "/usr/bin/foo", true},
+		{"/usr/bin/foo", "/usr/bin/foo2", false},
+		{"/usr/bin/*", "/usr/bin/foo", true},
+		{"/usr/bin/*", "/usr/bin/foo/bar", false},
+		{"/usr/bin/fo?", "/usr/bin/foo", true},
+		{"/usr/bin/[a-f]oo", "/usr/bin/foo", true},
+		{"/usr/bin/[!a-f]oo", "/usr/bin/foo", false},
+		{"/usr/bin/{foo,bar}", "/usr/bin/bar", true},
+		{"/usr/bin/{foo,bar}", "/usr/bin/baz", false},
+		{"/usr/src/*/rhel*/bin/**", "/usr/src/multus-cni/rhel10/bin", true},
+		{"/usr/src/*/rhel*/bin/**", "/usr/src/multus-cni/rhel10/bin/multus", true},
+		{"/usr/src/*/rhel*/bin/**", "/usr/src/multus-cni/rhel10/sbin", false},
+		{"/usr/**/bin", "/usr/bin", true},
+		{"/usr/**/bin", "/usr/local/share/bin", true},
+		{"/usr/**/bin", "/usr/local/sbin", false},
+		// ** in part of an element is a *.
+		{"/usr/src/a**", "/usr/src/abc", true},
+		{"/usr/src/a**", "/usr/src/a/bc", false},
+		{`/usr/bin/\*`, "/usr/bin/*", true},
+		{`/usr/bin/\*`, "/usr/bin/foo", false},
+		{`re:/usr/lib/python3\.[0-9]+/.*\.so`, "/usr/lib/python3.11/site-packages/_ssl.so", true},
+		{`re:/usr/lib/python3\.[0-9]+/.*\.so`, "/usr/lib/python3.11/site-packages/_ssl.so.1", false},
+	}
+	for _, tc := range testCases {
+		if got := matchPath(tc.entry, tc.path); got != tc.want {
+			t.Errorf("matchPath(%q, %q): got %v, want %v", tc.entry, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestMatchParentDir(t *testing.T) {
+	testCases := []struct {
+		dir, path string
+		want      bool
+	}{
+		{"/usr/src", "/usr/src/foo/bin", true},
+		{"/usr/src", "/usr/src", false},
+		{"/usr/src", "/usr/srcfoo/bin", false},
+		{"/usr/src/*/rhel*", "/usr/src/multus-cni/rhel9/bin/multus", true},
+		{"/usr/src/*/rhel*", "/usr/src/multus-cni/bin/multus", false},
+	}
+	for _, tc := range testCases {
+		if got := matchParentDir(tc.dir, tc.path); got != tc.want {
+			t.Errorf("matchParentDir(%q, %q): got %v, want %v", tc.dir, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestValidatePatterns(t *testing.T) {
+	testCases := []struct {
+		name              string
+		files, dirs       []string
+		wantErr, wantWarn bool
+	}{
+		{name: "valid", files: []string{"/usr/bin/*", `re:/opt/.*\.so`}, dirs: []string{"/usr/src/*/rhel*/bin/**"}},
+		{name: "unterminated class", files: []string{"/usr/bin/[a-z"}, wantErr: true},
+		{name: "unterminated alternatives", files: []string{"/usr/bin/{a,b"}, wantErr: true},
+		{name: "bad regexp", files: []string{"re:/usr/bin/(foo"}, wantErr: true},
+		{name: "relative glob", files: []string{"usr/bin/*"}, wantErr: true},
+		{name: "dir under a dir pattern", dirs: []string{"/usr/src/*", "/usr/src/foo/bin"}, wantWarn: true},
+		{name: "dir matched by a dir pattern", dirs: []string{"/usr/src/*", "/usr/src/foo"}, wantWarn: true},
+		{name: "pattern under a dir", dirs: []string{"/usr/src", "/usr/src/*/bin"}, wantWarn: true},
+		{name: "file pattern under a dir pattern", files: []string{"/usr/src/foo/*/bin"}, dirs: []string{"/usr/src/*"}, wantWarn: true},
+		{name: "no overlap", files: []string{"/usr/src/*/bin"}, dirs: []string{"/usr/src/foo/*"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &ConfigFile{FilterFiles: tc.files, FilterDirs: tc.dirs}
+			err, warn := c.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got error %v, want %v", err, tc.wantErr)
+			}
+			if (warn != nil) != tc.wantWarn {
+				t.Errorf("got warning %v, want %v", warn, tc.wantWarn)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package types
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmespath/go-jmespath"
+	v1 "github.com/openshift/api/image/v1"
+	"go.uber.org/multierr"
+	"k8s.io/klog/v2"
+)
+
+// Rule is a [[rule]] of the config: a JMESPath expression evaluated for
+// each error found, and the action to take when it is true.
+type Rule struct {
+	Name   string     `json:"name" toml:"name"`
+	When   RuleExpr   `json:"when" toml:"when"`
+	Action RuleAction `json:"action" toml:"action"`
+
+	Waiver
+}
+
+// RuleExpr is the compiled JMESPath expression of a rule.
+type RuleExpr struct {
+	src  string
+	expr *jmespath.JMESPath
+}
+
+// UnmarshalText is used when parsing toml config.
+func (e *RuleExpr) UnmarshalText(text []byte) error {
+	expr, err := jmespath.Compile(string(text))
+	if err != nil {
+		return fmt.Errorf("when=%q is not a valid expression: %w", text, err)
+	}
+	e.src, e.expr = string(text), expr
+	return nil
+}
+
+// MarshalText is used when writing the configuration as toml.
+func (e RuleExpr) MarshalText() ([]byte, error) {
+	return []byte(e.src), nil
+}
+
+func (e RuleExpr) String() string {
+	return e.src
+}
+
+// RuleAction is what a rule does with the errors it matches.
+type RuleAction string
+
+const (
+	RuleIgnore  RuleAction = "ignore"  // The error is ignored.
+	RuleWarning RuleAction = "warning" // The error is only a warning.
+	RuleError   RuleAction = "error"   // The error fails the scan.
+)
+
+// UnmarshalText is used when parsing toml config.
+func (a *RuleAction) UnmarshalText(text []byte) error {
+	switch act := RuleAction(text); act {
+	case RuleIgnore, RuleWarning, RuleError:
+		*a = act
+		return nil
+	}
+	return fmt.Errorf("action=%q is not recognized in config (must be \"ignore\", \"warning\" or \"error\")", text)
+}
+
+func validateRules(perr, pwarn *error, rules []Rule) {
+	seen := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		section := "[[rule]]"
+		if r.Name == "" {
+			multierr.AppendInto(perr, &errEmpty{section, "name="})
+		} else {
+			section += ".name=" + r.Name
+			if seen[r.Name] {
+				multierr.AppendInto(perr, &errDup{"[[rule]]", r.Name})
+			}
+			seen[r.Name] = true
+		}
+		if r.When.expr == nil {
+			multierr.AppendInto(perr, &errEmpty{section, "when="})
+		}
+		if r.Action == "" {
+			multierr.AppendInto(perr, &errEmpty{section, "action="})
+		}
+		validateWaiver(section, pwarn, r.Waiver)
+	}
+}
+
+// mergeRules adds the rules of add to main, but for the ones with the
+// names of rules main already has.
+func mergeRules(perr *error, main, add []Rule) []Rule {
+	for _, a := range add {
+		dup := false
+		for _, m := range main {
+			dup = dup || m.Name == a.Name
+		}
+		if dup {
+			multierr.AppendInto(perr, &errDup{"[[rule]]", a.Name})
+			continue
+		}
+		main = append(main, a)
+	}
+	return main
+}
+
+// ruleInput returns the data the rule expressions are evaluated against,
+// for the error err found for res, in the image of tag and component, whose
+// OS is os.
+func ruleInput(res *ScanResult, tag *v1.TagReference, component *OpenshiftComponent, os string, err error) map[string]any {
+	settings := make(map[string]any, len(res.GoBuildSettings))
+	for k, v := range res.GoBuildSettings {
+		settings[k] = v
+	}
+	modules := make([]any, 0, len(res.ModulesUsed))
+	for _, m := range res.ModulesUsed {
+		modules = append(modules, m)
+	}
+	symbols := make([]any, 0, len(res.GoFIPSSymbols))
+	for _, sym := range res.GoFIPSSymbols {
+		symbols = append(symbols, sym)
+	}
+	in := map[string]any{
+		"path":            res.Path,
+		"component":       "",
+		"tag":             "",
+		"image":           "",
+		"rpm":             res.RPM,
+		"nvra":            res.NVRA,
+		"os":              os,
+		"error":           KnownErrorName(err),
+		"message":         err.Error(),
+		"go_version":      res.GoVersion,
+		"build_settings":  settings,
+		"modules":         modules,
+		"go_no_crypto":    res.GoNoCrypto,
+		"go_native_fips":  res.GoNativeFIPS,
+		"go_fips_symbols": symbols,
+	}
+	if component != nil {
+		in["component"] = component.Component
+	}
+	if tag != nil {
+		in["tag"] = tag.Name
+		if tag.From != nil {
+			in["image"] = tag.From.Name
+		}
+	}
+	return in
+}
+
+// matchRules returns the first of the rules which have not expired whose
+// expression is true for the error err found for res, if any.
+func matchRules(rules []Rule, res *ScanResult, tag *v1.TagReference, component *OpenshiftComponent, os string, err error) *Rule {
+	if len(rules) == 0 || err == nil {
+		return nil
+	}
+	in := ruleInput(res, tag, component, os, err)
+	for i := range rules {
+		r := &rules[i]
+		if r.Expired() || r.When.expr == nil {
+			continue
+		}
+		v, evalErr := r.When.expr.Search(in)
+		if evalErr != nil {
+			klog.Warningf("rule %q: can't evaluate %q for %s: %v", r.Name, r.When, res.Path, evalErr)
+			continue
+		}
+		if v == true {
+			return r
+		}
+	}
+	return nil
+}
+
+// ApplyRules changes the errors of the results according to the first rule
+// matching each of them: ignored errors are removed, and the others get the
+// level the rule sets.
+func (c *Config) ApplyRules(results *ScanResults) {
+	for _, res := range results.Items {
+		if res.Error == nil {
+			continue
+		}
+		r := matchRules(c.Rules, res, res.Tag, res.Component, res.OS, res.Error.Error)
+		if r == nil {
+			continue
+		}
+		klog.V(1).InfoS("rule applied", "rule", r.Name, "action", r.Action, "path", res.Path, "error", res.Error.Error)
+		switch r.Action {
+		case RuleIgnore:
+			res.Success()
+		case RuleWarning:
+			res.Error.Level = Warning
+		case RuleError:
+			res.Error.Level = Error
+		}
+	}
+}
+
+type ruleScopeKey struct{}
+
+// ruleScope is what the rules are evaluated with when scanning an image,
+// besides the scan results.
+type ruleScope struct {
+	rules     []Rule
+	tag       *v1.TagReference
+	component *OpenshiftComponent
+	os        string
+}
+
+// WithRules returns a context for scanning the image of tag and component,
+// whose OS is os, with which IgnoredByRule evaluates the rules of the
+// config.
+func (c *Config) WithRules(ctx context.Context, tag *v1.TagReference, component *OpenshiftComponent, os string) context.Context {
+	if len(c.Rules) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, ruleScopeKey{}, &ruleScope{c.Rules, tag, component, os})
+}
+
+// IgnoredByRule tells if a rule of the context (see Config.WithRules)
+// ignores the error err found for res. Ignoring the errors as they are
+// found lets the remaining checks run, unlike ApplyRules.
+func IgnoredByRule(ctx context.Context, res *ScanResult, err error) bool {
+	scope, ok := ctx.Value(ruleScopeKey{}).(*ruleScope)
+	if !ok {
+		return false
+	}
+	os := res.OS
+	if os == "" {
+		// Not set until the scan is done.
+		os = scope.os
+	}
+	r := matchRules(scope.rules, res, scope.tag, scope.component, os, err)
+	if r == nil || r.Action != RuleIgnore {
+		return false
+	}
+	klog.V(1).InfoS("rule applied", "rule", r.Name, "action", r.Action, "path", res.Path, "error", err)
+	return true
+}
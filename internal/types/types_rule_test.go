@@ -0,0 +1,135 @@
+package types_test
+
+import (
+	"context"
+	"debug/buildinfo"
+	"fmt"
+	"runtime/debug"
+	"testing"
+
+	v1 "github.com/openshift/api/image/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+func TestRules(t *testing.T) {
+	// Not decode, which fails on the warning about the expired rule.
+	cfgFile, err := types.DecodeConfig([]byte(`
+[[rule]]
+name = "expired"
+when = "error == 'ErrNotDynLinked'"
+action = "error"
+expires = 2000-01-01
+
+[[rule]]
+name = "ose libexec"
+when = "error == 'ErrNotDynLinked' && starts_with(path, '/usr/libexec/') && starts_with(component, 'ose-') && length(modules) == ` + "`0`" + `"
+action = "ignore"
+reason = "no crypto"
+
+[[rule]]
+name = "no fips tag"
+when = "error == 'ErrGoMissingTag' && !contains(build_settings.\"-tags\" || '', 'strictfipsruntime')"
+action = "error"
+
+[[rule]]
+name = "old go"
+when = "starts_with(go_version, 'go1.20') && image == 'quay.io/foo'"
+action = "warning"
+`))
+	require.NoError(t, err)
+	cfg := &types.Config{ConfigFile: *cfgFile}
+
+	ose := &types.OpenshiftComponent{Component: "ose-foo"}
+	goBinary := func(res *types.ScanResult, goVersion, tags string) *types.ScanResult {
+		return res.SetGoBuildInfo(&buildinfo.BuildInfo{
+			GoVersion: goVersion,
+			Settings:  []debug.BuildSetting{{Key: "-tags", Value: tags}},
+		})
+	}
+	image := &v1.TagReference{Name: "foo", From: &corev1.ObjectReference{Name: "quay.io/foo"}}
+	missingTag := fmt.Errorf("%w: strictfipsruntime", types.ErrGoMissingTag)
+
+	for _, tc := range []struct {
+		name string
+		res  *types.ScanResult
+		want types.ErrorLevel
+		ok   bool // The result is not successful.
+	}{
+		{
+			"ignored",
+			types.NewScanResult().SetPath("/usr/libexec/x").SetComponent(ose).SetError(types.ErrNotDynLinked),
+			0, false,
+		},
+		{
+			"crypto modules used",
+			types.NewScanResult().SetPath("/usr/libexec/x").SetComponent(ose).SetModulesUsed([]string{"openssl"}).SetError(types.ErrNotDynLinked),
+			types.Error, true,
+		},
+		{
+			"other component",
+			types.NewScanResult().SetPath("/usr/libexec/x").SetComponent(&types.OpenshiftComponent{Component: "foo"}).SetValidationError(types.NewValidationError(types.ErrNotDynLinked).SetWarning()),
+			types.Warning, true,
+		},
+		{
+			"escalated",
+			goBinary(types.NewScanResult().SetValidationError(types.NewValidationError(missingTag).SetWarning()), "go1.22.1", "foo"),
+			types.Error, true,
+		},
+		{
+			"not escalated",
+			goBinary(types.NewScanResult().SetValidationError(types.NewValidationError(missingTag).SetWarning()), "go1.22.1", "foo,strictfipsruntime"),
+			types.Warning, true,
+		},
+		{
+			"downgraded",
+			goBinary(types.NewScanResult().SetTag(image).SetError(types.ErrGoNotCgoEnabled), "go1.20.3", ""),
+			types.Warning, true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg.ApplyRules(types.NewScanResults().Append(tc.res))
+			assert.Equal(t, tc.ok, !tc.res.IsSuccess())
+			if tc.ok {
+				assert.Equal(t, tc.want, tc.res.Error.Level)
+			}
+		})
+	}
+
+	ctx := cfg.WithRules(context.Background(), nil, ose, "")
+	res := types.NewScanResult().SetPath("/usr/libexec/x")
+	assert.True(t, types.IgnoredByRule(ctx, res, types.ErrNotDynLinked))
+	assert.False(t, types.IgnoredByRule(ctx, res, types.ErrGoNotCgoEnabled))
+	assert.False(t, types.IgnoredByRule(context.Background(), res, types.ErrNotDynLinked))
+
+	// The OS of the image is known before the results have it.
+	osCfg, err := types.DecodeConfig([]byte("[[rule]]\nname = \"rhel 9\"\nwhen = \"starts_with(os, 'rhel 9')\"\naction = \"ignore\"\n"))
+	require.NoError(t, err)
+	cfg = &types.Config{ConfigFile: *osCfg}
+	assert.True(t, types.IgnoredByRule(cfg.WithRules(context.Background(), nil, nil, "rhel 9.4 (ubi-minimal)"), res, types.ErrNotDynLinked))
+	assert.False(t, types.IgnoredByRule(cfg.WithRules(context.Background(), nil, nil, "rhel 8.10"), res, types.ErrNotDynLinked))
+	assert.False(t, types.IgnoredByRule(cfg.WithRules(context.Background(), nil, nil, "rhel 9.4"), res.SetOSName("rhel 10.0"), types.ErrNotDynLinked))
+
+	// What the checks of Go binaries found.
+	cryptoCfg, err := types.DecodeConfig([]byte("[[rule]]\nname = \"no crypto\"\nwhen = \"go_no_crypto || (!go_native_fips && contains(go_fips_symbols, 'vendor/github.com/golang-fips/openssl/v2.Init'))\"\naction = \"ignore\"\n"))
+	require.NoError(t, err)
+	cfg = &types.Config{ConfigFile: *cryptoCfg}
+	ctx = cfg.WithRules(context.Background(), nil, nil, "")
+	assert.True(t, types.IgnoredByRule(ctx, &types.ScanResult{GoNoCrypto: true}, types.ErrNotDynLinked))
+	assert.True(t, types.IgnoredByRule(ctx, &types.ScanResult{GoFIPSSymbols: []string{"vendor/github.com/golang-fips/openssl/v2.Init"}}, types.ErrNotDynLinked))
+	assert.False(t, types.IgnoredByRule(ctx, &types.ScanResult{GoNativeFIPS: true, GoFIPSSymbols: []string{"vendor/github.com/golang-fips/openssl/v2.Init"}}, types.ErrNotDynLinked))
+	assert.False(t, types.IgnoredByRule(ctx, &types.ScanResult{}, types.ErrNotDynLinked))
+
+	bad, err := types.DecodeConfig([]byte("[[rule]]\nwhen = \"path == 'a'\"\n\n[[rule]]\nname = \"a\"\naction = \"ignore\"\n"))
+	require.NoError(t, err)
+	err, _ = bad.Validate()
+	assert.EqualError(t, err, "config entry [[rule]] has no name= set; config entry [[rule]] has no action= set; config entry [[rule]].name=a has no when= set")
+
+	_, err = types.DecodeConfig([]byte("[[rule]]\nname = \"a\"\nwhen = \"foo((\"\naction = \"ignore\"\n"))
+	assert.ErrorContains(t, err, `when="foo((" is not a valid expression`)
+	_, err = types.DecodeConfig([]byte("[[rule]]\nname = \"a\"\nwhen = \"foo\"\naction = \"drop\"\n"))
+	assert.ErrorContains(t, err, `action="drop" is not recognized`)
+}
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"debug/buildinfo"
+
 	v1 "github.com/openshift/api/image/v1"
 )
 
@@ -52,11 +54,13 @@ func (r *ScanResult) SetOS(info OSInfo) *ScanResult {
 		r.SetError(ErrOSNotCertified)
 	}
 
-	// We currently only support checking a specific file for the
-	// distribution information. This may need to evolve to be more
-	// flexible in the future if distribution detection becomes more
-	// advanced.
 	r.Path = info.Path
+	r.OS = info.String()
+	return r
+}
+
+func (r *ScanResult) SetOSName(name string) *ScanResult {
+	r.OS = name
 	return r
 }
 
@@ -85,7 +89,21 @@ func (r *ScanResult) SetRPM(rpm string) *ScanResult {
 	return r
 }
 
+func (r *ScanResult) SetNVRA(nvra string) *ScanResult {
+	r.NVRA = nvra
+	return r
+}
+
 func (r *ScanResult) SetModulesUsed(used []string) *ScanResult {
 	r.ModulesUsed = used
 	return r
 }
+
+func (r *ScanResult) SetGoBuildInfo(bi *buildinfo.BuildInfo) *ScanResult {
+	r.GoVersion = bi.GoVersion
+	r.GoBuildSettings = make(map[string]string, len(bi.Settings))
+	for _, s := range bi.Settings {
+		r.GoBuildSettings[s.Key] = s.Value
+	}
+	return r
+}
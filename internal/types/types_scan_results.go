@@ -8,3 +8,13 @@ func (sr *ScanResults) Append(result *ScanResult) *ScanResults {
 	sr.Items = append(sr.Items, result)
 	return sr
 }
+
+// SetOSName sets the detected OS of all the results which have none.
+func (sr *ScanResults) SetOSName(name string) *ScanResults {
+	for _, r := range sr.Items {
+		if r.OS == "" {
+			r.SetOSName(name)
+		}
+	}
+	return sr
+}
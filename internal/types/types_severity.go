@@ -0,0 +1,136 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+
+	"go.uber.org/multierr"
+)
+
+// UnmarshalText is used when parsing the [severity] tables of the toml
+// config.
+func (l *ErrorLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "error":
+		*l = Error
+	case "warning":
+		*l = Warning
+	default:
+		return fmt.Errorf("severity %q is not recognized in config (must be \"error\" or \"warning\")", text)
+	}
+	return nil
+}
+
+// MarshalText is used when writing the configuration as toml.
+func (l ErrorLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l ErrorLevel) String() string {
+	switch l {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	}
+	return fmt.Sprintf("ErrorLevel(%d)", int64(l))
+}
+
+type errUnknownSeverity struct {
+	Listname string
+	ErrName  string
+}
+
+func (e *errUnknownSeverity) Error() string {
+	return `config entry ` + e.Listname + ` has unknown error "` + e.ErrName + `"`
+}
+
+func validateSeverity(listname string, perr *error, severity map[string]ErrorLevel) {
+	names := make([]string, 0, len(severity))
+	for name := range severity {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := KnownErrors[name]; !ok {
+			multierr.AppendInto(perr, &errUnknownSeverity{listname, name})
+		}
+	}
+}
+
+// mergeSeverity adds the severities of add to main, overriding the ones
+// main already has, so that a config can change the severities of the
+// config it is added to.
+func mergeSeverity(main, add map[string]ErrorLevel) map[string]ErrorLevel {
+	if main == nil {
+		return add
+	}
+	for k, v := range add {
+		main[k] = v
+	}
+	return main
+}
+
+// JavaImageSeverity is the default severity of the errors of java image
+// scans, which the config can override: as the crypto modules used are
+// those of the base image, failing their validation is only a warning.
+var JavaImageSeverity = map[string]ErrorLevel{
+	"ErrFipsArtifactMissing":     Warning,
+	"ErrFipsArtifactTampered":    Warning,
+	"ErrFipsArtifactUntrusted":   Warning,
+	"ErrFipsArtifactVersionHigh": Warning,
+	"ErrFipsArtifactVersionLow":  Warning,
+}
+
+// SetDefaultSeverity sets the top level severity of the errors of defaults
+// for which the config does not set one.
+func (c *ConfigFile) SetDefaultSeverity(defaults map[string]ErrorLevel) {
+	if c.Severity == nil {
+		c.Severity = make(map[string]ErrorLevel, len(defaults))
+	}
+	for k, v := range defaults {
+		if _, ok := c.Severity[k]; !ok {
+			c.Severity[k] = v
+		}
+	}
+}
+
+// SeverityOf returns the severity the config sets for the error of the
+// result, if any. The severity set for the rpm of the result comes first,
+// then the ones set for its component, for its tag, and at the top level.
+func (c *Config) SeverityOf(res *ScanResult) (ErrorLevel, bool) {
+	if res.Error == nil {
+		return 0, false
+	}
+	name := KnownErrorName(res.Error.Error)
+	if name == "" {
+		return 0, false
+	}
+	var tables []map[string]ErrorLevel
+	if res.RPM != "" {
+		tables = append(tables, c.RPMIgnores[res.RPM].Severity)
+	}
+	if res.Component != nil {
+		tables = append(tables, c.PayloadIgnores[res.Component.Component].Severity)
+	}
+	if res.Tag != nil {
+		tables = append(tables, c.TagIgnores[res.Tag.Name].Severity)
+	}
+	tables = append(tables, c.Severity)
+	for _, t := range tables {
+		if level, ok := t[name]; ok {
+			return level, true
+		}
+	}
+	return 0, false
+}
+
+// ApplySeverity sets the level of the errors of the results to the
+// severity the config sets for them, if any.
+func (c *Config) ApplySeverity(results *ScanResults) {
+	for _, res := range results.Items {
+		if level, ok := c.SeverityOf(res); ok {
+			res.Error.Level = level
+		}
+	}
+}
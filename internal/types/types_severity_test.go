@@ -0,0 +1,102 @@
+package types_test
+
+import (
+	"fmt"
+	"testing"
+
+	v1 "github.com/openshift/api/image/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+func TestSeverity(t *testing.T) {
+	cfg := &types.Config{ConfigFile: *decode(t, `
+[severity]
+ErrGoMissingTag = "error"
+ErrNotDynLinked = "warning"
+
+[payload.foo.severity]
+ErrGoMissingTag = "warning"
+
+[tag.bar.severity]
+ErrGoMissingTag = "error"
+ErrNotDynLinked = "error"
+
+[rpm.baz.severity]
+ErrNotDynLinked = "error"
+`)}
+	// A release config overrides the severities of the main config.
+	require.NoError(t, cfg.Add(decode(t, `
+[tag.bar.severity]
+ErrGoMissingTag = "warning"
+`)))
+
+	missingTag := fmt.Errorf("%w: strictfipsruntime", types.ErrGoMissingTag)
+	for _, tc := range []struct {
+		name       string
+		res        *types.ScanResult
+		want       types.ErrorLevel
+		overridden bool
+	}{
+		{"success", types.NewScanResult(), 0, false},
+		{"unknown error", types.NewScanResult().SetError(fmt.Errorf("foo")), 0, false},
+		{"not listed", types.NewScanResult().SetError(types.ErrGoNoTags), 0, false},
+		{"top level", types.NewScanResult().SetError(missingTag), types.Error, true},
+		{"top level warning", types.NewScanResult().SetError(types.ErrNotDynLinked), types.Warning, true},
+		{
+			"component",
+			types.NewScanResult().SetError(missingTag).SetComponent(&types.OpenshiftComponent{Component: "foo"}),
+			types.Warning, true,
+		},
+		{
+			"tag before top level",
+			types.NewScanResult().SetError(types.ErrNotDynLinked).SetComponent(&types.OpenshiftComponent{Component: "foo"}).SetTag(&v1.TagReference{Name: "bar"}),
+			types.Error, true,
+		},
+		{"tag overridden by release", types.NewScanResult().SetError(missingTag).SetTag(&v1.TagReference{Name: "bar"}), types.Warning, true},
+		{
+			"rpm before component",
+			types.NewScanResult().SetError(types.ErrNotDynLinked).SetRPM("baz").SetComponent(&types.OpenshiftComponent{Component: "foo"}),
+			types.Error, true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			level, ok := cfg.SeverityOf(tc.res)
+			assert.Equal(t, tc.overridden, ok)
+			assert.Equal(t, tc.want, level)
+		})
+	}
+
+	results := types.NewScanResults().Append(types.NewScanResult().SetError(missingTag))
+	results.Items[0].Error.SetWarning()
+	cfg.ApplySeverity(results)
+	assert.True(t, results.Items[0].IsLevel(types.Error))
+
+	bad, err := types.DecodeConfig([]byte("[severity]\nErrFoo = \"error\"\n\n[rpm.x.severity]\nErrBar = \"warning\"\n"))
+	require.NoError(t, err)
+	err, _ = bad.Validate()
+	assert.EqualError(t, err, `config entry [severity] has unknown error "ErrFoo"; config entry [rpm.x.severity] has unknown error "ErrBar"`)
+
+	_, err = types.DecodeConfig([]byte("[severity]\nErrGoNoTags = \"fatal\"\n"))
+	assert.ErrorContains(t, err, `severity "fatal" is not recognized`)
+}
+
+func TestSetDefaultSeverity(t *testing.T) {
+	cfg := &types.Config{ConfigFile: *decode(t, `
+[severity]
+ErrFipsArtifactMissing = "error"
+`)}
+	cfg.SetDefaultSeverity(types.JavaImageSeverity)
+	err, _ := cfg.Validate()
+	require.NoError(t, err)
+
+	// The config overrides the defaults.
+	level, ok := cfg.SeverityOf(types.NewScanResult().SetError(types.ErrFipsArtifactMissing))
+	assert.True(t, ok)
+	assert.Equal(t, types.Error, level)
+	level, ok = cfg.SeverityOf(types.NewScanResult().SetError(types.ErrFipsArtifactTampered))
+	assert.True(t, ok)
+	assert.Equal(t, types.Warning, level)
+}
@@ -0,0 +1,47 @@
+package types
+
+import "fmt"
+
+// RecordSources records name as the source of the exception entries of
+// cfg, decoded from data, with their line if it is toml. When configs are
+// added (see ConfigFile.Add), the sources of the entries the config already
+// has are kept, as are the entries.
+func RecordSources(name string, data []byte, cfg *ConfigFile) {
+	var src *tomlSource
+	if ConfigFormat(name, data) == FormatTOML {
+		src = parseTOMLSource(data)
+	}
+	if cfg.sources == nil {
+		cfg.sources = make(map[exceptionKey]string)
+	}
+	for _, l := range cfg.exceptionLists() {
+		table, errName, key := parseListname(l.section)
+		for _, e := range l.entries {
+			k := l.key(e)
+			if _, ok := cfg.sources[k]; ok {
+				continue
+			}
+			loc := name
+			if src != nil {
+				if line := src.line(table, errName, key, e); line > 0 {
+					loc = fmt.Sprintf("%s:%d", name, line)
+				}
+			}
+			cfg.sources[k] = loc
+		}
+	}
+}
+
+// mergeSources adds the sources of add to main, keeping the ones main
+// already has.
+func mergeSources(main, add map[exceptionKey]string) map[exceptionKey]string {
+	if main == nil {
+		return add
+	}
+	for k, v := range add {
+		if _, ok := main[k]; !ok {
+			main[k] = v
+		}
+	}
+	return main
+}
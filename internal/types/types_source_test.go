@@ -0,0 +1,66 @@
+package types_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+func TestRecordSources(t *testing.T) {
+	mainSrc := []byte(`filter_files = ["/usr/bin/src-main"]
+
+[[ignore]]
+error = "ErrNotDynLinked"
+files = [
+  "/usr/bin/src-static",
+  "/usr/bin/src-dup",
+]
+`)
+	dropInSrc := []byte(`{"ignore": [
+  {"error": "ErrNotDynLinked", "files": ["/usr/bin/src-dup", "/usr/bin/src-drop-in"]},
+  {"error": "ErrNotDynLinked", "files": ["/usr/bin/src-static"], "ticket": "OCPBUGS-1"}
+]}`)
+
+	load := func() *types.Config {
+		cfg, err := types.DecodeConfigFile("config.toml", mainSrc)
+		require.NoError(t, err)
+		types.RecordSources("config.toml", mainSrc, cfg)
+		add, err := types.DecodeConfigFile("10-extra.json", dropInSrc)
+		require.NoError(t, err)
+		types.RecordSources("10-extra.json", dropInSrc, add)
+		// The duplicate entry is kept from the first config.
+		assert.Error(t, cfg.Add(add))
+		return &types.Config{ConfigFile: *cfg}
+	}
+
+	c := load()
+	ctx := c.WithExceptions(context.Background())
+	assert.True(t, c.ErrIgnores.Ignore(ctx, "/usr/bin/src-static", types.ErrNotDynLinked))
+	assert.True(t, c.ErrIgnores.Ignore(ctx, "/usr/bin/src-drop-in", types.ErrNotDynLinked))
+
+	type use struct{ entry, ticket, source string }
+	var applied, unused []use
+	for _, u := range c.AppliedWaivers() {
+		applied = append(applied, use{u.Entry, u.Ticket, u.Source})
+	}
+	for _, u := range c.UnusedExceptions() {
+		unused = append(unused, use{u.Entry, u.Ticket, u.Source})
+	}
+	assert.Equal(t, []use{
+		{"/usr/bin/src-static", "", "config.toml:6"},
+		{"/usr/bin/src-drop-in", "", "10-extra.json"},
+	}, applied)
+	// The same entry, with another waiver, keeps its own source.
+	assert.Equal(t, []use{
+		{"/usr/bin/src-main", "", "config.toml:1"},
+		{"/usr/bin/src-dup", "", "config.toml:7"},
+		{"/usr/bin/src-static", "OCPBUGS-1", "10-extra.json"},
+	}, unused)
+
+	// The uses are recorded per config.
+	assert.Empty(t, load().AppliedWaivers())
+}
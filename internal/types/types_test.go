@@ -31,6 +31,20 @@ func TestVersionInRange(t *testing.T) {
 		{"below range", "1.0.0", "1.1.1", "1.1.1", false, true},
 		{"above range", "3.0.7", "1.1.1", "1.1.1", true, false},
 		{"no bounds", "3.0.7", "", "", true, true},
+
+		{"semver bound ignores epoch", "1:3.0.7-27.el9", "3.0.7", "3.0.7", true, true},
+		{"semver bound ignores letter suffix", "1:1.1.1k-12.el8_9", "1.1.1", "1.1.1", true, true},
+		{"release above min", "1:3.0.7-27.el9", "3.0.7-25.el9", "", true, true},
+		{"release below min", "1:3.0.7-24.el9", "3.0.7-25.el9", "", false, true},
+		{"release above max", "1:3.0.7-27.el9", "", "3.0.7-25.el9", true, false},
+		{"release numeric ordering", "3.0.7-100.el9", "3.0.7-25.el9", "3.0.7-100.el9", true, true},
+		{"release dist suffix", "3.0.7-24.el9_2", "3.0.7-24.el9", "3.0.7-24.el9", true, false},
+		{"epoch bound", "1:3.0.7-27.el9", "2:3.0.0", "", false, true},
+		{"epoch bound missing in installed", "3.0.7-27.el9", "", "0:3.0.7", true, true},
+		{"version only with letters", "1.1.1k-12.el8", "1.1.1g", "1.1.1k", true, true},
+		{"tilde pre-release", "3.2.0~beta1-1.el10", "3.2.0-1.el10", "", false, true},
+		{"caret post-release", "3.2.0^20250101-1.el10", "3.2.0", "3.2.0", true, true},
+		{"caret post-release rpm bound", "3.2.0^20250101-1.el10", "", "3.2.0-1.el10", true, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -42,3 +56,54 @@ func TestVersionInRange(t *testing.T) {
 		})
 	}
 }
+
+func TestRpmvercmp(t *testing.T) {
+	// From rpm's tests/rpmvercmp.at.
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "2.0", -1},
+		{"2.0.1", "2.0.1", 0},
+		{"2.0", "2.0.1", -1},
+		{"2.0.1a", "2.0.1", 1},
+		{"5.5p1", "5.5p2", -1},
+		{"5.5p10", "5.5p1", 1},
+		{"10xyz", "10.1xyz", -1},
+		{"xyz10", "xyz10.1", -1},
+		{"xyz.4", "8", -1},
+		{"xyz.4", "2", -1},
+		{"5.5p2", "5.6p1", -1},
+		{"5.6p1", "6.5p1", -1},
+		{"6.0.rc1", "6.0", 1},
+		{"10b2", "10a1", 1},
+		{"1.0a", "1.0aa", -1},
+		{"10.0001", "10.1", 0},
+		{"10.0001", "10.0039", -1},
+		{"4.999.9", "5.0", -1},
+		{"20101121", "20101122", -1},
+		{"2_0", "2_0", 0},
+		{"2.0", "2_0", 0},
+		{"a", "a", 0},
+		{"a+", "a_", 0},
+		{"+", "_", 0},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0~rc1~git123", "1.0~rc1", -1},
+		{"1.0^", "1.0", 1},
+		{"1.0^git1", "1.0^git2", -1},
+		{"1.0^git1", "1.01", -1},
+		{"1.0^20160101", "1.0.1", -1},
+		{"1.0~rc1^git1", "1.0~rc1", 1},
+		{"1.0^git1~pre", "1.0^git1", -1},
+	}
+	for _, tt := range tests {
+		if got := rpmvercmp(tt.a, tt.b); got != tt.want {
+			t.Errorf("rpmvercmp(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := rpmvercmp(tt.b, tt.a); got != -tt.want {
+			t.Errorf("rpmvercmp(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
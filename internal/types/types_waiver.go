@@ -0,0 +1,101 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Date is a calendar date, written as a toml local date (2026-12-31) or a
+// string ("2026-12-31", as in json and yaml configs).
+type Date struct {
+	t time.Time
+}
+
+// NewDate returns the date of t.
+func NewDate(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{time.Date(y, m, d, 0, 0, 0, 0, time.UTC)}
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (d *Date) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case time.Time:
+		*d = NewDate(v)
+		return nil
+	case string:
+		t, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return fmt.Errorf("expires=%q is not a date (YYYY-MM-DD)", v)
+		}
+		*d = NewDate(t)
+		return nil
+	}
+	return fmt.Errorf("expires must be a date (YYYY-MM-DD), not %T", v)
+}
+
+// UnmarshalJSON is used when parsing json and yaml config.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("expires must be a date (YYYY-MM-DD), not %s", data)
+	}
+	return d.UnmarshalTOML(s)
+}
+
+// MarshalTOML implements toml.Marshaler.
+func (d Date) MarshalTOML() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// MarshalText is used when writing the configuration as json.
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d Date) IsZero() bool {
+	return d.t.IsZero()
+}
+
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.t.Format(dateLayout)
+}
+
+// Waiver is the optional metadata of an exception (an [[ignore]] entry, or
+// the filter lists of a [payload.*], [tag.*] or [rpm.*] section): why it
+// was added, the ticket tracking it, and the last day it applies.
+type Waiver struct {
+	Reason  string `json:"reason,omitempty" toml:"reason,omitempty"`
+	Ticket  string `json:"ticket,omitempty" toml:"ticket,omitempty"`
+	Expires Date   `json:"expires,omitzero" toml:"expires,omitempty"`
+}
+
+// Expired tells if the waiver has an expiration date which has passed.
+func (w Waiver) Expired() bool {
+	return !w.Expires.IsZero() && NewDate(time.Now()).t.After(w.Expires.t)
+}
+
+// Describe describes the ticket and expiration of the waiver, if any. It is
+// not a String method, so that it is not promoted to the types embedding
+// a Waiver.
+func (w Waiver) Describe() string {
+	var s []string
+	if w.Ticket != "" {
+		s = append(s, "ticket "+w.Ticket)
+	}
+	if !w.Expires.IsZero() {
+		if w.Expired() {
+			s = append(s, "expired on "+w.Expires.String())
+		} else {
+			s = append(s, "expires on "+w.Expires.String())
+		}
+	}
+	return strings.Join(s, ", ")
+}
@@ -26,6 +26,9 @@ import (
 var (
 	// Compile regular expressions once during initialization.
 	validateStringsOpensslRegexp = regexp.MustCompile(`libcrypto.so(\.?\d+)*`)
+	// OPENSSL_VERSION_TEXT, compiled into every copy of libcrypto,
+	// e.g. "OpenSSL 3.0.13 30 Jan 2024" or "OpenSSL 1.1.1k  FIPS 25 Mar 2021".
+	embeddedOpensslRegexp = regexp.MustCompile(`OpenSSL (\d+\.\d+\.\d+[a-z]?)(?:-[\w.]+)?(?: +[A-Za-z-]+)? +\d{1,2} [A-Z][a-z]{2} \d{4}`)
 
 	// Use these symbols for all go versions up to 1.21.13. These symbols
 	// changed in version 1.21.13 and again in 1.22
@@ -63,6 +66,7 @@ var (
 
 	// Library prefix used to detect openssl linkage in ELF DT_NEEDED.
 	libcryptoPrefix = "libcrypto.so"
+	libsslPrefix    = "libssl.so"
 )
 
 var goFIPSMinVersion string
@@ -80,7 +84,31 @@ type Baton struct {
 	GoVersion   *semver.Version
 	GoBuildInfo *buildinfo.BuildInfo
 	GoSymTable  *gosym.Table
-	ModulesUsed []string
+	// GoFIPSSymbols are the golang-fips symbols found in a Go binary.
+	GoFIPSSymbols []string
+	ModulesUsed   []string
+
+	// The result of the scan of the binary, if any, for the rules
+	// evaluated as the errors are found.
+	result *types.ScanResult
+}
+
+// useModule records that the binary uses the crypto module m.
+func (b *Baton) useModule(m string) {
+	b.ModulesUsed = append(b.ModulesUsed, m)
+	b.updateResult()
+}
+
+// updateResult copies what the checks found so far to the result of the
+// scan, if any.
+func (b *Baton) updateResult() {
+	if b.result == nil {
+		return
+	}
+	b.result.SetModulesUsed(b.ModulesUsed)
+	b.result.GoNoCrypto = b.GoNoCrypto
+	b.result.GoNativeFIPS = b.GoNativeFIPS
+	b.result.GoFIPSSymbols = b.GoFIPSSymbols
 }
 
 type ValidationFn func(ctx context.Context, path string, baton *Baton) *types.ValidationError
@@ -99,6 +127,7 @@ var validationFns = map[string][]ValidationFn{
 	"exe": {
 		validateNotStatic,
 		validateExeOpenssl,
+		validateNodeOpenssl,
 	},
 }
 
@@ -114,6 +143,7 @@ func _loadGoSymbols(_ context.Context, path string, baton *Baton) *types.Validat
 	baton.GoSymTable = symtable
 	if !isUsingCryptoModule(baton.GoSymTable) {
 		baton.GoNoCrypto = true
+		baton.updateResult()
 	}
 	return nil
 }
@@ -143,7 +173,7 @@ func validateGoNativeFIPS(_ context.Context, _ string, baton *Baton) *types.Vali
 		return types.NewValidationError(types.ErrGoFIPSNotCertified)
 	}
 	baton.GoNativeFIPS = true
-	baton.ModulesUsed = append(baton.ModulesUsed, moduleGo)
+	baton.useModule(moduleGo)
 	return nil
 }
 
@@ -186,19 +216,31 @@ func hasGOFIPS140Certified(baton *Baton) bool {
 	return false
 }
 
+// requiredGoSymbols returns the golang-fips symbols a Go binary of version v
+// using crypto must have one of, or nil if they are not checked.
+func requiredGoSymbols(v *semver.Version) []string {
+	switch {
+	case goLessThan118.Check(v):
+		return nil
+	case goLessThan12113.Check(v):
+		return requiredGolangSymbolsPre12113
+	case goLessThan122.Check(v):
+		return requiredGolangSymbolsPre122
+	}
+	return requiredGolangSymbolsPost122
+}
+
 func validateGoSymbols(_ context.Context, _ string, baton *Baton) *types.ValidationError {
 	if baton.GoNoCrypto || baton.GoNativeFIPS {
 		return nil
 	}
-	requiredGolangSymbols := requiredGolangSymbolsPost122
-	if goLessThan118.Check(baton.GoVersion) {
+	requiredGolangSymbols := requiredGoSymbols(baton.GoVersion)
+	if requiredGolangSymbols == nil {
 		return nil
-	} else if goLessThan12113.Check(baton.GoVersion) {
-		requiredGolangSymbols = requiredGolangSymbolsPre12113
-	} else if goLessThan122.Check(baton.GoVersion) {
-		requiredGolangSymbols = requiredGolangSymbolsPre122
 	}
-	if !golang.ExpectedSyms(requiredGolangSymbols, baton.GoSymTable) {
+	baton.GoFIPSSymbols = golang.MatchedSyms(requiredGolangSymbols, baton.GoSymTable)
+	baton.updateResult()
+	if len(baton.GoFIPSSymbols) == 0 {
 		return types.NewValidationError(types.ErrGoMissingSymbols)
 	}
 
@@ -300,7 +342,7 @@ func validateGoOpenssl(_ context.Context, path string, baton *Baton) *types.Vali
 		return nil
 	}
 	// All legacy golang-fips binaries use OpenSSL — tag for module artifact check (Phase 4).
-	baton.ModulesUsed = append(baton.ModulesUsed, moduleOpenssl)
+	baton.useModule(moduleOpenssl)
 	if goGreaterThanOrEqualTo122.Check(baton.GoVersion) {
 		return nil
 	}
@@ -402,6 +444,58 @@ func validateStringsOpenssl(path string, baton *Baton) error {
 	return nil
 }
 
+// embeddedOpensslVersion returns the version of OpenSSL statically linked
+// into the file at path, or an empty string if there is none.
+func embeddedOpensslVersion(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	const (
+		size    = 1 * 1024 * 1024
+		overlap = 128 // Longer than any version string.
+	)
+	buf := make([]byte, size+overlap)
+	n := 0
+	for {
+		m, err := io.ReadFull(f, buf[n:])
+		n += m
+		if match := embeddedOpensslRegexp.FindSubmatch(buf[:n]); match != nil {
+			return "OpenSSL " + string(match[1]), nil
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		// Keep the tail, in case the string spans two chunks.
+		n = copy(buf, buf[n-overlap:n])
+	}
+}
+
+// elfEmbeddedOpenssl returns the version of OpenSSL statically linked into
+// the ELF file. An empty string is returned if there is none, if the file
+// uses the system OpenSSL, or if it is not an ELF file.
+func elfEmbeddedOpenssl(file string) (string, error) {
+	exe, err := elf.Open(file)
+	if err != nil {
+		// Not an ELF file, or not a regular one.
+		return "", nil
+	}
+	libs, _ := exe.ImportedLibraries()
+	exe.Close()
+	for _, lib := range libs {
+		if strings.HasPrefix(lib, libcryptoPrefix) || strings.HasPrefix(lib, libsslPrefix) {
+			// Uses the system OpenSSL.
+			return "", nil
+		}
+	}
+	return embeddedOpensslVersion(file)
+}
+
 func validateExeOpenssl(_ context.Context, path string, baton *Baton) *types.ValidationError {
 	if baton.Static {
 		return nil
@@ -418,7 +512,7 @@ func validateExeOpenssl(_ context.Context, path string, baton *Baton) *types.Val
 	}
 	for _, lib := range libs {
 		if strings.HasPrefix(lib, libcryptoPrefix) {
-			baton.ModulesUsed = append(baton.ModulesUsed, moduleOpenssl)
+			baton.useModule(moduleOpenssl)
 			return nil
 		}
 	}
@@ -499,8 +593,8 @@ func isElfExe(path string, baton *Baton) (bool, error) {
 }
 
 func ScanBinary(ctx context.Context, topDir, innerPath string, rpmIgnores map[string]types.IgnoreLists, errIgnores ...types.ErrIgnoreList) *types.ScanResult {
-	baton := &Baton{TopDir: topDir}
 	res := types.NewScanResult().SetPath(innerPath)
+	baton := &Baton{TopDir: topDir, result: res}
 
 	path := filepath.Join(topDir, innerPath)
 
@@ -519,43 +613,71 @@ func ScanBinary(ctx context.Context, topDir, innerPath string, rpmIgnores map[st
 	}
 	var checks []ValidationFn
 	if goBinary {
+		res.SetGoBuildInfo(baton.GoBuildInfo)
 		checks = validationFns["go"]
 	} else {
 		checks = validationFns["exe"]
 	}
 
-checks:
 	for _, fn := range checks {
 		if err := fn(ctx, path, baton); err != nil {
-			// See if the error is to be ignored.
-			for _, list := range errIgnores {
-				if list.Ignore(innerPath, err.Error) {
-					continue checks
-				}
-			}
-			if res.RPM == "" {
-				// Find out which rpm the file belongs to. For performance reasons,
-				// only do it for files that failed validation.
-				rpm, rpmErr := rpm.NameFromFile(ctx, topDir, innerPath)
-				if rpmErr != nil {
-					klog.Info(rpmErr) // XXX: a minor warning.
-				} else {
-					res.SetRPM(rpm)
-				}
+			if isIgnored(ctx, res, topDir, innerPath, err.Error, rpmIgnores, errIgnores) {
+				continue
 			}
-			// See if the error is to be ignored for the rpm.
-			if res.RPM != "" && len(rpmIgnores) > 0 {
-				if i, ok := rpmIgnores[res.RPM]; ok {
-					if i.ErrIgnores.Ignore(innerPath, err.Error) {
-						continue
-					}
-				}
-			}
-			return res.SetModulesUsed(baton.ModulesUsed).SetValidationError(err)
+			return res.SetValidationError(err)
 		}
 	}
 
-	return res.SetModulesUsed(baton.ModulesUsed).Success()
+	return res.Success()
+}
+
+// isIgnored tells if err found for innerPath is to be ignored, according to
+// either errIgnores, the per-rpm rules from rpmIgnores, or the config rules
+// of the context (see types.Config.WithRules). As a side effect,
+// it sets res.RPM to the name of the rpm the file belongs to, and
+// res.ExpiredWaiver to the waiver that would have ignored err, had it not
+// expired.
+func isIgnored(ctx context.Context, res *types.ScanResult, topDir, innerPath string, err error, rpmIgnores map[string]types.IgnoreLists, errIgnores []types.ErrIgnoreList) bool {
+	ignored := func(list types.ErrIgnoreList) bool {
+		ie := list.Match(ctx, innerPath, err)
+		if ie == nil {
+			return false
+		}
+		if ie.Expired() {
+			res.ExpiredWaiver = &ie.Waiver
+			return false
+		}
+		res.ExpiredWaiver = nil
+		return true
+	}
+	for _, list := range errIgnores {
+		if ignored(list) {
+			return true
+		}
+	}
+	if res.RPM == "" {
+		// Find out which rpm the file belongs to. For performance reasons,
+		// only do it for files that failed validation.
+		idx, rpmErr := rpm.IndexOf(topDir)
+		if rpmErr != nil {
+			klog.Info(rpmErr) // XXX: a minor warning.
+		} else if pkg := idx.Owner(innerPath); pkg != nil {
+			res.SetRPM(pkg.Name).SetNVRA(pkg.NVRA())
+		}
+	}
+	// See if the error is to be ignored for the rpm.
+	if res.RPM != "" && len(rpmIgnores) > 0 {
+		if i, ok := rpmIgnores[res.RPM]; ok {
+			if ignored(i.ErrIgnores) {
+				return true
+			}
+		}
+	}
+	if types.IgnoredByRule(ctx, res, err) {
+		res.ExpiredWaiver = nil
+		return true
+	}
+	return false
 }
 
 // newSemverConstraint is like semver.NewConstraint but panics if the expression cannot be parsed.
@@ -3,6 +3,7 @@ package validations
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -16,12 +17,18 @@ import (
 )
 
 func CheckArtifact(ctx context.Context, m types.FipsModule, mountPath string) *types.ValidationError {
-	version, present := rpmPresentAndVersion(ctx, mountPath, m.CertifiedArtifact)
-	if !present {
+	pkg, err := rpmPackage(mountPath, m.CertifiedArtifact)
+	if err != nil {
+		// Minimal and scratch images may have no rpmdb at all: say so
+		// rather than just report the artifact as missing.
+		klog.V(1).InfoS("fips artifact RPM can't be looked up", "artifact", m.CertifiedArtifact, "module", m.Module, "error", err)
+		return types.NewValidationError(fmt.Errorf("%s: %w: %w", m.CertifiedArtifact, err, types.ErrFipsArtifactMissing))
+	}
+	if pkg == nil {
 		klog.V(1).InfoS("fips artifact RPM missing", "artifact", m.CertifiedArtifact, "module", m.Module)
 		return types.NewValidationError(fmt.Errorf("%s: %w", m.CertifiedArtifact, types.ErrFipsArtifactMissing))
 	}
-	if version != "" {
+	if version := pkg.EVR(); pkg.Version != "" {
 		atLeast, atMost := types.VersionInRange(version, m.CertifiedArtifactMinVersion, m.CertifiedArtifactMaxVersion)
 		if !atLeast {
 			klog.V(1).InfoS("fips artifact version too low", "artifact", m.CertifiedArtifact, "installed", version, "required", m.CertifiedArtifactMinVersion)
@@ -39,27 +46,78 @@ func CheckArtifact(ctx context.Context, m types.FipsModule, mountPath string) *t
 		return types.NewValidationError(fmt.Errorf("%s RPM present but certified file not found at %v: %w",
 			m.CertifiedArtifact, m.CertifiedArtifactPaths, types.ErrFipsArtifactMissing))
 	}
-	klog.V(1).InfoS("fips artifact present", "artifact", m.CertifiedArtifact, "version", version, "module", m.Module)
+	if err := checkArtifactOrigin(m, pkg); err != nil {
+		klog.V(1).InfoS("fips artifact RPM not trusted", "artifact", m.CertifiedArtifact, "nvra", pkg.NVRA(), "error", err)
+		return types.NewValidationError(fmt.Errorf("%s: %w: %w", pkg.NVRA(), err, types.ErrFipsArtifactUntrusted))
+	}
+	if err := verifyArtifactPaths(mountPath, m.CertifiedArtifactPaths); err != nil {
+		klog.V(1).InfoS("fips artifact file does not match the rpmdb", "artifact", m.CertifiedArtifact, "error", err)
+		return types.NewValidationError(fmt.Errorf("%s: %w: %w", m.CertifiedArtifact, err, types.ErrFipsArtifactTampered))
+	}
+	klog.V(1).InfoS("fips artifact present", "artifact", m.CertifiedArtifact, "version", pkg.Version, "module", m.Module)
 	return nil
 }
 
-func rpmPresentAndVersion(ctx context.Context, mountPath, rpmName string) (version string, present bool) {
-	rpms, err := rpm.GetAllRPMs(ctx, mountPath)
+// rpmPackage returns the installed package of the given name, or nil, or
+// an error if the rpmdb can't be read.
+func rpmPackage(mountPath, rpmName string) (*rpm.Package, error) {
+	idx, err := rpm.IndexOf(mountPath)
 	if err != nil {
-		return "", false
-	}
-	for _, r := range rpms {
-		if r.Name == rpmName {
-			v, err := rpm.VersionOf(ctx, mountPath, rpmName)
-			if err == nil {
-				klog.V(1).InfoS("fips artifact found via RPM", "artifact", rpmName, "version", v)
-				return v, true
-			}
-			klog.V(1).InfoS("fips artifact RPM found but version query failed", "artifact", rpmName, "error", err)
-			return "", true
+		return nil, err
+	}
+	pkg := idx.Package(rpmName)
+	if pkg != nil {
+		klog.V(1).InfoS("fips artifact found via RPM", "artifact", rpmName, "version", pkg.Version, "nvra", pkg.NVRA())
+	}
+	return pkg, nil
+}
+
+// checkArtifactOrigin checks the vendor and signing key of pkg, if required
+// by m.
+func checkArtifactOrigin(m types.FipsModule, pkg *rpm.Package) error {
+	if m.CertifiedArtifactVendor != "" && pkg.Vendor != m.CertifiedArtifactVendor {
+		return fmt.Errorf("vendor %q, expected %q", pkg.Vendor, m.CertifiedArtifactVendor)
+	}
+	if len(m.CertifiedArtifactSigningKeys) == 0 {
+		return nil
+	}
+	if pkg.SigningKeyID == "" {
+		return errors.New("not signed")
+	}
+	for _, key := range m.CertifiedArtifactSigningKeys {
+		key = types.NormalizeKeyID(key)
+		// Short key IDs and fingerprints both end with (part of) the key ID.
+		if key != "" && (strings.HasSuffix(pkg.SigningKeyID, key) || strings.HasSuffix(key, pkg.SigningKeyID)) {
+			return nil
 		}
 	}
-	return "", false
+	return fmt.Errorf("signed with key %s, expected one of %v", pkg.SigningKeyID, m.CertifiedArtifactSigningKeys)
+}
+
+// verifyArtifactPaths checks the digest, size and mode of those of paths
+// which exist against the rpmdb record of the package owning them.
+func verifyArtifactPaths(mountPath string, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	idx, err := rpm.IndexOf(mountPath)
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if _, err := os.Lstat(filepath.Join(mountPath, p)); err != nil {
+			continue
+		}
+		pkg := idx.Owner(p)
+		if pkg == nil {
+			return fmt.Errorf("%s is not owned by any RPM", p)
+		}
+		f, _ := pkg.File(p)
+		if err := f.Verify(mountPath); err != nil {
+			return fmt.Errorf("%w (from %s)", err, pkg.NVRA())
+		}
+	}
+	return nil
 }
 
 func anyPathExists(mountPath string, paths []string) bool {
@@ -107,11 +165,9 @@ func findLib(mountPath string, searchPaths []string, subname string) (string, er
 // Image-source modules try artifact check first, then fall back to host lib
 // FIPS symbol check. Passes if either succeeds.
 func ValidateModule(ctx context.Context, cfg *types.Config, mountPath string, module string) *types.ValidationError {
-	for _, r := range cfg.GetFIPSCertifiedModules() {
-		if r.Module == module && r.IsBinarySource() {
-			klog.V(1).InfoS("fips module validated at binary level, skipping image check", "module", module)
-			return nil
-		}
+	if isBinarySourceModule(cfg, module) {
+		klog.V(1).InfoS("fips module validated at binary level, skipping image check", "module", module)
+		return nil
 	}
 
 	for _, r := range cfg.GetFIPSCertifiedModules() {
@@ -119,9 +175,14 @@ func ValidateModule(ctx context.Context, cfg *types.Config, mountPath string, mo
 			continue
 		}
 		klog.V(1).InfoS("checking fips artifact", "module", r.Module, "artifact", r.CertifiedArtifact)
-		if ve := CheckArtifact(ctx, r, mountPath); ve == nil {
+		ve := CheckArtifact(ctx, r, mountPath)
+		if ve == nil {
 			return nil
 		}
+		if errors.Is(ve.Error, types.ErrFipsArtifactTampered) || errors.Is(ve.Error, types.ErrFipsArtifactUntrusted) {
+			// Do not fall back to other checks.
+			return ve
+		}
 	}
 
 	if check, ok := moduleHostLibChecks[module]; ok {
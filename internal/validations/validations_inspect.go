@@ -0,0 +1,219 @@
+package validations
+
+import (
+	"context"
+	"debug/elf"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/openshift/check-payload/internal/golang"
+	"github.com/openshift/check-payload/internal/types"
+)
+
+// Inspection is what the validations see of a binary, and their verdicts.
+// See InspectBinary.
+type Inspection struct {
+	Path        string        `json:"path"`
+	ELFType     string        `json:"elf_type,omitempty"`
+	Executable  bool          `json:"executable"` // An ELF executable, which the validations check.
+	PIE         bool          `json:"pie"`
+	Static      bool          `json:"static"`
+	Needed      []string      `json:"needed,omitempty"` // DT_NEEDED.
+	Go          *GoInspection `json:"go,omitempty"`
+	ModulesUsed []string      `json:"modules_used,omitempty"`
+	Checks      []CheckResult `json:"checks,omitempty"`
+	// Error and Level are what a scan would report, with no config
+	// exceptions: the error of the first check failing, and its level.
+	Error string `json:"error,omitempty"`
+	Level string `json:"level,omitempty"`
+}
+
+// GoInspection is what the validations see of a Go binary.
+type GoInspection struct {
+	Version         string           `json:"version"`
+	Settings        []GoBuildSetting `json:"settings"`
+	PclntabSection  string           `json:"pclntab_section,omitempty"`
+	NoCrypto        bool             `json:"no_crypto"`   // No crypto functions, so the crypto checks are skipped.
+	NativeFIPS      bool             `json:"native_fips"` // The native FIPS module is used.
+	RequiredSymbols []string         `json:"required_symbols,omitempty"`
+	MatchedSymbols  []string         `json:"matched_symbols,omitempty"`
+}
+
+// GoBuildSetting is a build setting of a Go binary, as in its buildinfo.
+type GoBuildSetting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Setting returns the value of the build setting key.
+func (g *GoInspection) Setting(key string) (string, bool) {
+	for _, s := range g.Settings {
+		if s.Key == key {
+			return s.Value, true
+		}
+	}
+	return "", false
+}
+
+// CheckResult is the verdict of a ValidationFn.
+type CheckResult struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"` // "passed", "failed", or "skipped".
+	Level     string `json:"level,omitempty"`
+	Error     string `json:"error,omitempty"`
+	ErrorName string `json:"error_name,omitempty"`
+}
+
+// fnName returns the name of the ValidationFn, without its package.
+func fnName(fn ValidationFn) string {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
+
+// InspectBinary runs the validations ScanBinary runs on innerPath, in the
+// image (or root) at topDir, and returns what they see, and the verdict of
+// each of them. Unlike ScanBinary, the checks go on after a failure, so
+// that all of them are reported, and no config exceptions apply.
+func InspectBinary(ctx context.Context, topDir, innerPath string) (*Inspection, error) {
+	baton := &Baton{TopDir: topDir}
+	path := filepath.Join(topDir, innerPath)
+	ins := &Inspection{Path: innerPath}
+
+	exe, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	ins.ELFType = exe.Type.String()
+	if exe.Type == elf.ET_DYN {
+		ins.PIE, _ = golang.IsPie(exe)
+	}
+	ins.Needed, _ = exe.ImportedLibraries()
+	pclntab := golang.PclntabSection(exe)
+	exe.Close()
+
+	if ins.Executable, err = isElfExe(path, baton); err != nil {
+		return nil, err
+	}
+	if !ins.Executable {
+		return ins, nil
+	}
+	ins.Static = baton.Static
+
+	goBinary, err := isGoExecutable(path, baton)
+	if err != nil {
+		return nil, err
+	}
+	checks := validationFns["exe"]
+	if goBinary {
+		checks = validationFns["go"]
+		ins.Go = &GoInspection{Version: baton.GoBuildInfo.GoVersion}
+		for _, s := range baton.GoBuildInfo.Settings {
+			ins.Go.Settings = append(ins.Go.Settings, GoBuildSetting{s.Key, s.Value})
+		}
+		if pclntab != nil {
+			ins.Go.PclntabSection = pclntab.Name
+		}
+	}
+
+	skip := false
+	for _, fn := range checks {
+		check := CheckResult{Name: fnName(fn), Status: "skipped"}
+		if !skip {
+			check.Status = "passed"
+			if verr := fn(ctx, path, baton); verr != nil {
+				check.Status = "failed"
+				check.Level = verr.Level.String()
+				check.Error = verr.Error.Error()
+				check.ErrorName = types.KnownErrorName(verr.Error)
+				if ins.Error == "" {
+					ins.Error, ins.Level = check.Error, check.Level
+				}
+				// The other Go checks need the symbol table.
+				skip = goBinary && baton.GoSymTable == nil
+			}
+		}
+		ins.Checks = append(ins.Checks, check)
+	}
+	ins.ModulesUsed = baton.ModulesUsed
+
+	if goBinary {
+		ins.Go.NoCrypto = baton.GoNoCrypto
+		ins.Go.NativeFIPS = baton.GoNativeFIPS
+		// The symbols are only checked for golang-fips binaries.
+		if baton.GoSymTable != nil && !baton.GoNoCrypto && !baton.GoNativeFIPS {
+			ins.Go.RequiredSymbols = requiredGoSymbols(baton.GoVersion)
+			ins.Go.MatchedSymbols = golang.MatchedSyms(ins.Go.RequiredSymbols, baton.GoSymTable)
+		}
+	}
+	return ins, nil
+}
+
+// String returns the inspection as text.
+func (ins *Inspection) String() string {
+	var sb strings.Builder
+	field := func(name string, value any) {
+		fmt.Fprintf(&sb, "%-17s %v\n", name+":", value)
+	}
+	list := func(name string, values []string) {
+		if len(values) == 0 {
+			field(name, "-")
+			return
+		}
+		field(name, values[0])
+		for _, v := range values[1:] {
+			fmt.Fprintf(&sb, "%-17s %s\n", "", v)
+		}
+	}
+
+	field("Path", ins.Path)
+	field("ELF type", ins.ELFType)
+	field("Executable", ins.Executable)
+	if !ins.Executable {
+		return sb.String()
+	}
+	field("PIE", ins.PIE)
+	field("Static", ins.Static)
+	list("DT_NEEDED", ins.Needed)
+	if g := ins.Go; g != nil {
+		field("Go version", g.Version)
+		// The settings the checks use first, then the others.
+		notable := []string{"-tags", "GOEXPERIMENT", "CGO_ENABLED", "GOFIPS140", "DefaultGODEBUG"}
+		for _, key := range notable {
+			v, ok := g.Setting(key)
+			if !ok {
+				v = "(not set)"
+			}
+			field("  "+key, v)
+		}
+		for _, s := range g.Settings {
+			if !slices.Contains(notable, s.Key) {
+				field("  "+s.Key, s.Value)
+			}
+		}
+		field("Pclntab section", g.PclntabSection)
+		field("Go crypto", !g.NoCrypto)
+		field("Go native FIPS", g.NativeFIPS)
+		list("FIPS symbols", g.RequiredSymbols)
+		list("Matched symbols", g.MatchedSymbols)
+	}
+	list("Modules used", ins.ModulesUsed)
+
+	sb.WriteString("\nChecks:\n")
+	for _, c := range ins.Checks {
+		fmt.Fprintf(&sb, "  %-30s %s", c.Name, c.Status)
+		if c.Error != "" {
+			fmt.Fprintf(&sb, " (%s): %s", c.Level, c.Error)
+		}
+		sb.WriteString("\n")
+	}
+	if ins.Error != "" {
+		fmt.Fprintf(&sb, "\nVerdict: %s: %s\n", ins.Level, ins.Error)
+	} else {
+		sb.WriteString("\nVerdict: passed\n")
+	}
+	return sb.String()
+}
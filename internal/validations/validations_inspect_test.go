@@ -0,0 +1,59 @@
+package validations
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestInspectBinary(t *testing.T) {
+	ins, err := InspectBinary(context.Background(), "../../test/resources/mock_unpacked_dir_9_4", "usr/fips_compliant_app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ins.Executable || ins.Static || ins.Go == nil || ins.Error != "" {
+		t.Fatalf("unexpected inspection:\n%s", ins)
+	}
+	if tags, _ := ins.Go.Setting("-tags"); !strings.Contains(tags, "strictfipsruntime") {
+		t.Errorf("got -tags %q", tags)
+	}
+	if ins.Go.PclntabSection != ".gopclntab" || len(ins.Go.MatchedSymbols) == 0 {
+		t.Errorf("got pclntab section %q, matched symbols %v", ins.Go.PclntabSection, ins.Go.MatchedSymbols)
+	}
+	if len(ins.Checks) != len(validationFns["go"]) || ins.Checks[0].Name != "_loadGoSymbols" {
+		t.Errorf("unexpected checks: %+v", ins.Checks)
+	}
+	for _, c := range ins.Checks {
+		if c.Status != "passed" {
+			t.Errorf("%s: got %s (%s)", c.Name, c.Status, c.Error)
+		}
+	}
+
+	// Without the libcrypto it needs in the root.
+	dir := t.TempDir()
+	copyResource(t, "fips_compliant_app", dir, "usr/bin/app")
+	ins, err = InspectBinary(context.Background(), dir, "usr/bin/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failed []string
+	for _, c := range ins.Checks {
+		if c.Status == "failed" {
+			failed = append(failed, c.Name+":"+c.ErrorName)
+		}
+	}
+	if strings.Join(failed, " ") != "validateGoOpenssl:ErrLibcryptoSoMissing" || ins.Level != "error" {
+		t.Errorf("got failed checks %v, level %q", failed, ins.Level)
+	}
+	if s := ins.String(); !strings.Contains(s, "Verdict: error: could not find dependent openssl version") {
+		t.Errorf("unexpected text:\n%s", s)
+	}
+
+	ins, err = InspectBinary(context.Background(), "../../test/resources", "libcrypto.so")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ins.Executable || ins.ELFType != "ET_DYN" || len(ins.Checks) != 0 {
+		t.Errorf("unexpected inspection of a library:\n%s", ins)
+	}
+}
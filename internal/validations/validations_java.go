@@ -0,0 +1,332 @@
+package validations
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+const (
+	// javaProviderServices is the ServiceLoader file used to register
+	// JCA providers.
+	javaProviderServices = "META-INF/services/java.security.Provider"
+	// javaProviderClass is the internal name of the JCA provider base class.
+	javaProviderClass = "java/security/Provider"
+
+	// Limits for nested archives, which have to be read into memory.
+	maxJavaArchiveDepth      = 8
+	maxNestedJavaArchiveSize = 512 << 20
+)
+
+// javaArchiveExts lists the extensions of archives that are searched for
+// bundled crypto providers.
+var javaArchiveExts = map[string]bool{
+	".jar": true,
+	".war": true,
+	".ear": true,
+}
+
+// javaClassPrefixes are archive directories that hold classes in
+// application archives (Spring Boot fat jars and web archives).
+var javaClassPrefixes = []string{
+	"BOOT-INF/classes/",
+	"WEB-INF/classes/",
+}
+
+// javaFIPSProviders are provider classes that are either FIPS validated
+// or do not implement cryptography on their own, and are therefore allowed.
+var javaFIPSProviders = map[string]bool{
+	"org.bouncycastle.jcajce.provider.BouncyCastleFipsProvider": true,
+	// JSSE provider; delegates crypto to the configured JCA provider.
+	"org.bouncycastle.jsse.provider.BouncyCastleJsseProvider": true,
+}
+
+// javaKnownProviders maps well-known non-FIPS crypto provider classes
+// to human-readable names.
+var javaKnownProviders = map[string]string{
+	"org.bouncycastle.jce.provider.BouncyCastleProvider":               "BouncyCastle",
+	"org.bouncycastle.pqc.jcajce.provider.BouncyCastlePQCProvider":     "BouncyCastle PQC",
+	"org.conscrypt.OpenSSLProvider":                                    "Conscrypt",
+	"com.amazon.corretto.crypto.provider.AmazonCorrettoCryptoProvider": "Amazon Corretto Crypto Provider",
+	"iaik.security.provider.IAIK":                                      "IAIK-JCE",
+	"cryptix.jce.provider.CryptixCrypto":                               "Cryptix",
+	"gnu.crypto.jce.GnuCrypto":                                         "GNU Crypto",
+	"org.apache.harmony.security.provider.crypto.CryptoProvider":       "Apache Harmony",
+	"com.wolfssl.provider.jce.WolfCryptProvider":                       "wolfCrypt JCE",
+}
+
+// javaProvider is a crypto provider found in a java archive.
+type javaProvider struct {
+	Class string // Fully qualified class name.
+	Name  string // Human-readable name, if known.
+	Entry string // Path to the (possibly nested) archive containing it.
+	How   string // How the provider was found.
+}
+
+func (p javaProvider) String() string {
+	s := p.Class
+	if p.Name != "" {
+		s = p.Name + " (" + p.Class + ")"
+	}
+	return s + " " + p.How + " in " + p.Entry
+}
+
+// IsJavaArchive tells if path looks like a java archive (jar, war, or ear).
+func IsJavaArchive(path string) bool {
+	return javaArchiveExts[strings.ToLower(filepath.Ext(path))]
+}
+
+// ScanJavaArchive checks a java archive, including any nested archives,
+// for bundled crypto providers which are not FIPS validated. Those are
+// both well-known provider classes (such as BouncyCastle's bcprov or
+// Conscrypt), and any classes registered in, or extending,
+// java.security.Provider.
+func ScanJavaArchive(ctx context.Context, topDir, innerPath string, rpmIgnores map[string]types.IgnoreLists, errIgnores ...types.ErrIgnoreList) *types.ScanResult {
+	res := types.NewScanResult().SetPath(innerPath)
+
+	zr, err := zip.OpenReader(filepath.Join(topDir, innerPath))
+	if err != nil {
+		if errors.Is(err, zip.ErrFormat) {
+			// Not a zip archive, nothing to check.
+			return res.Skipped()
+		}
+		return res.SetError(err)
+	}
+	defer zr.Close()
+
+	providers, err := findJavaProviders(&zr.Reader, innerPath, 0)
+	if err != nil {
+		return res.SetError(fmt.Errorf("can't read java archive: %w", err))
+	}
+	if len(providers) == 0 {
+		return res.Success()
+	}
+
+	desc := make([]string, len(providers))
+	for i, p := range providers {
+		desc[i] = p.String()
+	}
+	sort.Strings(desc)
+	err = fmt.Errorf("%w: %s", types.ErrJavaCryptoProvider, strings.Join(desc, "; "))
+	if isIgnored(ctx, res, topDir, innerPath, err, rpmIgnores, errIgnores) {
+		return res.Success()
+	}
+	return res.SetError(err)
+}
+
+func findJavaProviders(zr *zip.Reader, name string, depth int) ([]javaProvider, error) {
+	var providers []javaProvider
+	seen := make(map[string]bool)
+	add := func(p javaProvider) {
+		if javaFIPSProviders[p.Class] || seen[p.Class] {
+			return
+		}
+		seen[p.Class] = true
+		if p.Name == "" {
+			p.Name = javaKnownProviders[p.Class]
+		}
+		if p.Entry == "" {
+			p.Entry = name
+		}
+		providers = append(providers, p)
+	}
+
+	for _, f := range zr.File {
+		switch {
+		case f.Name == javaProviderServices || strings.HasSuffix(f.Name, "/"+javaProviderServices):
+			classes, err := readJavaServices(f)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+			for _, class := range classes {
+				add(javaProvider{Class: class, How: "registered"})
+			}
+		case strings.HasSuffix(f.Name, ".class"):
+			class := javaClassName(f.Name)
+			if _, ok := javaKnownProviders[class]; ok {
+				add(javaProvider{Class: class, How: "bundled"})
+				continue
+			}
+			// Parsing every class is expensive, so only look
+			// at those which are likely to be providers.
+			if !strings.Contains(path.Base(f.Name), "Provider") {
+				continue
+			}
+			super, err := readJavaSuperclass(f)
+			if err != nil {
+				// Not a valid class file; ignore.
+				continue
+			}
+			if super == javaProviderClass {
+				add(javaProvider{Class: class, How: "extends java.security.Provider"})
+			}
+		case IsJavaArchive(f.Name):
+			if depth >= maxJavaArchiveDepth || f.UncompressedSize64 > maxNestedJavaArchiveSize {
+				continue
+			}
+			nested, err := openNestedJavaArchive(f)
+			if err != nil {
+				// Not a valid archive; ignore.
+				continue
+			}
+			found, err := findJavaProviders(nested, name+"!/"+f.Name, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range found {
+				add(p)
+			}
+		}
+	}
+
+	return providers, nil
+}
+
+func openNestedJavaArchive(f *zip.File) (*zip.Reader, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxNestedJavaArchiveSize))
+	if err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// javaClassName converts an archive entry name to a class name.
+func javaClassName(name string) string {
+	name = strings.TrimSuffix(name, ".class")
+	for _, p := range javaClassPrefixes {
+		name = strings.TrimPrefix(name, p)
+	}
+	// Multi-release jars: META-INF/versions/N/.
+	if rest, ok := strings.CutPrefix(name, "META-INF/versions/"); ok {
+		if i := strings.IndexByte(rest, '/'); i != -1 {
+			name = rest[i+1:]
+		}
+	}
+	return strings.ReplaceAll(name, "/", ".")
+}
+
+// readJavaServices returns the class names listed in a ServiceLoader file.
+func readJavaServices(f *zip.File) ([]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var classes []string
+	s := bufio.NewScanner(rc)
+	for s.Scan() {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			classes = append(classes, line)
+		}
+	}
+	return classes, s.Err()
+}
+
+// Class file constant pool tags, see
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.4.
+const (
+	cpUtf8    = 1
+	cpClass   = 7
+	cpLong    = 5
+	cpDouble  = 6
+	javaMagic = 0xCAFEBABE
+)
+
+// cpEntrySize holds the size of constant pool entries (excluding the tag),
+// for all fixed-size entry types.
+var cpEntrySize = map[byte]int{
+	3: 4, 4: 4, cpLong: 8, cpDouble: 8, cpClass: 2, 8: 2, 9: 4, 10: 4,
+	11: 4, 12: 4, 15: 3, 16: 2, 17: 4, 18: 4, 19: 2, 20: 2,
+}
+
+// readJavaSuperclass returns the internal name of the superclass of
+// a class stored in the archive.
+func readJavaSuperclass(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	return parseJavaSuperclass(bufio.NewReader(rc))
+}
+
+func parseJavaSuperclass(r io.Reader) (string, error) {
+	var hdr struct {
+		Magic        uint32
+		Minor, Major uint16
+		PoolCount    uint16
+	}
+	if err := binary.Read(r, binary.BigEndian, &hdr); err != nil {
+		return "", err
+	}
+	if hdr.Magic != javaMagic {
+		return "", errors.New("not a java class file")
+	}
+
+	utf8 := make(map[uint16]string)
+	classes := make(map[uint16]uint16)
+	var buf [8]byte
+	for i := uint16(1); i < hdr.PoolCount; i++ {
+		if _, err := io.ReadFull(r, buf[:1]); err != nil {
+			return "", err
+		}
+		switch tag := buf[0]; tag {
+		case cpUtf8:
+			if _, err := io.ReadFull(r, buf[:2]); err != nil {
+				return "", err
+			}
+			str := make([]byte, binary.BigEndian.Uint16(buf[:2]))
+			if _, err := io.ReadFull(r, str); err != nil {
+				return "", err
+			}
+			utf8[i] = string(str)
+		case cpClass:
+			if _, err := io.ReadFull(r, buf[:2]); err != nil {
+				return "", err
+			}
+			classes[i] = binary.BigEndian.Uint16(buf[:2])
+		default:
+			size, ok := cpEntrySize[tag]
+			if !ok {
+				return "", fmt.Errorf("unknown constant pool tag %d", tag)
+			}
+			if _, err := io.ReadFull(r, buf[:size]); err != nil {
+				return "", err
+			}
+			if tag == cpLong || tag == cpDouble {
+				// These take two constant pool slots.
+				i++
+			}
+		}
+	}
+
+	var flags struct {
+		Access, This, Super uint16
+	}
+	if err := binary.Read(r, binary.BigEndian, &flags); err != nil {
+		return "", err
+	}
+	name, ok := classes[flags.Super]
+	if !ok {
+		return "", errors.New("invalid superclass index")
+	}
+	return utf8[name], nil
+}
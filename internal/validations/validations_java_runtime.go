@@ -0,0 +1,296 @@
+package validations
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+const (
+	defaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+	maxSymlinks         = 40
+	maxEntrypointScript = 1 << 20
+)
+
+var (
+	// javaHomeGlobs are the locations where Java runtimes are usually
+	// installed.
+	javaHomeGlobs = []string{
+		"/usr/lib/jvm/*",
+		"/usr/java/*",
+		"/opt/*",
+		"/opt/java/*",
+	}
+
+	// Shells which may be used to wrap the entrypoint.
+	shells = map[string]bool{
+		"sh": true, "bash": true, "dash": true, "ash": true, "zsh": true, "ksh": true,
+	}
+	// Init wrappers which execute their arguments (after options).
+	initWrappers = map[string]bool{
+		"tini": true, "dumb-init": true, "catatonit": true, "env": true, "exec": true,
+	}
+
+	javaHomeAssignRE = regexp.MustCompile(`(?m)^\s*(?:export\s+)?JAVA_HOME=("[^"]*"|'[^']*'|[^\s;&|]*)`)
+	javaInvokeRE     = regexp.MustCompile(`(?:^|[\s;&|("'` + "`" + `])((?:\$\{?JAVA_HOME\}?|[\w./+-]*)/bin/java|java)(?:$|[\s;&|)"'` + "`" + `])`)
+)
+
+// FindJavaRuntimes finds all Java runtimes installed in the image root
+// mounted at mountPath, using image configuration from info to find out
+// which one is used by the image entrypoint. The entrypoint runtime, if
+// found, comes first.
+func FindJavaRuntimes(mountPath string, info *types.JavaComponent) []types.JavaRuntime {
+	env := envMap(info.Env)
+	var runtimes []types.JavaRuntime
+	seen := make(map[string]bool)
+	add := func(java string, entrypoint bool) {
+		java, err := resolveInRoot(mountPath, java)
+		if err != nil || !isRegularFile(mountPath, java) {
+			return
+		}
+		home := javaHomeOf(java)
+		if seen[home] {
+			return
+		}
+		seen[home] = true
+		rt := types.JavaRuntime{
+			Home:       home,
+			Java:       java,
+			Entrypoint: entrypoint,
+		}
+		if javac, err := resolveInRoot(mountPath, path.Join(home, "bin/javac")); err == nil && isRegularFile(mountPath, javac) {
+			rt.Javac = javac
+		}
+		runtimes = append(runtimes, rt)
+	}
+
+	if java := entrypointJava(mountPath, info, env); java != "" {
+		add(java, true)
+	}
+	if home := env["JAVA_HOME"]; home != "" {
+		add(path.Join(home, "bin/java"), false)
+	}
+	if java := lookPathInRoot(mountPath, "java", env["PATH"]); java != "" {
+		add(java, false)
+	}
+	var found []string
+	for _, g := range javaHomeGlobs {
+		for _, suffix := range []string{"bin/java", "jre/bin/java"} {
+			matches, _ := filepath.Glob(filepath.Join(mountPath, g, suffix))
+			for _, m := range matches {
+				found = append(found, stripRoot(mountPath, m))
+			}
+		}
+	}
+	sort.Strings(found)
+	for _, java := range found {
+		add(java, false)
+	}
+
+	return runtimes
+}
+
+// entrypointJava returns the path to the java executable used by the
+// image entrypoint, or an empty string if it can't be determined.
+func entrypointJava(mountPath string, info *types.JavaComponent, imageEnv map[string]string) string {
+	// The entrypoint may modify its environment, so use a copy.
+	env := make(map[string]string, len(imageEnv))
+	for k, v := range imageEnv {
+		env[k] = v
+	}
+	argv := append(append([]string{}, info.Entrypoint...), info.Cmd...)
+	for len(argv) > 0 {
+		cmd := argv[0]
+		base := path.Base(cmd)
+		switch {
+		case base == "java":
+			return javaCommand(mountPath, cmd, env)
+		case initWrappers[base]:
+			argv = argv[1:]
+			// Skip options and variable assignments (as in "env A=B cmd").
+			for len(argv) > 0 {
+				if k, v, ok := strings.Cut(argv[0], "="); ok && !strings.ContainsAny(k, "/-") {
+					env[k] = v
+				} else if !strings.HasPrefix(argv[0], "-") {
+					break
+				}
+				argv = argv[1:]
+			}
+		case shells[base]:
+			for i, arg := range argv[1:] {
+				if arg == "-c" && i+2 < len(argv) {
+					return scriptJava(mountPath, argv[i+2], env)
+				}
+			}
+			// A script run by the shell.
+			if len(argv) > 1 && !strings.HasPrefix(argv[1], "-") {
+				return fileScriptJava(mountPath, argv[1], info.WorkingDir, env)
+			}
+			return ""
+		default:
+			return fileScriptJava(mountPath, cmd, info.WorkingDir, env)
+		}
+	}
+	return ""
+}
+
+// fileScriptJava returns the java executable used by the script file.
+func fileScriptJava(mountPath, file, workDir string, env map[string]string) string {
+	if !strings.Contains(file, "/") {
+		file = lookPathInRoot(mountPath, file, env["PATH"])
+	} else if !path.IsAbs(file) {
+		file = path.Join("/", workDir, file)
+	}
+	if file == "" {
+		return ""
+	}
+	resolved, err := resolveInRoot(mountPath, file)
+	if err != nil {
+		return ""
+	}
+	f, err := os.Open(filepath.Join(mountPath, resolved))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	script, err := io.ReadAll(io.LimitReader(f, maxEntrypointScript))
+	if err != nil || !bytes.HasPrefix(script, []byte("#!")) {
+		// Not a script.
+		return ""
+	}
+	return scriptJava(mountPath, string(script), env)
+}
+
+// scriptJava finds the first java invocation in a shell script, taking
+// into account any JAVA_HOME assignments made before it.
+func scriptJava(mountPath, script string, env map[string]string) string {
+	s := bufio.NewScanner(strings.NewReader(script))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := javaHomeAssignRE.FindStringSubmatch(line); m != nil {
+			env["JAVA_HOME"] = expandJavaHome(strings.Trim(m[1], `"'`), env)
+		}
+		if m := javaInvokeRE.FindStringSubmatch(line); m != nil {
+			return javaCommand(mountPath, expandJavaHome(m[1], env), env)
+		}
+	}
+	return ""
+}
+
+func expandJavaHome(s string, env map[string]string) string {
+	home := env["JAVA_HOME"]
+	s = strings.ReplaceAll(s, "${JAVA_HOME}", home)
+	return strings.ReplaceAll(s, "$JAVA_HOME", home)
+}
+
+// javaCommand resolves java command cmd to a path.
+func javaCommand(mountPath, cmd string, env map[string]string) string {
+	if strings.Contains(cmd, "/") {
+		if !path.IsAbs(cmd) {
+			// Relative to an unexpanded or empty variable.
+			return ""
+		}
+		return cmd
+	}
+	return lookPathInRoot(mountPath, cmd, env["PATH"])
+}
+
+// lookPathInRoot is like exec.LookPath, but searches inside mountPath.
+func lookPathInRoot(mountPath, file, pathEnv string) string {
+	if pathEnv == "" {
+		pathEnv = defaultPath
+	}
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if !path.IsAbs(dir) {
+			continue
+		}
+		p := path.Join(dir, file)
+		if resolved, err := resolveInRoot(mountPath, p); err == nil && isRegularFile(mountPath, resolved) {
+			return p
+		}
+	}
+	return ""
+}
+
+// resolveInRoot resolves all symlinks in p, treating mountPath as the root
+// directory, and returns the resulting path relative to mountPath.
+func resolveInRoot(mountPath, p string) (string, error) {
+	links := 0
+	resolved := "/"
+	rest := strings.Split(path.Clean("/"+p), "/")
+	for len(rest) > 0 {
+		part := rest[0]
+		rest = rest[1:]
+		if part == "" || part == "." {
+			continue
+		}
+		if part == ".." {
+			resolved = path.Dir(resolved)
+			continue
+		}
+		next := path.Join(resolved, part)
+		fi, err := os.Lstat(filepath.Join(mountPath, next))
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if links++; links > maxSymlinks {
+			return "", errors.New("too many levels of symbolic links")
+		}
+		target, err := os.Readlink(filepath.Join(mountPath, next))
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		rest = append(strings.Split(target, "/"), rest...)
+	}
+	return resolved, nil
+}
+
+func isRegularFile(mountPath, p string) bool {
+	fi, err := os.Stat(filepath.Join(mountPath, p))
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// javaHomeOf returns the Java home directory for the java executable.
+func javaHomeOf(java string) string {
+	home := path.Dir(path.Dir(java))
+	if path.Base(home) == "jre" {
+		// Java 8 JDK layout.
+		home = path.Dir(home)
+	}
+	return home
+}
+
+func stripRoot(mountPath, p string) string {
+	return "/" + strings.TrimPrefix(strings.TrimPrefix(p, mountPath), "/")
+}
+
+func envMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, e := range env {
+		if k, v, ok := strings.Cut(e, "="); ok {
+			m[k] = v
+		}
+	}
+	return m
+}
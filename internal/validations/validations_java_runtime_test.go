@@ -0,0 +1,178 @@
+package validations
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+// makeRoot creates a root filesystem with the given files and symlinks.
+func makeRoot(t *testing.T, files map[string]string, links map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, data := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range links {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestFindJavaRuntimes(t *testing.T) {
+	jdks := map[string]string{
+		"/usr/lib/jvm/java-17-openjdk/bin/java": "",
+		"/usr/lib/jvm/java-21-openjdk/bin/java": "",
+		"/opt/jdk8/jre/bin/java":                "",
+		"/opt/jdk8/bin/javac":                   "",
+		"/usr/lib/jvm/java-21-devel/bin/javac":  "",
+	}
+	links := map[string]string{
+		"/etc/alternatives/java": "/usr/lib/jvm/java-17-openjdk/bin/java",
+		"/usr/bin/java":          "/etc/alternatives/java",
+		// Absolute links are resolved in the image, not on the host.
+		"/usr/lib/jvm/java-21-openjdk/bin/javac": "/usr/lib/jvm/java-21-devel/bin/javac",
+		"/usr/lib/jvm/java-17-openjdk/bin/javac": "/bin/sh",
+	}
+	withFiles := func(extra map[string]string) map[string]string {
+		files := make(map[string]string, len(jdks)+len(extra))
+		for k, v := range jdks {
+			files[k] = v
+		}
+		for k, v := range extra {
+			files[k] = v
+		}
+		return files
+	}
+	// All runtimes, in the order of discovery when none is used by the entrypoint.
+	all := []types.JavaRuntime{
+		{Home: "/usr/lib/jvm/java-17-openjdk", Java: "/usr/lib/jvm/java-17-openjdk/bin/java"},
+		{Home: "/opt/jdk8", Java: "/opt/jdk8/jre/bin/java", Javac: "/opt/jdk8/bin/javac"},
+		{Home: "/usr/lib/jvm/java-21-openjdk", Java: "/usr/lib/jvm/java-21-openjdk/bin/java", Javac: "/usr/lib/jvm/java-21-devel/bin/javac"},
+	}
+
+	testCases := []struct {
+		name  string
+		files map[string]string
+		info  types.JavaComponent
+		want  []types.JavaRuntime
+	}{
+		{
+			name: "no entrypoint",
+			want: all,
+		},
+		{
+			name: "java from PATH",
+			info: types.JavaComponent{Entrypoint: []string{"java", "-jar", "app.jar"}},
+			want: []types.JavaRuntime{
+				{Home: "/usr/lib/jvm/java-17-openjdk", Java: "/usr/lib/jvm/java-17-openjdk/bin/java", Entrypoint: true},
+				all[1], all[2],
+			},
+		},
+		{
+			name: "JAVA_HOME",
+			info: types.JavaComponent{
+				Env:        []string{"JAVA_HOME=/usr/lib/jvm/java-21-openjdk"},
+				Entrypoint: []string{"/usr/lib/jvm/java-21-openjdk/bin/java"},
+			},
+			want: []types.JavaRuntime{
+				{Home: "/usr/lib/jvm/java-21-openjdk", Java: "/usr/lib/jvm/java-21-openjdk/bin/java", Javac: "/usr/lib/jvm/java-21-devel/bin/javac", Entrypoint: true},
+				all[0], all[1],
+			},
+		},
+		{
+			name: "shell wrapper",
+			info: types.JavaComponent{
+				Entrypoint: []string{"/usr/bin/tini", "--", "/bin/sh", "-c"},
+				Cmd:        []string{"export JAVA_HOME=/opt/jdk8/jre; exec $JAVA_HOME/bin/java -jar /app.jar"},
+			},
+			want: []types.JavaRuntime{
+				{Home: "/opt/jdk8", Java: "/opt/jdk8/jre/bin/java", Javac: "/opt/jdk8/bin/javac", Entrypoint: true},
+				all[0], all[2],
+			},
+		},
+		{
+			name: "entrypoint script",
+			files: withFiles(map[string]string{
+				"/app/run.sh": "#!/bin/sh\n# java is started below\nJAVA_HOME=\"/usr/lib/jvm/java-21-openjdk\"\nexec \"${JAVA_HOME}/bin/java\" -jar app.jar\n",
+			}),
+			info: types.JavaComponent{
+				Entrypoint: []string{"./run.sh"},
+				WorkingDir: "/app",
+			},
+			want: []types.JavaRuntime{
+				{Home: "/usr/lib/jvm/java-21-openjdk", Java: "/usr/lib/jvm/java-21-openjdk/bin/java", Javac: "/usr/lib/jvm/java-21-devel/bin/javac", Entrypoint: true},
+				all[0], all[1],
+			},
+		},
+		{
+			name: "entrypoint is not java",
+			files: withFiles(map[string]string{
+				"/usr/bin/server": "\x7fELF",
+			}),
+			info: types.JavaComponent{Entrypoint: []string{"server"}},
+			want: all,
+		},
+		{
+			name:  "no java",
+			files: map[string]string{"/usr/bin/server": ""},
+			info:  types.JavaComponent{Entrypoint: []string{"java"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			files, ln := tc.files, links
+			if files == nil {
+				files = jdks
+			}
+			if _, ok := files["/usr/lib/jvm/java-17-openjdk/bin/java"]; !ok {
+				ln = nil
+			}
+			root := makeRoot(t, files, ln)
+			got := FindJavaRuntimes(root, &tc.info)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResolveInRoot(t *testing.T) {
+	root := makeRoot(t, map[string]string{"/usr/lib/jvm/jdk/bin/java": ""}, map[string]string{
+		"/usr/lib/jvm/current": "jdk",
+		"/usr/bin/java":        "../lib/jvm/current/bin/java",
+		"/escape":              "../../../../usr/bin/java",
+		"/loop":                "/loop",
+	})
+	for in, want := range map[string]string{
+		"/usr/bin/java":                        "/usr/lib/jvm/jdk/bin/java",
+		"/escape":                              "/usr/lib/jvm/jdk/bin/java",
+		"/usr/lib/jvm/current/../jdk/bin/java": "/usr/lib/jvm/jdk/bin/java",
+	} {
+		got, err := resolveInRoot(root, in)
+		if err != nil {
+			t.Errorf("resolveInRoot(%q): %v", in, err)
+		} else if got != want {
+			t.Errorf("resolveInRoot(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if _, err := resolveInRoot(root, "/loop"); err == nil {
+		t.Error("expected error for symlink loop")
+	}
+}
@@ -0,0 +1,192 @@
+package validations
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+// javaClass returns a minimal class file for class extending super.
+func javaClass(class, super string) []byte {
+	var b bytes.Buffer
+	w := func(v any) { _ = binary.Write(&b, binary.BigEndian, v) }
+	w(uint32(javaMagic))
+	w([]uint16{0, 61, 5}) // minor, major, constant pool count
+	for _, name := range []string{class, super} {
+		w(uint8(cpUtf8))
+		w(uint16(len(name)))
+		b.WriteString(name)
+	}
+	w(uint8(cpClass))
+	w(uint16(1))
+	w(uint8(cpClass))
+	w(uint16(2))
+	w([]uint16{0x21, 3, 4}) // access flags, this, super
+	return b.Bytes()
+}
+
+func makeJar(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestParseJavaSuperclass(t *testing.T) {
+	super, err := parseJavaSuperclass(bytes.NewReader(javaClass("com/example/MyProvider", javaProviderClass)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if super != javaProviderClass {
+		t.Errorf("got %q, want %q", super, javaProviderClass)
+	}
+	if _, err := parseJavaSuperclass(strings.NewReader("not a class file")); err == nil {
+		t.Error("expected error for invalid class file")
+	}
+}
+
+func TestJavaClassName(t *testing.T) {
+	for in, want := range map[string]string{
+		"org/conscrypt/OpenSSLProvider.class":                "org.conscrypt.OpenSSLProvider",
+		"BOOT-INF/classes/com/example/A.class":               "com.example.A",
+		"WEB-INF/classes/com/example/A.class":                "com.example.A",
+		"META-INF/versions/11/com/example/A.class":           "com.example.A",
+		"BOOT-INF/classes/META-INF/versions/9/x/Y.class":     "x.Y",
+		"org/bouncycastle/jce/provider/BouncyCastleProvider": "org.bouncycastle.jce.provider.BouncyCastleProvider",
+	} {
+		if got := javaClassName(in); got != want {
+			t.Errorf("javaClassName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestScanJavaArchive(t *testing.T) {
+	ctx := context.Background()
+
+	bcprov := makeJar(t, map[string][]byte{
+		javaProviderServices: []byte("# comment\norg.bouncycastle.jce.provider.BouncyCastleProvider\n"),
+		"org/bouncycastle/jce/provider/BouncyCastleProvider.class": javaClass("org/bouncycastle/jce/provider/BouncyCastleProvider", javaProviderClass),
+	})
+	bcFIPS := makeJar(t, map[string][]byte{
+		javaProviderServices: []byte("org.bouncycastle.jcajce.provider.BouncyCastleFipsProvider\n"),
+		"org/bouncycastle/jcajce/provider/BouncyCastleFipsProvider.class": javaClass("org/bouncycastle/jcajce/provider/BouncyCastleFipsProvider", javaProviderClass),
+	})
+
+	testCases := []struct {
+		name    string
+		files   map[string][]byte
+		wantErr []string
+	}{
+		{
+			name:  "no providers",
+			files: map[string][]byte{"com/example/Main.class": javaClass("com/example/Main", "java/lang/Object")},
+		},
+		{
+			name:    "bcprov",
+			files:   map[string][]byte{"BOOT-INF/lib/bcprov-jdk18on-1.78.jar": bcprov},
+			wantErr: []string{"BouncyCastle (org.bouncycastle.jce.provider.BouncyCastleProvider) registered in /app.jar!/BOOT-INF/lib/bcprov-jdk18on-1.78.jar"},
+		},
+		{
+			name:  "bc-fips is allowed",
+			files: map[string][]byte{"BOOT-INF/lib/bc-fips-2.0.0.jar": bcFIPS},
+		},
+		{
+			name: "doubly nested conscrypt",
+			files: map[string][]byte{
+				"WEB-INF/lib/inner.war": makeJar(t, map[string][]byte{
+					"lib/conscrypt.jar": makeJar(t, map[string][]byte{
+						"org/conscrypt/OpenSSLProvider.class": javaClass("org/conscrypt/OpenSSLProvider", javaProviderClass),
+					}),
+				}),
+			},
+			wantErr: []string{"Conscrypt (org.conscrypt.OpenSSLProvider) bundled in /app.jar!/WEB-INF/lib/inner.war!/lib/conscrypt.jar"},
+		},
+		{
+			name: "custom provider",
+			files: map[string][]byte{
+				"BOOT-INF/classes/com/example/MyProvider.class": javaClass("com/example/MyProvider", javaProviderClass),
+			},
+			wantErr: []string{"com.example.MyProvider extends java.security.Provider in /app.jar"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "app.jar"), makeJar(t, tc.files), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			res := ScanJavaArchive(ctx, dir, "/app.jar", nil)
+			if res.Skip {
+				t.Fatal("unexpected skip")
+			}
+			if len(tc.wantErr) == 0 {
+				if !res.IsSuccess() {
+					t.Fatalf("expected success, got %v", res.Error.Error)
+				}
+				return
+			}
+			if res.IsSuccess() {
+				t.Fatal("expected error, got success")
+			}
+			if !errors.Is(res.Error.Error, types.ErrJavaCryptoProvider) {
+				t.Errorf("expected ErrJavaCryptoProvider, got %v", res.Error.Error)
+			}
+			for _, want := range tc.wantErr {
+				if !strings.Contains(res.Error.Error.Error(), want) {
+					t.Errorf("error %q does not contain %q", res.Error.Error, want)
+				}
+			}
+		})
+	}
+
+	t.Run("ignored", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "app.jar"), bcprov, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		ignores := types.ErrIgnoreList{{
+			Error: types.KnownError{Err: types.ErrJavaCryptoProvider},
+			Files: []string{"/app.jar"},
+		}}
+		if res := ScanJavaArchive(ctx, dir, "/app.jar", nil, ignores); !res.IsSuccess() {
+			t.Errorf("expected success, got %v", res.Error.Error)
+		}
+	})
+
+	t.Run("not an archive", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "app.jar"), []byte("garbage"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if res := ScanJavaArchive(ctx, dir, "/app.jar", nil); !res.Skip {
+			t.Error("expected skip")
+		}
+	})
+}
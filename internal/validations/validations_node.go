@@ -0,0 +1,105 @@
+package validations
+
+import (
+	"context"
+	"debug/elf"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+// Node.js exports its C++ API (namespace node) for the use of addons.
+const nodeSymbolPrefix = "_ZN4node"
+
+// nodeExecutableNames are the usual names of the Node.js executable.
+var nodeExecutableNames = map[string]bool{
+	"node":   true,
+	"nodejs": true,
+}
+
+// isNodeExecutable tells if the ELF binary at path is Node.js. Only the
+// binaries named like it are looked at: either one of the usual names, or a
+// name starting with node (node20, etc) when the binary exports the Node.js
+// C++ API. This runs on every executable, so others are not opened.
+func isNodeExecutable(path string) bool {
+	name := filepath.Base(path)
+	if nodeExecutableNames[name] {
+		return true
+	}
+	if !strings.HasPrefix(name, "node") {
+		return false
+	}
+	exe, err := elf.Open(path)
+	if err != nil {
+		return false
+	}
+	defer exe.Close()
+	syms, err := exe.DynamicSymbols()
+	if err != nil {
+		return false
+	}
+	for _, s := range syms {
+		if strings.HasPrefix(s.Name, nodeSymbolPrefix) && s.Section != elf.SHN_UNDEF {
+			return true
+		}
+	}
+	return false
+}
+
+// validateNodeOpenssl checks that a Node.js executable is built with
+// --shared-openssl, i.e. uses the system libcrypto rather than the copy of
+// OpenSSL bundled with the Node.js sources.
+func validateNodeOpenssl(_ context.Context, path string, _ *Baton) *types.ValidationError {
+	if !isNodeExecutable(path) {
+		return nil
+	}
+	version, err := elfEmbeddedOpenssl(path)
+	if err != nil {
+		return types.NewValidationError(err)
+	}
+	if version != "" {
+		return types.NewValidationError(fmt.Errorf("%w: node.js executable (embedded %s)", types.ErrNodeBundledOpenSSL, version))
+	}
+	return nil
+}
+
+// IsNodeAddon tells if path looks like a native Node.js addon.
+func IsNodeAddon(path string) bool {
+	return filepath.Ext(path) == ".node"
+}
+
+// ScanNodeAddon checks a prebuilt native Node.js addon for a statically
+// linked copy of OpenSSL. Addons normally use the OpenSSL exported by the
+// node executable, or the system libcrypto.
+func ScanNodeAddon(ctx context.Context, topDir, innerPath string, rpmIgnores map[string]types.IgnoreLists, errIgnores ...types.ErrIgnoreList) *types.ScanResult {
+	res := types.NewScanResult().SetPath(innerPath)
+	path := filepath.Join(topDir, innerPath)
+
+	exe, err := elf.Open(path)
+	if err != nil {
+		// Not an ELF file, nothing to check.
+		return res.Skipped()
+	}
+	libs, _ := exe.ImportedLibraries()
+	exe.Close()
+	for _, lib := range libs {
+		if strings.HasPrefix(lib, libcryptoPrefix) {
+			return res.SetModulesUsed([]string{moduleOpenssl}).Success()
+		}
+	}
+
+	version, err := embeddedOpensslVersion(path)
+	if err != nil {
+		return res.SetError(err)
+	}
+	if version == "" {
+		return res.Success()
+	}
+	err = fmt.Errorf("%w: node.js addon (embedded %s)", types.ErrNodeBundledOpenSSL, version)
+	if isIgnored(ctx, res, topDir, innerPath, err, rpmIgnores, errIgnores) {
+		return res.Success()
+	}
+	return res.SetError(err)
+}
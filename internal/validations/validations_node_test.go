@@ -0,0 +1,90 @@
+package validations
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+// copyResource copies a file from test/resources to dir/name.
+func copyResource(t *testing.T, resource, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("../../test/resources", resource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, data, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestValidateNodeOpenssl(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	testCases := []struct {
+		name     string
+		resource string
+		wantErr  error
+	}{
+		// Not named node, so not looked at.
+		{name: "app", resource: "libcrypto.so"},
+		// Named like node, but does not export node symbols.
+		{name: "node20", resource: "libcrypto.so"},
+		{name: "node", resource: "fips_compliant_app"},
+		{name: "nodejs", resource: "libcrypto.so", wantErr: types.ErrNodeBundledOpenSSL},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := copyResource(t, tc.resource, dir, tc.name)
+			ve := validateNodeOpenssl(ctx, path, &Baton{TopDir: dir})
+			if tc.wantErr == nil {
+				if ve != nil {
+					t.Errorf("unexpected error: %v", ve.Error)
+				}
+				return
+			}
+			if ve == nil || !errors.Is(ve.Error, tc.wantErr) {
+				t.Errorf("expected %v, got %v", tc.wantErr, ve)
+			}
+		})
+	}
+}
+
+func TestScanNodeAddon(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	copyResource(t, "libcrypto.so", dir, "app/node_modules/bad/build/Release/bad.node")
+	copyResource(t, "fips_compliant_app", dir, "app/node_modules/good/build/Release/good.node")
+	if err := os.WriteFile(filepath.Join(dir, "garbage.node"), []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res := ScanNodeAddon(ctx, dir, "/app/node_modules/bad/build/Release/bad.node", nil)
+	if res.IsSuccess() || !errors.Is(res.Error.Error, types.ErrNodeBundledOpenSSL) {
+		t.Errorf("expected ErrNodeBundledOpenSSL, got %+v", res.Error)
+	}
+	if res := ScanNodeAddon(ctx, dir, "/app/node_modules/good/build/Release/good.node", nil); !res.IsSuccess() {
+		t.Errorf("expected success, got %v", res.Error.Error)
+	}
+	if res := ScanNodeAddon(ctx, dir, "/garbage.node", nil); !res.Skip {
+		t.Error("expected skip")
+	}
+
+	ignores := types.ErrIgnoreList{{
+		Error: types.KnownError{Err: types.ErrNodeBundledOpenSSL},
+		Dirs:  []string{"/app/node_modules/bad"},
+	}}
+	if res := ScanNodeAddon(ctx, dir, "/app/node_modules/bad/build/Release/bad.node", nil, ignores); !res.IsSuccess() {
+		t.Errorf("expected success, got %v", res.Error.Error)
+	}
+}
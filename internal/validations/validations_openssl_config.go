@@ -0,0 +1,359 @@
+package validations
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+const (
+	opensslConfPath       = "/etc/pki/tls/openssl.cnf"
+	cryptoPolicyPath      = "/etc/crypto-policies/config"
+	cryptoPolicyOpenssl   = "/etc/crypto-policies/back-ends/opensslcnf.config"
+	opensslDefaultSection = "default"
+
+	// Key used by fipscheck (and fips-mode-setup) for .hmac files.
+	fipscheckHMACKey = "orboDeJITITejsirpADONivirpUkvarP"
+	// Default OpenSSL FIPS module key (FIPSKEY), used for module-mac in
+	// fipsmodule.cnf.
+	opensslFIPSKey = "f4556650ac31d35461610bac4ed81b1a181b2d8a43ea2854cbae22ca74560813"
+
+	maxOpensslIncludeDepth = 16
+)
+
+// openssl3Paths tell that the image has OpenSSL 3, which uses providers.
+// Earlier versions have no providers to configure.
+var openssl3Paths = []string{
+	"/usr/lib64/libcrypto.so.3",
+	"/usr/lib/libcrypto.so.3",
+	"/usr/lib64/ossl-modules",
+	"/usr/lib/ossl-modules",
+}
+
+// fipsProviderPaths are the usual locations of the OpenSSL FIPS provider.
+var fipsProviderPaths = []string{
+	"/usr/lib64/ossl-modules/fips.so",
+	"/usr/lib/ossl-modules/fips.so",
+}
+
+// opensslValue is a configuration value, and the file it comes from.
+type opensslValue struct {
+	Value string
+	File  string
+}
+
+// opensslConf is a parsed OpenSSL configuration, including all the files
+// it includes.
+type opensslConf struct {
+	mountPath string
+	sections  map[string]map[string]opensslValue
+	includes  []string
+	problems  []*types.ScanResult
+	seen      map[string]bool
+}
+
+func (c *opensslConf) get(section, key string) opensslValue {
+	return c.sections[section][key]
+}
+
+func (c *opensslConf) problem(file string, warning bool, format string, args ...any) {
+	ve := types.NewValidationError(fmt.Errorf("%w: "+format, append([]any{types.ErrOpenSSLFIPSMisconfigured}, args...)...))
+	if warning {
+		ve.SetWarning()
+	}
+	c.problems = append(c.problems, types.NewScanResult().SetPath(file).SetValidationError(ve))
+}
+
+// ScanOpensslConfig checks that the OpenSSL configuration of the image can
+// activate the FIPS provider. It parses openssl.cnf and the files it
+// includes, checks the fips provider include and activation and the default
+// properties, reads the active system-wide crypto policy, and verifies the
+// FIPS provider against its .hmac and fipsmodule.cnf checksums, if any.
+// Each problem found is reported separately, with the path of the file
+// concerned. Extra locations of fips.so can be passed in fipsPaths. Images
+// without OpenSSL 3 are not checked.
+func ScanOpensslConfig(ctx context.Context, mountPath string, fipsPaths []string, rpmIgnores map[string]types.IgnoreLists, errIgnores ...types.ErrIgnoreList) []*types.ScanResult {
+	if !anyPathExists(mountPath, append(append([]string{}, fipsPaths...), openssl3Paths...)) {
+		return nil
+	}
+	conf := &opensslConf{
+		mountPath: mountPath,
+		sections:  make(map[string]map[string]opensslValue),
+		seen:      make(map[string]bool),
+	}
+	if !conf.parseFile(opensslConfPath, 0) {
+		// OpenSSL falls back to its built-in defaults.
+		conf.problem(opensslConfPath, true, "%s not found", opensslConfPath)
+	} else {
+		conf.checkFIPSProvider(append(append([]string{}, fipsPaths...), fipsProviderPaths...))
+	}
+	conf.checkCryptoPolicy()
+
+	var results []*types.ScanResult
+	for _, res := range conf.problems {
+		if isIgnored(ctx, res, mountPath, res.Path, res.Error.Error, rpmIgnores, errIgnores) {
+			continue
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// parseFile reads an OpenSSL configuration file, following any .include
+// directives. It returns false if the file does not exist.
+func (c *opensslConf) parseFile(file string, depth int) bool {
+	resolved, err := resolveInRoot(c.mountPath, file)
+	if err != nil {
+		return false
+	}
+	fi, err := os.Stat(filepath.Join(c.mountPath, resolved))
+	if err != nil {
+		return false
+	}
+	if c.seen[resolved] || depth > maxOpensslIncludeDepth {
+		return true
+	}
+	c.seen[resolved] = true
+
+	if fi.IsDir() {
+		// All *.cnf and *.conf files in the directory are included.
+		entries, err := os.ReadDir(filepath.Join(c.mountPath, resolved))
+		if err != nil {
+			c.problem(file, false, "can't read %s: %v", file, err)
+			return true
+		}
+		for _, e := range entries {
+			if ext := path.Ext(e.Name()); ext == ".cnf" || ext == ".conf" {
+				c.parseFile(path.Join(file, e.Name()), depth+1)
+			}
+		}
+		return true
+	}
+
+	f, err := os.Open(filepath.Join(c.mountPath, resolved))
+	if err != nil {
+		c.problem(file, false, "can't read %s: %v", file, err)
+		return true
+	}
+	defer f.Close()
+
+	section := opensslDefaultSection
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(stripOpensslComment(s.Text()))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, ".include"):
+			inc := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(line, ".include")), "="))
+			if !path.IsAbs(inc) {
+				inc = path.Join(path.Dir(file), inc)
+			}
+			c.includes = append(c.includes, inc)
+			if !c.parseFile(inc, depth+1) {
+				c.problem(file, false, "included file %s not found", inc)
+			}
+		case strings.HasPrefix(line, "."):
+			// Other directives, such as .pragma.
+		case strings.HasPrefix(line, "["):
+			section = strings.TrimSpace(strings.Trim(line, "[]"))
+		default:
+			k, v, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			k, v = strings.TrimSpace(k), strings.Trim(strings.TrimSpace(v), `"'`)
+			if c.sections[section] == nil {
+				c.sections[section] = make(map[string]opensslValue)
+			}
+			c.sections[section][k] = opensslValue{Value: v, File: file}
+		}
+	}
+	if err := s.Err(); err != nil {
+		c.problem(file, false, "can't read %s: %v", file, err)
+	}
+	return true
+}
+
+// stripOpensslComment removes a comment (outside of quotes) from line.
+func stripOpensslComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// isOpensslTrue tells if a boolean configuration value is set.
+func isOpensslTrue(v string) bool {
+	switch strings.ToLower(v) {
+	case "1", "yes", "true", "on":
+		return true
+	}
+	return false
+}
+
+func (c *opensslConf) checkFIPSProvider(fipsPaths []string) {
+	initSect := c.get(opensslDefaultSection, "openssl_conf").Value
+	if algSect := c.get(initSect, "alg_section").Value; algSect != "" {
+		if props := c.get(algSect, "default_properties"); disablesFIPS(props.Value) {
+			c.problem(props.File, false, "default_properties %q disable FIPS", props.Value)
+		}
+	}
+
+	// Find the fips provider among the configured ones.
+	var fips opensslValue
+	providers := c.get(initSect, "providers").Value
+	names := make([]string, 0, len(c.sections[providers]))
+	for name := range c.sections[providers] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sect := c.sections[providers][name]
+		if name == "fips" || strings.HasSuffix(c.get(sect.Value, "module").Value, "/fips.so") {
+			fips = sect
+			break
+		}
+	}
+	fipsSect := fips.Value
+	if fipsSect == "" {
+		// RHEL activates the FIPS provider automatically when the kernel
+		// is in FIPS mode, provided the crypto policy back-end is used.
+		for _, inc := range c.includes {
+			if inc == cryptoPolicyOpenssl {
+				return
+			}
+		}
+		c.problem(opensslConfPath, false, "neither the fips provider nor the crypto policy back-end (%s) is configured", cryptoPolicyOpenssl)
+		return
+	}
+	if _, ok := c.sections[fipsSect]; !ok {
+		c.problem(fips.File, false, "fips provider section [%s] not defined (missing fipsmodule.cnf include?)", fipsSect)
+		return
+	}
+	if activate := c.get(fipsSect, "activate"); !isOpensslTrue(activate.Value) {
+		file := activate.File
+		if file == "" {
+			file = fips.File
+		}
+		c.problem(file, false, "fips provider section [%s] is not activated", fipsSect)
+	}
+
+	module := c.get(fipsSect, "module").Value
+	if module == "" {
+		for _, p := range fipsPaths {
+			if isRegularFile(c.mountPath, p) {
+				module = p
+				break
+			}
+		}
+	}
+	if module == "" || !isRegularFile(c.mountPath, module) {
+		c.problem(opensslConfPath, false, "fips provider module %s not found", module)
+		return
+	}
+	c.checkFIPSModuleMAC(module, c.get(fipsSect, "module-mac"))
+}
+
+// disablesFIPS tells if a property query string turns FIPS off.
+func disablesFIPS(props string) bool {
+	for _, p := range strings.Split(props, ",") {
+		// A leading "?" makes the property optional, it still applies.
+		p = strings.TrimPrefix(strings.ReplaceAll(strings.ToLower(p), " ", ""), "?")
+		if p == "-fips" || p == "fips=no" || p == "fips!=yes" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFIPSModuleMAC verifies the FIPS provider module against its .hmac
+// file, and the module-mac value from fipsmodule.cnf.
+func (c *opensslConf) checkFIPSModuleMAC(module string, mac opensslValue) {
+	hmacFile := path.Join(path.Dir(module), "."+path.Base(module)+".hmac")
+	if data, err := os.ReadFile(filepath.Join(c.mountPath, hmacFile)); err == nil {
+		want := strings.ToLower(firstField(string(data)))
+		got, err := c.fileHMAC(module, []byte(fipscheckHMACKey))
+		if err != nil {
+			c.problem(module, false, "can't read %s: %v", module, err)
+			return
+		}
+		if got != want {
+			c.problem(module, false, "%s does not match checksum in %s", module, hmacFile)
+		}
+	}
+	if mac.Value != "" {
+		key, _ := hex.DecodeString(opensslFIPSKey)
+		got, err := c.fileHMAC(module, key)
+		if err != nil {
+			c.problem(module, false, "can't read %s: %v", module, err)
+			return
+		}
+		want := strings.ToLower(strings.ReplaceAll(mac.Value, ":", ""))
+		if got != want {
+			c.problem(mac.File, false, "%s does not match module-mac in %s", module, mac.File)
+		}
+	}
+}
+
+func (c *opensslConf) fileHMAC(file string, key []byte) (string, error) {
+	f, err := os.Open(filepath.Join(c.mountPath, file))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := hmac.New(sha256.New, key)
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func firstField(s string) string {
+	if f := strings.Fields(s); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
+
+// checkCryptoPolicy warns about system-wide crypto policies which enable
+// algorithms not allowed in FIPS mode.
+func (c *opensslConf) checkCryptoPolicy() {
+	data, err := os.ReadFile(filepath.Join(c.mountPath, cryptoPolicyPath))
+	if err != nil {
+		return
+	}
+	s := bufio.NewScanner(strings.NewReader(string(data)))
+	for s.Scan() {
+		line := strings.TrimSpace(stripOpensslComment(s.Text()))
+		if line == "" {
+			continue
+		}
+		// Policy with optional subpolicies, e.g. "DEFAULT:SHA1".
+		policy, _, _ := strings.Cut(line, ":")
+		if policy == "LEGACY" {
+			c.problem(cryptoPolicyPath, true, "crypto policy %s enables algorithms not allowed in FIPS mode", line)
+		}
+		return
+	}
+}
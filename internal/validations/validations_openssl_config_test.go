@@ -0,0 +1,222 @@
+package validations
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+func hmacHex(key []byte, data string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestScanOpensslConfig(t *testing.T) {
+	ctx := context.Background()
+
+	const fipsSo = "fips provider"
+	fipsKey, _ := hex.DecodeString(opensslFIPSKey)
+	moduleMAC := strings.ToUpper(hmacHex(fipsKey, fipsSo))
+	fileHMAC := hmacHex([]byte(fipscheckHMACKey), fipsSo)
+
+	rhelConf := `# RHEL style
+openssl_conf = openssl_init
+config_diagnostics = 1
+.include /etc/crypto-policies/back-ends/opensslcnf.config
+
+[openssl_init]
+providers = provider_sect
+alg_section = evp_properties
+
+[provider_sect]
+default = default_sect
+##fips = fips_sect
+
+[default_sect]
+activate = 1
+
+[evp_properties]
+`
+	fipsConf := func(props string) string {
+		return `openssl_conf = openssl_init
+.include = fipsmodule.cnf
+
+[openssl_init]
+providers = provider_sect
+alg_section = algorithm_sect
+
+[provider_sect]
+fips = fips_sect
+base = base_sect
+
+[base_sect]
+activate = 1
+
+[algorithm_sect]
+default_properties = ` + props + "\n"
+	}
+	fipsModuleCnf := func(mac, activate string) string {
+		return "[fips_sect]\n" + activate + "\ninstall-version = 1\nmodule-mac = " + mac + "\n"
+	}
+	base := map[string]string{
+		"/usr/lib64/libcrypto.so.3":             "",
+		"/usr/lib64/ossl-modules/fips.so":       fipsSo,
+		"/usr/lib64/ossl-modules/.fips.so.hmac": fileHMAC + "\n",
+		"/etc/crypto-policies/config":           "# policy\nFIPS\n",
+		cryptoPolicyOpenssl:                     "CipherString = @SECLEVEL=2\n",
+	}
+	with := func(files map[string]string) map[string]string {
+		m := make(map[string]string)
+		for k, v := range base {
+			m[k] = v
+		}
+		for k, v := range files {
+			if v == "-" {
+				delete(m, k)
+			} else {
+				m[k] = v
+			}
+		}
+		return m
+	}
+
+	testCases := []struct {
+		name  string
+		files map[string]string
+		want  []string // Expected problems, as "path: message".
+		warn  bool
+	}{
+		{
+			name:  "crypto policies",
+			files: with(map[string]string{opensslConfPath: rhelConf}),
+		},
+		{
+			name:  "crypto policy back-end missing",
+			files: with(map[string]string{opensslConfPath: rhelConf, cryptoPolicyOpenssl: "-"}),
+			want:  []string{opensslConfPath + ": included file " + cryptoPolicyOpenssl + " not found"},
+		},
+		{
+			name:  "nothing configured",
+			files: with(map[string]string{opensslConfPath: "[openssl_init]\n"}),
+			want:  []string{opensslConfPath + ": neither the fips provider"},
+		},
+		{
+			name: "fips provider",
+			files: with(map[string]string{
+				opensslConfPath:               fipsConf(`"fips=yes"`),
+				"/etc/pki/tls/fipsmodule.cnf": fipsModuleCnf(moduleMAC, "activate = 1"),
+			}),
+		},
+		{
+			name: "fips provider not activated",
+			files: with(map[string]string{
+				opensslConfPath:               fipsConf(`"fips=yes"`),
+				"/etc/pki/tls/fipsmodule.cnf": fipsModuleCnf(moduleMAC, "# activate = 1"),
+			}),
+			want: []string{opensslConfPath + ": fips provider section [fips_sect] is not activated"},
+		},
+		{
+			name: "fips disabled by default properties",
+			files: with(map[string]string{
+				opensslConfPath:               fipsConf("?fips=no"),
+				"/etc/pki/tls/fipsmodule.cnf": fipsModuleCnf(moduleMAC, "activate = 1"),
+			}),
+			want: []string{opensslConfPath + `: default_properties "?fips=no" disable FIPS`},
+		},
+		{
+			name: "fipsmodule.cnf missing",
+			files: with(map[string]string{
+				opensslConfPath: fipsConf(""),
+			}),
+			want: []string{
+				opensslConfPath + ": included file /etc/pki/tls/fipsmodule.cnf not found",
+				opensslConfPath + ": fips provider section [fips_sect] not defined",
+			},
+		},
+		{
+			name: "checksum mismatch",
+			files: with(map[string]string{
+				opensslConfPath:                         fipsConf(""),
+				"/etc/pki/tls/fipsmodule.cnf":           fipsModuleCnf("00:11:22", "activate = 1"),
+				"/usr/lib64/ossl-modules/.fips.so.hmac": "0000  fips.so\n",
+			}),
+			want: []string{
+				"/usr/lib64/ossl-modules/fips.so: /usr/lib64/ossl-modules/fips.so does not match checksum in /usr/lib64/ossl-modules/.fips.so.hmac",
+				"/etc/pki/tls/fipsmodule.cnf: /usr/lib64/ossl-modules/fips.so does not match module-mac",
+			},
+		},
+		{
+			name:  "legacy crypto policy",
+			files: with(map[string]string{opensslConfPath: rhelConf, "/etc/crypto-policies/config": "LEGACY:AD-SUPPORT\n"}),
+			want:  []string{cryptoPolicyPath + ": crypto policy LEGACY:AD-SUPPORT enables"},
+			warn:  true,
+		},
+		{
+			name:  "no openssl.cnf",
+			files: with(nil),
+			want:  []string{opensslConfPath + ": " + opensslConfPath + " not found"},
+			warn:  true,
+		},
+		{
+			name:  "no openssl 3",
+			files: map[string]string{"/usr/lib64/libcrypto.so.1.1": ""},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, data := range tc.files {
+				p := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			results := ScanOpensslConfig(ctx, root, nil, nil)
+			if len(results) != len(tc.want) {
+				for _, res := range results {
+					t.Log(res.Path, res.Error.Error)
+				}
+				t.Fatalf("got %d problems, want %d", len(results), len(tc.want))
+			}
+			for i, res := range results {
+				if !errors.Is(res.Error.Error, types.ErrOpenSSLFIPSMisconfigured) {
+					t.Errorf("expected ErrOpenSSLFIPSMisconfigured, got %v", res.Error.Error)
+				}
+				msg := strings.TrimPrefix(res.Error.Error.Error(), types.ErrOpenSSLFIPSMisconfigured.Error()+": ")
+				if got := res.Path + ": " + msg; !strings.HasPrefix(got, tc.want[i]) {
+					t.Errorf("got %q, want %q", got, tc.want[i])
+				}
+				if res.Error.IsWarning() != tc.warn {
+					t.Errorf("got warning = %v, want %v", res.Error.IsWarning(), tc.warn)
+				}
+			}
+		})
+	}
+
+	t.Run("ignored", func(t *testing.T) {
+		root := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(root, "usr/lib64/ossl-modules"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		ignores := types.ErrIgnoreList{{
+			Error: types.KnownError{Err: types.ErrOpenSSLFIPSMisconfigured},
+			Files: []string{opensslConfPath},
+		}}
+		if results := ScanOpensslConfig(ctx, root, nil, nil, ignores); len(results) != 0 {
+			t.Errorf("expected no problems, got %d", len(results))
+		}
+	})
+}
@@ -2,10 +2,13 @@ package validations
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/openshift/check-payload/internal/rpm/rpmtest"
 	"github.com/openshift/check-payload/internal/types"
 )
 
@@ -94,4 +97,100 @@ func TestCheckArtifact(t *testing.T) {
 			t.Error("expected error when RPM missing")
 		}
 	})
+	fipsProvider := func(digest string) rpmtest.Package {
+		return rpmtest.Package{
+			Name: "openssl-fips-provider", Version: "3.0.7", Release: "2.el9", Arch: "x86_64",
+			DigestAlgo: 8, // SHA256.
+			Files: []rpmtest.File{{
+				Path:   "/usr/lib64/ossl-modules/fips.so",
+				Mode:   0o100644,
+				Digest: digest,
+			}},
+		}
+	}
+	// Digest of the empty fips.so written by createDirAndFile.
+	const emptyDigest = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	m := types.FipsModule{
+		Module:                      "openssl",
+		CertifiedArtifact:           "openssl-fips-provider",
+		CertifiedArtifactMinVersion: "3.0.7",
+		CertifiedArtifactPaths:      []string{"/usr/lib64/ossl-modules/fips.so"},
+	}
+
+	t.Run("RPM present and file intact", func(t *testing.T) {
+		dir := t.TempDir()
+		createDirAndFile(t, filepath.Join(dir, "usr", "lib64", "ossl-modules"))
+		rpmtest.Install(t, dir, fipsProvider(emptyDigest))
+		if err := CheckArtifact(ctx, m, dir); err != nil {
+			t.Errorf("unexpected error: %v", err.Error)
+		}
+	})
+
+	t.Run("file modified", func(t *testing.T) {
+		dir := t.TempDir()
+		createDirAndFile(t, filepath.Join(dir, "usr", "lib64", "ossl-modules"))
+		rpmtest.Install(t, dir, fipsProvider(strings.Repeat("0", 64)))
+		err := CheckArtifact(ctx, m, dir)
+		if err == nil || !errors.Is(err.Error, types.ErrFipsArtifactTampered) {
+			t.Fatalf("expected ErrFipsArtifactTampered, got %v", err)
+		}
+		// No fallback to the host library check.
+		cfg := &types.Config{ConfigFile: types.ConfigFile{FIPSCertifiedModules: []types.FipsModule{m}}}
+		if ve := ValidateModule(ctx, cfg, dir, "openssl"); ve == nil || !errors.Is(ve.Error, types.ErrFipsArtifactTampered) {
+			t.Errorf("ValidateModule: expected ErrFipsArtifactTampered, got %v", ve)
+		}
+	})
+
+	t.Run("vendor and signing key", func(t *testing.T) {
+		const (
+			redHat    = "Red Hat, Inc."
+			redHatKey = "199e2f91fd431d51"
+		)
+		testCases := []struct {
+			name       string
+			vendor     string // Of the installed package.
+			sig        []byte
+			wantVendor string
+			wantKeys   []string
+			trusted    bool
+		}{
+			{name: "not required", vendor: "Acme", trusted: true},
+			{name: "trusted", vendor: redHat, sig: rpmtest.Signature(redHatKey), wantVendor: redHat, wantKeys: []string{"0xFD431D51"}, trusted: true},
+			{name: "fingerprint", sig: rpmtest.Signature(redHatKey), wantKeys: []string{"567E 347A D004 4ADE 55BA 8A5F 199E 2F91 FD43 1D51"}, trusted: true},
+			{name: "wrong vendor", vendor: "Acme", sig: rpmtest.Signature(redHatKey), wantVendor: redHat},
+			{name: "wrong key", vendor: redHat, sig: rpmtest.Signature("0123456789abcdef"), wantKeys: []string{redHatKey}},
+			{name: "not signed", vendor: redHat, wantKeys: []string{redHatKey}},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				dir := t.TempDir()
+				createDirAndFile(t, filepath.Join(dir, "usr", "lib64", "ossl-modules"))
+				pkg := fipsProvider(emptyDigest)
+				pkg.Vendor, pkg.Signature = tc.vendor, tc.sig
+				rpmtest.Install(t, dir, pkg)
+				m := m
+				m.CertifiedArtifactVendor = tc.wantVendor
+				m.CertifiedArtifactSigningKeys = tc.wantKeys
+				err := CheckArtifact(ctx, m, dir)
+				if tc.trusted {
+					if err != nil {
+						t.Errorf("unexpected error: %v", err.Error)
+					}
+				} else if err == nil || !errors.Is(err.Error, types.ErrFipsArtifactUntrusted) {
+					t.Errorf("expected ErrFipsArtifactUntrusted, got %v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("file not owned by any RPM", func(t *testing.T) {
+		dir := t.TempDir()
+		createDirAndFile(t, filepath.Join(dir, "usr", "lib64", "ossl-modules"))
+		pkg := fipsProvider(emptyDigest)
+		pkg.Files = nil
+		rpmtest.Install(t, dir, pkg)
+		if err := CheckArtifact(ctx, m, dir); err == nil || !errors.Is(err.Error, types.ErrFipsArtifactTampered) {
+			t.Errorf("expected ErrFipsArtifactTampered, got %v", err)
+		}
+	})
 }
@@ -1,59 +1,254 @@
 package validations
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/openshift/check-payload/internal/rpm"
 	"github.com/openshift/check-payload/internal/types"
 
 	"k8s.io/klog/v2"
 )
 
-const releaseFilePath = "/etc/redhat-release"
+const (
+	releaseFilePath   = "/etc/redhat-release"
+	osReleasePath     = "/etc/os-release"
+	usrOSReleasePath  = "/usr/lib/os-release"
+	systemCPEFilePath = "/etc/system-release-cpe"
+	ubiRepoPath       = "/etc/yum.repos.d/ubi.repo"
+)
 
-func ValidateOS(cfg *types.Config, mountPath string) (info types.OSInfo) {
-	info.Path = releaseFilePath
+// releasePackages are the packages which provide the distribution files,
+// in the order of preference.
+var releasePackages = []string{"redhat-release", "redhat-release-eula"}
 
-	cd := cfg.GetCertifiedDistributions()
-	if len(cd) == 0 {
-		info.Error = types.NewValidationError(types.ErrCertifiedDistributionsEmpty).SetWarning()
-		return info
+// DetectOS finds the OS and the class of the image under mountPath. The OS
+// is read from the distribution files: os-release, /etc/system-release-cpe
+// and /etc/redhat-release, or, if there are none, from the release package
+// in the rpmdb. It returns an error if none of those is found.
+func DetectOS(mountPath string) (info types.OSInfo) {
+	idx, err := rpm.IndexOf(mountPath)
+	if err != nil {
+		klog.V(1).InfoS("no usable rpmdb", "mountPath", mountPath, "error", err)
+		idx = nil
 	}
+	info.Class = imageClass(mountPath, idx)
 
-	path, err := GetTargetPath(mountPath)
+	found, err := readOSFiles(mountPath, &info)
 	if err != nil {
 		info.Error = types.NewValidationError(err)
 		return info
 	}
+	if !found && idx != nil {
+		for _, name := range releasePackages {
+			if pkg := idx.Package(name); pkg != nil {
+				klog.V(1).InfoS("no distribution files, using the release package", "mountPath", mountPath, "nvra", pkg.NVRA())
+				info.ID = "rhel"
+				info.VersionID = pkg.Version
+				info.Name = pkg.NVRA()
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		info.Path = releaseFilePath
+		info.Error = types.NewValidationError(types.ErrDistributionFileMissing)
+	}
+	return info
+}
 
-	f, err := os.ReadFile(path)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			info.Error = types.NewValidationError(types.ErrDistributionFileMissing)
-		} else {
-			info.Error = types.NewValidationError(err)
+// readOSFiles fills info from the distribution files under mountPath, and
+// tells whether there are any.
+func readOSFiles(mountPath string, info *types.OSInfo) (found bool, _ error) {
+	for _, p := range []string{osReleasePath, usrOSReleasePath} {
+		data, err := readDistributionFile(mountPath, p)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return false, err
+			}
+			continue
 		}
-		return info
+		vars := parseOSRelease(data)
+		info.ID = vars["ID"]
+		info.VersionID = vars["VERSION_ID"]
+		info.Name = vars["PRETTY_NAME"]
+		info.CPE = vars["CPE_NAME"]
+		info.Path = p
+		found = true
+		break
 	}
-	if len(f) == 0 {
-		info.Error = types.NewValidationError(fmt.Errorf("%v is an empty file", releaseFilePath))
+
+	data, err := readDistributionFile(mountPath, systemCPEFilePath)
+	switch {
+	case err == nil:
+		if info.CPE == "" {
+			info.CPE = firstLine(data)
+		}
+		if info.Path == "" {
+			info.Path = systemCPEFilePath
+		}
+		found = true
+	case !errors.Is(err, os.ErrNotExist):
+		return false, err
+	}
+
+	data, err = readDistributionFile(mountPath, releaseFilePath)
+	switch {
+	case err == nil:
+		if len(data) == 0 && !found {
+			info.Path = releaseFilePath
+			return false, fmt.Errorf("%v is an empty file", releaseFilePath)
+		}
+		info.Release = firstLine(data)
+		if !found {
+			info.Path = releaseFilePath
+		}
+		found = true
+	case !errors.Is(err, os.ErrNotExist):
+		return false, err
+	}
+	return found, nil
+}
+
+// imageClass tells the class of the image under mountPath from the
+// packages installed, idx being nil if there is no rpmdb.
+func imageClass(mountPath string, idx *rpm.Index) types.ImageClass {
+	if idx == nil || len(idx.Packages()) == 0 {
+		return types.ImageClassScratch
+	}
+	switch {
+	case idx.Package("microdnf") != nil:
+		return types.ImageClassUBIMinimal
+	case idx.Package("dnf") != nil || idx.Package("yum") != nil:
+		if _, err := os.Stat(filepath.Join(mountPath, ubiRepoPath)); err == nil {
+			return types.ImageClassUBI
+		}
+		return types.ImageClassRHEL
+	case idx.Package("rpm") != nil || idx.Package("rpm-ostree") != nil:
+		return types.ImageClassRHEL
+	}
+	return types.ImageClassUBIMicro
+}
+
+// ValidateOS checks the OS of an image, as detected by DetectOS, against
+// the certified_distributions. An image with no OS at all (a scratch or
+// static image) passes if its executables use crypto modules, modulesInUse,
+// and these are all built into the executables.
+func ValidateOS(cfg *types.Config, info types.OSInfo, modulesInUse []string) types.OSInfo {
+	cd := cfg.GetCertifiedDistributions()
+	if len(cd) == 0 {
+		info.Path = releaseFilePath
+		info.Error = types.NewValidationError(types.ErrCertifiedDistributionsEmpty).SetWarning()
 		return info
 	}
 
+	if info.Error != nil {
+		if info.Class == types.ImageClassScratch && errors.Is(info.Error.Error, types.ErrDistributionFileMissing) {
+			validateScratchImage(cfg, &info, modulesInUse)
+		}
+		return info
+	}
 	for _, d := range cd {
-		if bytes.HasPrefix(f, []byte(d)) {
+		if d.Matches(&info) {
 			info.Certified = true
+			if d.ID == "" && !d.IsCPE() {
+				// Report the file the match was made against.
+				info.Path = releaseFilePath
+			}
 			break
 		}
 	}
 	return info
 }
 
+// validateScratchImage checks an image with no OS. It needs none as long
+// as it only uses crypto modules built into its executables.
+func validateScratchImage(cfg *types.Config, info *types.OSInfo, modulesInUse []string) {
+	if len(modulesInUse) == 0 {
+		// Nothing to tell it is FIPS capable.
+		return
+	}
+	var needOS []string
+	for _, m := range modulesInUse {
+		if !isBinarySourceModule(cfg, m) {
+			needOS = append(needOS, m)
+		}
+	}
+	if len(needOS) > 0 {
+		sort.Strings(needOS)
+		info.Error = types.NewValidationError(fmt.Errorf("%s image uses %s from the OS: %w",
+			info.Class, strings.Join(needOS, ", "), types.ErrDistributionFileMissing))
+		return
+	}
+	klog.V(1).InfoS("image has no OS, but only uses crypto modules built into its executables", "class", info.Class, "modules", modulesInUse)
+	info.Error = nil
+	info.Path = ""
+	info.Certified = true
+}
+
+func isBinarySourceModule(cfg *types.Config, module string) bool {
+	for _, m := range cfg.GetFIPSCertifiedModules() {
+		if m.Module == module && m.IsBinarySource() {
+			return true
+		}
+	}
+	return false
+}
+
+// readDistributionFile reads the distribution file at path in the image
+// under mountPath, following a symlink.
+func readDistributionFile(mountPath, path string) ([]byte, error) {
+	target, err := targetPath(mountPath, path)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(target)
+}
+
+// parseOSRelease parses an os-release(5) file into its variables.
+func parseOSRelease(data []byte) map[string]string {
+	vars := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			q := v[0]
+			v = v[1 : len(v)-1]
+			if q == '"' {
+				// Unescape \", \\, \$ and \`.
+				var sb strings.Builder
+				for i := 0; i < len(v); i++ {
+					if v[i] == '\\' && i+1 < len(v) {
+						i++
+					}
+					sb.WriteByte(v[i])
+				}
+				v = sb.String()
+			}
+		}
+		vars[strings.TrimSpace(k)] = v
+	}
+	return vars
+}
+
+func firstLine(data []byte) string {
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSpace(line)
+}
+
 // ValidateModuleArtifacts checks that each detected module's certified
 // artifact is present. Runs independently of the OS allowlist check.
 func ValidateModuleArtifacts(ctx context.Context, cfg *types.Config, mountPath string, modulesInUse []string) *types.ValidationError {
@@ -84,7 +279,11 @@ func containsStr(ss []string, s string) bool {
 
 // in case the file is symlinked, we need to check to ensure there is not a target path
 func GetTargetPath(mountPath string) (string, error) {
-	path := filepath.Join(mountPath, releaseFilePath)
+	return targetPath(mountPath, releaseFilePath)
+}
+
+func targetPath(mountPath, file string) (string, error) {
+	path := filepath.Join(mountPath, file)
 	fi, err := os.Lstat(path)
 	if err != nil {
 		return path, err
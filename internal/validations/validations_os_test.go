@@ -2,9 +2,13 @@ package validations
 
 import (
 	"context"
+	"errors"
+	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/openshift/check-payload/internal/rpm/rpmtest"
 	"github.com/openshift/check-payload/internal/types"
 )
 
@@ -99,3 +103,181 @@ func TestValidateModule(t *testing.T) {
 		}
 	})
 }
+
+func TestValidateOS(t *testing.T) {
+	const osRelease = `# Comments and blank lines are skipped.
+
+NAME="Red Hat Enterprise Linux"
+ID="rhel"
+VERSION_ID='9.4'
+PRETTY_NAME="Red Hat Enterprise Linux 9.4 (\"Plow\")"
+CPE_NAME="cpe:/o:redhat:enterprise_linux:9::baseos"
+`
+	testCases := []struct {
+		name    string
+		files   map[string]string
+		links   map[string]string
+		pkgs    []rpmtest.Package
+		cd      []types.CertifiedDistribution
+		modules []string
+		want    types.OSInfo
+		wantErr error
+	}{
+		{
+			name:  "os-release",
+			files: map[string]string{"/usr/lib/os-release": osRelease},
+			links: map[string]string{"/etc/os-release": "../usr/lib/os-release"},
+			cd:    []types.CertifiedDistribution{{ID: "rhel", Versions: []string{"9.2", "9.4"}}},
+			want: types.OSInfo{
+				Certified: true, Path: "/etc/os-release", ID: "rhel", VersionID: "9.4",
+				Name: `Red Hat Enterprise Linux 9.4 ("Plow")`, CPE: "cpe:/o:redhat:enterprise_linux:9::baseos",
+				Class: types.ImageClassScratch,
+			},
+		},
+		{
+			name:  "os-release version not certified",
+			files: map[string]string{"/usr/lib/os-release": "ID=rhel\nVERSION_ID=9.40\n"},
+			cd:    []types.CertifiedDistribution{{ID: "rhel", Versions: []string{"9.4"}}},
+			want:  types.OSInfo{Path: "/usr/lib/os-release", ID: "rhel", VersionID: "9.40", Class: types.ImageClassScratch},
+		},
+		{
+			name: "redhat-release",
+			files: map[string]string{
+				"/etc/os-release":     osRelease,
+				"/etc/redhat-release": "Red Hat Enterprise Linux release 9.4 (Plow)\n",
+			},
+			cd: []types.CertifiedDistribution{{Release: "Red Hat Enterprise Linux release 9.4 (Plow)"}},
+			want: types.OSInfo{
+				Certified: true, Path: "/etc/redhat-release", ID: "rhel", VersionID: "9.4",
+				Name: `Red Hat Enterprise Linux 9.4 ("Plow")`, CPE: "cpe:/o:redhat:enterprise_linux:9::baseos",
+				Release: "Red Hat Enterprise Linux release 9.4 (Plow)", Class: types.ImageClassScratch,
+			},
+		},
+		{
+			name:  "system-release-cpe",
+			files: map[string]string{"/etc/system-release-cpe": "cpe:/o:redhat:enterprise_linux:9::baseos\n"},
+			cd:    []types.CertifiedDistribution{{Release: "cpe:/o:redhat:enterprise_linux:9::baseos"}},
+			want:  types.OSInfo{Certified: true, Path: "/etc/system-release-cpe", CPE: "cpe:/o:redhat:enterprise_linux:9::baseos", Class: types.ImageClassScratch},
+		},
+		{
+			name: "ubi-micro with no distribution files",
+			pkgs: []rpmtest.Package{
+				{Name: "redhat-release", Version: "9.4", Release: "0.5.el9", Arch: "x86_64"},
+				{Name: "glibc", Version: "2.34", Release: "100.el9", Arch: "x86_64"},
+			},
+			cd: []types.CertifiedDistribution{{ID: "rhel", Versions: []string{"9.4"}}},
+			want: types.OSInfo{
+				Certified: true, ID: "rhel", VersionID: "9.4", Name: "redhat-release-9.4-0.5.el9.x86_64",
+				Class: types.ImageClassUBIMicro,
+			},
+		},
+		{
+			name:    "scratch with native FIPS go only",
+			cd:      []types.CertifiedDistribution{{ID: "rhel"}},
+			modules: []string{"go"},
+			want:    types.OSInfo{Certified: true, Class: types.ImageClassScratch},
+		},
+		{
+			name:    "scratch with no crypto",
+			cd:      []types.CertifiedDistribution{{ID: "rhel"}},
+			wantErr: types.ErrDistributionFileMissing,
+		},
+		{
+			name:    "scratch with openssl",
+			cd:      []types.CertifiedDistribution{{ID: "rhel"}},
+			modules: []string{"go", "openssl"},
+			wantErr: types.ErrDistributionFileMissing,
+		},
+		{
+			name:    "ubi-micro with no distribution file",
+			pkgs:    []rpmtest.Package{{Name: "glibc", Version: "2.34", Release: "100.el9", Arch: "x86_64"}},
+			cd:      []types.CertifiedDistribution{{ID: "rhel"}},
+			wantErr: types.ErrDistributionFileMissing,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tc.files {
+				path := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for name, target := range tc.links {
+				if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.pkgs != nil {
+				rpmtest.Install(t, root, tc.pkgs...)
+			}
+			cfg := &types.Config{ConfigFile: types.ConfigFile{
+				CertifiedDistributions: tc.cd,
+				FIPSCertifiedModules: []types.FipsModule{
+					{Module: "openssl", CertifiedArtifact: "openssl-fips-provider"},
+					{Module: "go", ArtifactSource: "binary"},
+				},
+			}}
+			got := ValidateOS(cfg, DetectOS(root), tc.modules)
+			if tc.wantErr != nil {
+				if got.Error == nil || !errors.Is(got.Error.Error, tc.wantErr) {
+					t.Fatalf("got error %v, want %v", got.Error, tc.wantErr)
+				}
+				return
+			}
+			if got.Error != nil {
+				t.Fatalf("unexpected error: %v", got.Error.Error)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestImageClass(t *testing.T) {
+	pkg := func(name string) rpmtest.Package {
+		return rpmtest.Package{Name: name, Version: "1", Release: "1.el9", Arch: "x86_64"}
+	}
+	testCases := []struct {
+		name  string
+		pkgs  []rpmtest.Package
+		files []string
+		want  types.ImageClass
+	}{
+		{name: "scratch", want: types.ImageClassScratch},
+		{name: "empty rpmdb", pkgs: []rpmtest.Package{}, want: types.ImageClassScratch},
+		{name: "ubi-micro", pkgs: []rpmtest.Package{pkg("redhat-release"), pkg("glibc")}, want: types.ImageClassUBIMicro},
+		{name: "ubi-minimal", pkgs: []rpmtest.Package{pkg("rpm"), pkg("microdnf")}, want: types.ImageClassUBIMinimal},
+		{name: "ubi", pkgs: []rpmtest.Package{pkg("rpm"), pkg("dnf")}, files: []string{ubiRepoPath}, want: types.ImageClassUBI},
+		{name: "rhel", pkgs: []rpmtest.Package{pkg("rpm"), pkg("dnf")}, want: types.ImageClassRHEL},
+		{name: "rhcos", pkgs: []rpmtest.Package{pkg("rpm-ostree")}, want: types.ImageClassRHEL},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			if tc.pkgs != nil {
+				rpmtest.Install(t, root, tc.pkgs...)
+			}
+			for _, f := range tc.files {
+				path := filepath.Join(root, f)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := DetectOS(root).Class; got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package validations
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+var (
+	// pythonPackageDirs are directories where python packages are installed.
+	pythonPackageDirs = map[string]bool{
+		"site-packages": true,
+		"dist-packages": true,
+	}
+	// pythonDynloadDir holds the interpreter's own extension modules.
+	pythonDynloadDir = "lib-dynload"
+
+	// Copies of libssl or libcrypto, including those renamed by auditwheel
+	// (e.g. cryptography.libs/libcrypto-a1b2c3d4.so.3).
+	bundledOpensslLibRegexp = regexp.MustCompile(`^lib(?:ssl|crypto)(?:-[0-9a-f]+)?\.so(?:\.\d+)*$`)
+)
+
+// pythonPackage is a python distribution installed in a package directory.
+type pythonPackage struct {
+	Name    string
+	Version string
+	Files   []string // Paths relative to the package directory.
+}
+
+func (p *pythonPackage) String() string {
+	if p.Version == "" {
+		return p.Name
+	}
+	return p.Name + " " + p.Version
+}
+
+// IsPythonLibDir tells if innerPath is a directory holding python packages
+// (site-packages or dist-packages), or the interpreter extension modules
+// (lib-dynload).
+func IsPythonLibDir(innerPath string) bool {
+	base := path.Base(innerPath)
+	return pythonPackageDirs[base] || base == pythonDynloadDir
+}
+
+// ScanPythonLibDir checks python packages installed in the innerDir
+// directory for their own copies of OpenSSL, which sidestep the system FIPS
+// provider. Those are either shared libraries (such as the ones vendored by
+// auditwheel under <package>.libs), or extension modules with OpenSSL
+// statically linked in. Only the offending packages are reported, one
+// result per package, with the path of the first offending file.
+func ScanPythonLibDir(ctx context.Context, topDir, innerDir string, rpmIgnores map[string]types.IgnoreLists, errIgnores ...types.ErrIgnoreList) []*types.ScanResult {
+	dir := filepath.Join(topDir, innerDir)
+	var (
+		pkgs []*pythonPackage
+		err  error
+	)
+	if path.Base(innerDir) == pythonDynloadDir {
+		pkgs, err = readPythonDynload(dir, path.Base(path.Dir(innerDir)))
+	} else {
+		pkgs, err = readPythonPackages(dir)
+	}
+	if err != nil {
+		return []*types.ScanResult{types.NewScanResult().SetPath(innerDir).SetError(err)}
+	}
+
+	var results []*types.ScanResult
+pkgs:
+	for _, pkg := range pkgs {
+		var first string
+		var findings []string
+		for _, file := range pkg.Files {
+			what, err := bundledOpenssl(filepath.Join(dir, file))
+			if err != nil {
+				// Report the error for this package, and go on with the others.
+				results = append(results, types.NewScanResult().SetPath(path.Join(innerDir, file)).SetError(err))
+				continue pkgs
+			}
+			if what == "" {
+				continue
+			}
+			if first == "" {
+				first = path.Join(innerDir, file)
+			}
+			findings = append(findings, file+" ("+what+")")
+		}
+		if len(findings) == 0 {
+			continue
+		}
+		res := types.NewScanResult().SetPath(first)
+		err := fmt.Errorf("%w: %s: %s", types.ErrPythonBundledOpenSSL, pkg, strings.Join(findings, "; "))
+		if isIgnored(ctx, res, topDir, first, err, rpmIgnores, errIgnores) {
+			results = append(results, res.Success())
+			continue
+		}
+		results = append(results, res.SetError(err))
+	}
+	return results
+}
+
+// bundledOpenssl tells whether the file is a copy of OpenSSL, and how it
+// was found. An empty string is returned for any other file.
+func bundledOpenssl(file string) (string, error) {
+	base := filepath.Base(file)
+	if bundledOpensslLibRegexp.MatchString(base) {
+		return "bundled library", nil
+	}
+	if !strings.HasSuffix(base, ".so") && !strings.Contains(base, ".so.") {
+		return "", nil
+	}
+	version, err := elfEmbeddedOpenssl(file)
+	if err != nil || version == "" {
+		return "", err
+	}
+	return "embedded " + version, nil
+}
+
+// readPythonPackages returns the packages installed in the dir directory,
+// according to their dist-info or egg-info metadata. Any files not owned by
+// any package are assigned to a package named after their top level entry.
+func readPythonPackages(dir string) ([]*pythonPackage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []*pythonPackage
+	owned := make(map[string]bool)
+	for _, e := range entries {
+		var pkg *pythonPackage
+		switch {
+		case !e.IsDir():
+			continue
+		case strings.HasSuffix(e.Name(), ".dist-info"):
+			pkg, err = readDistInfo(dir, e.Name())
+		case strings.HasSuffix(e.Name(), ".egg-info"):
+			pkg, err = readEggInfo(dir, e.Name())
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		for _, f := range pkg.Files {
+			owned[f] = true
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	// Files not listed in any metadata.
+	orphans := make(map[string]*pythonPackage)
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		if d.IsDir() {
+			if strings.HasSuffix(rel, ".dist-info") || strings.HasSuffix(rel, ".egg-info") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || owned[rel] {
+			return nil
+		}
+		// E.g. "foo" for foo/x.so, foo.libs/y.so, and foo.cpython-311.so.
+		name, _, _ := strings.Cut(rel, "/")
+		name, _, _ = strings.Cut(name, ".")
+		if orphans[name] == nil {
+			orphans[name] = &pythonPackage{Name: name}
+		}
+		orphans[name].Files = append(orphans[name].Files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range orphans {
+		pkgs = append(pkgs, pkg)
+	}
+
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+	for _, pkg := range pkgs {
+		sort.Strings(pkg.Files)
+	}
+	return pkgs, nil
+}
+
+// readDistInfo reads the metadata of a package installed from a wheel.
+func readDistInfo(dir, distInfo string) (*pythonPackage, error) {
+	pkg, err := readPythonMetadata(filepath.Join(dir, distInfo, "METADATA"))
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(dir, distInfo, "RECORD"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return pkg, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("RECORD: %w", err)
+		}
+		if file, ok := inPackageDir(rec[0]); ok {
+			pkg.Files = append(pkg.Files, file)
+		}
+	}
+	return pkg, nil
+}
+
+// readEggInfo reads the metadata of a package installed by setuptools.
+func readEggInfo(dir, eggInfo string) (*pythonPackage, error) {
+	pkg, err := readPythonMetadata(filepath.Join(dir, eggInfo, "PKG-INFO"))
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(dir, eggInfo, "installed-files.txt"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return pkg, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// Paths are relative to the egg-info directory.
+		if file, ok := inPackageDir(path.Join(eggInfo, strings.TrimSpace(s.Text()))); ok {
+			pkg.Files = append(pkg.Files, file)
+		}
+	}
+	return pkg, s.Err()
+}
+
+// readPythonMetadata reads the package name and version from core
+// metadata (METADATA or PKG-INFO). A missing file is not an error.
+func readPythonMetadata(file string) (*pythonPackage, error) {
+	pkg := &pythonPackage{}
+	dir := filepath.Base(filepath.Dir(file))
+	if name, version, ok := strings.Cut(strings.TrimSuffix(strings.TrimSuffix(dir, ".dist-info"), ".egg-info"), "-"); ok {
+		pkg.Name, pkg.Version = name, strings.SplitN(version, "-", 2)[0]
+	} else {
+		pkg.Name = name
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return pkg, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			// End of headers.
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "Name:"); ok {
+			pkg.Name = strings.TrimSpace(v)
+		} else if v, ok := strings.CutPrefix(line, "Version:"); ok {
+			pkg.Version = strings.TrimSpace(v)
+		}
+	}
+	return pkg, s.Err()
+}
+
+// inPackageDir cleans a path relative to the package directory, and tells
+// whether it lies within it (scripts and data files may be outside).
+func inPackageDir(file string) (string, bool) {
+	if file == "" || path.IsAbs(file) {
+		return "", false
+	}
+	file = path.Clean(file)
+	return file, file != ".." && !strings.HasPrefix(file, "../")
+}
+
+// readPythonDynload returns the interpreter's extension modules as a
+// single package.
+func readPythonDynload(dir, name string) ([]*pythonPackage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &pythonPackage{Name: name}
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			pkg.Files = append(pkg.Files, e.Name())
+		}
+	}
+	return []*pythonPackage{pkg}, nil
+}
@@ -0,0 +1,160 @@
+package validations
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/check-payload/internal/types"
+)
+
+func TestEmbeddedOpensslVersion(t *testing.T) {
+	for file, want := range map[string]string{
+		"../../test/resources/libcrypto.so":       "OpenSSL 1.1.1k",
+		"../../test/resources/fips_compliant_app": "",
+	} {
+		got, err := embeddedOpensslVersion(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("embeddedOpensslVersion(%s) = %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestScanPythonLibDir(t *testing.T) {
+	ctx := context.Background()
+	libcrypto, err := os.ReadFile("../../test/resources/libcrypto.so")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clean, err := os.ReadFile("../../test/resources/fips_compliant_app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const site = "/usr/lib/python3.11/site-packages"
+
+	testCases := []struct {
+		name     string
+		dir      string
+		files    map[string][]byte
+		wantPath []string
+		wantErr  []string
+	}{
+		{
+			name: "wheel with vendored libraries",
+			dir:  site,
+			files: map[string][]byte{
+				"cryptography-42.0.5.dist-info/METADATA": []byte("Metadata-Version: 2.1\nName: cryptography\nVersion: 42.0.5\n\nDescription\nName: bogus\n"),
+				"cryptography-42.0.5.dist-info/RECORD": []byte("cryptography/__init__.py,sha256=x,1\n" +
+					"cryptography/hazmat/bindings/_rust.abi3.so,sha256=x,1\n" +
+					"cryptography.libs/libcrypto-a1b2c3d4.so.3,sha256=x,1\n" +
+					"../../../bin/script,sha256=x,1\n" +
+					"cryptography-42.0.5.dist-info/RECORD,,\n"),
+				"cryptography/__init__.py":                   nil,
+				"cryptography/hazmat/bindings/_rust.abi3.so": clean,
+				"cryptography.libs/libcrypto-a1b2c3d4.so.3":  libcrypto,
+			},
+			wantPath: []string{site + "/cryptography.libs/libcrypto-a1b2c3d4.so.3"},
+			wantErr:  []string{"cryptography 42.0.5: cryptography.libs/libcrypto-a1b2c3d4.so.3 (bundled library)"},
+		},
+		{
+			name: "statically linked extension",
+			dir:  site,
+			files: map[string][]byte{
+				"cryptography-41.0.7.dist-info/METADATA":     []byte("Name: cryptography\nVersion: 41.0.7\n"),
+				"cryptography-41.0.7.dist-info/RECORD":       []byte("cryptography/hazmat/bindings/_rust.abi3.so,,\n"),
+				"cryptography/hazmat/bindings/_rust.abi3.so": libcrypto,
+			},
+			wantPath: []string{site + "/cryptography/hazmat/bindings/_rust.abi3.so"},
+			wantErr:  []string{"cryptography 41.0.7: cryptography/hazmat/bindings/_rust.abi3.so (embedded OpenSSL 1.1.1k)"},
+		},
+		{
+			name: "egg-info and unowned files",
+			dir:  site,
+			files: map[string][]byte{
+				"pycryptodome-3.20.0-py3.11.egg-info/PKG-INFO":            []byte("Name: pycryptodome\nVersion: 3.20.0\n"),
+				"pycryptodome-3.20.0-py3.11.egg-info/installed-files.txt": []byte("../Crypto/Cipher/_raw_aes.abi3.so\n"),
+				"Crypto/Cipher/_raw_aes.abi3.so":                          libcrypto,
+				"orphan.libs/libssl.so.3":                                 nil,
+				"clean/_speedups.so":                                      clean,
+			},
+			wantPath: []string{site + "/orphan.libs/libssl.so.3", site + "/Crypto/Cipher/_raw_aes.abi3.so"},
+			wantErr:  []string{"orphan: orphan.libs/libssl.so.3 (bundled library)", "pycryptodome 3.20.0: Crypto/Cipher/_raw_aes.abi3.so (embedded OpenSSL 1.1.1k)"},
+		},
+		{
+			name: "interpreter extension modules",
+			dir:  "/usr/local/lib/python3.12/lib-dynload",
+			files: map[string][]byte{
+				"_hashlib.cpython-312-x86_64-linux-gnu.so": libcrypto,
+				"_json.cpython-312-x86_64-linux-gnu.so":    clean,
+			},
+			wantPath: []string{"/usr/local/lib/python3.12/lib-dynload/_hashlib.cpython-312-x86_64-linux-gnu.so"},
+			wantErr:  []string{"python3.12: _hashlib.cpython-312-x86_64-linux-gnu.so (embedded OpenSSL 1.1.1k)"},
+		},
+		{
+			name: "clean",
+			dir:  site,
+			files: map[string][]byte{
+				"six-1.16.0.dist-info/RECORD": []byte("six.py,,\n"),
+				"six.py":                      nil,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, data := range tc.files {
+				p := filepath.Join(root, tc.dir, name)
+				if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, data, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			results := ScanPythonLibDir(ctx, root, tc.dir, nil)
+			if len(results) != len(tc.wantErr) {
+				t.Fatalf("got %d results, want %d", len(results), len(tc.wantErr))
+			}
+			for i, res := range results {
+				if res.IsSuccess() {
+					t.Fatalf("result %d: expected error, got success", i)
+				}
+				if !errors.Is(res.Error.Error, types.ErrPythonBundledOpenSSL) {
+					t.Errorf("expected ErrPythonBundledOpenSSL, got %v", res.Error.Error)
+				}
+				if !strings.HasSuffix(res.Error.Error.Error(), tc.wantErr[i]) {
+					t.Errorf("got error %q, want suffix %q", res.Error.Error, tc.wantErr[i])
+				}
+				if res.Path != tc.wantPath[i] {
+					t.Errorf("got path %q, want %q", res.Path, tc.wantPath[i])
+				}
+			}
+		})
+	}
+
+	t.Run("ignored", func(t *testing.T) {
+		root := t.TempDir()
+		p := filepath.Join(root, site, "bundled.libs/libcrypto.so.3")
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		ignores := types.ErrIgnoreList{{
+			Error: types.KnownError{Err: types.ErrPythonBundledOpenSSL},
+			Dirs:  []string{site + "/bundled.libs"},
+		}}
+		results := ScanPythonLibDir(ctx, root, site, nil, ignores)
+		if len(results) != 1 || !results[0].IsSuccess() {
+			t.Errorf("expected a single success, got %+v", results)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/openshift/check-payload/dist/releases"
+	"github.com/openshift/check-payload/internal/types"
+)
+
+// lintTarget is a config to be linted.
+type lintTarget struct {
+	name    string // The config file, or embedded:version/config.toml.
+	file    string // The config file on disk, if any, for --fix.
+	data    []byte
+	base    *types.ConfigFile // The config it is added to, if any.
+	resolve func() error      // Resolves its extends and include directives.
+}
+
+// lintTargets returns the configs to lint: the files, if any, with the
+// --config files as their base, or the embedded config for -V, or else the
+// embedded main config and all the embedded configs for versions.
+func lintTargets(files []string) ([]lintTarget, error) {
+	var targets []lintTarget
+	if len(files) > 0 {
+		var base *types.ConfigFile
+		if len(configFiles) > 0 {
+			cfg, err := loadConfigFiles(configFiles)
+			if err != nil {
+				return nil, err
+			}
+			base = cfg
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, lintTarget{
+				name: file,
+				file: file,
+				data: data,
+				base: base,
+				resolve: func() error {
+					_, _, err := releases.ResolveConfig(file, data)
+					return err
+				},
+			})
+		}
+		return targets, nil
+	}
+
+	base, _, err := releases.ResolveEmbeddedConfig(defaultConfigFile, []byte(embeddedConfig))
+	if err != nil {
+		return nil, fmt.Errorf("invalid embedded config: %w", err)
+	}
+	versions := releases.GetVersions()
+	if configForVersion != "" {
+		versions = []string{configForVersion}
+	} else {
+		targets = append(targets, lintTarget{
+			name: "embedded:" + defaultConfigFile,
+			data: []byte(embeddedConfig),
+			resolve: func() error {
+				_, _, err := releases.ResolveEmbeddedConfig(defaultConfigFile, []byte(embeddedConfig))
+				return err
+			},
+		})
+	}
+	for _, v := range versions {
+		data, err := releases.GetConfigFor(v)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, lintTarget{
+			name: "embedded:" + path.Join(v, "config.toml"),
+			data: data,
+			base: base,
+			resolve: func() error {
+				_, _, err := releases.ResolveConfigFor(v)
+				return err
+			},
+		})
+	}
+	return targets, nil
+}
+
+// lintConfigs reports the issues found in the configs, and, if fix is
+// set, rewrites the config files without duplicate or redundant entries.
+func lintConfigs(files []string, fix bool) error {
+	targets, err := lintTargets(files)
+	if err != nil {
+		return err
+	}
+	if fix && len(files) == 0 {
+		return errors.New("--fix requires config files to rewrite")
+	}
+
+	var failed bool
+	for _, t := range targets {
+		cfg, issues := types.LintConfigFile(t.name, t.data, t.base)
+		var errs bool
+		for _, i := range issues {
+			errs = errs || !i.Warning
+		}
+		if !errs {
+			if err := t.resolve(); err != nil {
+				issues = append(issues, types.LintIssue{Msg: err.Error()})
+				errs = true
+			}
+		}
+		for _, i := range issues {
+			if i.Line > 0 {
+				fmt.Printf("%s:%d: %s: %s\n", t.name, i.Line, i.Level(), i.Msg)
+			} else {
+				fmt.Printf("%s: %s: %s\n", t.name, i.Level(), i.Msg)
+			}
+		}
+		failed = failed || errs
+
+		if !fix || len(issues) == 0 {
+			continue
+		}
+		if errs {
+			fmt.Printf("%s: not fixed, as it has errors\n", t.name)
+			continue
+		}
+		cfg.Tidy(t.base)
+		data, err := types.EncodeConfigFile(t.file, cfg)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(t.file, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("%s: fixed\n", t.name)
+	}
+	if failed {
+		return errors.New("config lint found errors")
+	}
+	return nil
+}
@@ -7,7 +7,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime/pprof"
+	"strings"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -22,6 +24,7 @@ import (
 const (
 	defaultPayloadFilename = "payload.json"
 	defaultConfigFile      = "config.toml"
+	configDirEnv           = "CHECK_PAYLOAD_CONFIG_DIR"
 )
 
 //go:embed config.toml
@@ -35,14 +38,14 @@ var applicationDeps = []string{
 
 var applicationDepsNodeScan = []string{
 	"nm",
-	"rpm",
 }
 
 var Commit string
 
 var (
 	components                            []string
-	configFile, configForVersion          string
+	configFiles                           []string
+	configForVersion                      string
 	cpuProfile                            string
 	failOnWarnings                        bool
 	filterFiles, filterDirs, filterImages []string
@@ -54,6 +57,11 @@ var (
 	parallelism                           int
 	printExceptions                       bool
 	pullSecretFile                        string
+	reportWaivers                         bool
+	reportUnused, failOnUnused            bool
+	writeExceptions                       string
+	scanJavaArchives                      bool
+	scanPythonPackages                    bool
 	timeLimit                             time.Duration
 	verbose                               bool
 	localBundlePath                       string
@@ -87,6 +95,104 @@ func main() {
 		},
 	}
 
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Work with configurations",
+	}
+	configCmd.PersistentFlags().StringArrayVarP(&configFiles, "config", "c", nil, "use config file, in toml, json or yaml; can be repeated, later files adding to earlier ones (default: "+defaultConfigFile+")")
+	configCmd.PersistentFlags().StringVarP(&configForVersion, "config-for-version", "V", "", "use embedded toml config file for specified version")
+
+	configShowCmd := &cobra.Command{
+		Use:          "show",
+		Short:        "Print the effective configuration, with extends and include resolved",
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if err := getConfig(&config.ConfigFile); err != nil {
+				return err
+			}
+			return toml.NewEncoder(os.Stdout).Encode(config.ConfigFile)
+		},
+	}
+	configCmd.AddCommand(configShowCmd)
+
+	var lintFix bool
+	configLintCmd := &cobra.Command{
+		Use:   "lint [files...]",
+		Short: "Check configurations for errors, and for duplicate or redundant entries",
+		Long: `Check configurations for errors, and for duplicate or redundant entries.
+
+With files, check them, reporting the entries the --config file, if set,
+already has. With -V, check the embedded configuration for that version.
+Otherwise, check the embedded main configuration, and the embedded
+configurations for all versions, reporting the entries the main one
+already has.
+
+With --fix, the files are rewritten from their decoded configuration,
+sorted and without the duplicate or redundant entries: their comments and
+formatting are not kept.`,
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return lintConfigs(args, lintFix)
+		},
+	}
+	configLintCmd.Flags().BoolVar(&lintFix, "fix", false, "rewrite the files, sorted, without duplicate or redundant entries (comments and formatting are lost)")
+	configCmd.AddCommand(configLintCmd)
+
+	var diffFormat string
+	configDiffCmd := &cobra.Command{
+		Use:   "diff OLD NEW",
+		Short: "Show the exceptions added or removed between two configurations",
+		Long: `Show the exceptions added or removed between two configurations.
+
+OLD and NEW are versions of the embedded configurations (see list-configs),
+or config files, with their extends and include resolved. The entries added
+and removed are listed per section, error, and list.`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return diffConfigs(args[0], args[1], diffFormat)
+		},
+	}
+	configDiffCmd.Flags().StringVar(&diffFormat, "output-format", "text", "output format (text, json)")
+	configCmd.AddCommand(configDiffCmd)
+
+	var explainFormat string
+	explainCmd := &cobra.Command{
+		Use:   "explain [ErrName...]",
+		Short: "Explain the errors reported by scans, and how to fix them",
+		Long: `Explain the errors reported by scans, and how to fix them.
+
+For each error name, such as ErrNotDynLinked, print its description, its
+likely causes, the remediation steps, the check reporting it, and the Go
+versions it applies to. With no names, list all the known errors.`,
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return explainErrors(args, explainFormat)
+		},
+	}
+	explainCmd.Flags().StringVar(&explainFormat, "output-format", "text", "output format (text, json)")
+
+	var inspectRoot, inspectFormat string
+	inspectCmd := &cobra.Command{
+		Use:   "inspect-binary FILE [--root dir]",
+		Short: "Print what the validations see of a binary, and their verdicts",
+		Long: `Print what the validations see of a binary, and their verdicts.
+
+This is the ELF type, whether the binary is PIE and static, the libraries
+it needs, for Go binaries their version, build settings, pclntab section
+and FIPS symbols, the crypto modules used, and the result of each check.
+With --root, FILE is a path in that directory, such as an unpacked image,
+which the checks look at (for example for the libcrypto needed), instead
+of this system. Config exceptions are not applied.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return inspectBinary(args[0], inspectRoot, inspectFormat)
+		},
+	}
+	inspectCmd.Flags().StringVar(&inspectRoot, "root", "", "directory FILE is in, such as an unpacked image")
+	inspectCmd.Flags().StringVar(&inspectFormat, "output-format", "text", "output format (text, json)")
+
 	scanCmd := &cobra.Command{
 		Use:   "scan",
 		Short: "Run a scan",
@@ -104,6 +210,11 @@ func main() {
 			config.OutputFormat = outputFormat
 			config.PrintExceptions = printExceptions
 			config.PullSecret = pullSecretFile
+			config.ReportWaivers = reportWaivers
+			config.ReportUnusedExceptions = reportUnused || failOnUnused
+			config.FailOnUnusedExceptions = failOnUnused
+			config.ScanJavaArchives = scanJavaArchives
+			config.ScanPythonPackages = scanPythonPackages
 			config.Limit = limit
 			config.TimeLimit = timeLimit
 			config.Verbose = verbose
@@ -142,16 +253,24 @@ func main() {
 				klog.Info("CPU profile saved to ", cpuProfile)
 			}
 			scan.PrintResults(&config, results)
+			if writeExceptions != "" {
+				if err := scan.WriteExceptions(writeExceptions, results); err != nil {
+					return fmt.Errorf("can't write exceptions: %w", err)
+				}
+			}
 			if scan.IsFailed(results) {
 				return errors.New("run failed")
 			}
 			if scan.IsWarnings(results) && config.FailOnWarnings {
 				return errors.New("run failed with warnings")
 			}
+			if config.FailOnUnusedExceptions && len(config.UnusedExceptions()) > 0 {
+				return errors.New("run failed with unused exceptions")
+			}
 			return nil
 		},
 	}
-	scanCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "use toml config file (default: "+defaultConfigFile+")")
+	scanCmd.PersistentFlags().StringArrayVarP(&configFiles, "config", "c", nil, "use config file, in toml, json or yaml; can be repeated, later files adding to earlier ones (default: "+defaultConfigFile+")")
 	scanCmd.PersistentFlags().StringVarP(&configForVersion, "config-for-version", "V", "", "use embedded toml config file for specified version")
 	scanCmd.PersistentFlags().StringSliceVar(&filterFiles, "filter-files", nil, "")
 	scanCmd.PersistentFlags().StringSliceVar(&filterDirs, "filter-dirs", nil, "")
@@ -162,11 +281,17 @@ func main() {
 	scanCmd.PersistentFlags().IntVar(&limit, "limit", -1, "limit the number of pods scanned")
 	scanCmd.PersistentFlags().IntVar(&parallelism, "parallelism", 5, "how many pods to check at once")
 	scanCmd.PersistentFlags().StringVar(&outputFile, "output-file", "", "write report to file")
-	scanCmd.PersistentFlags().StringVar(&outputFormat, "output-format", "table", "output format (table, csv, markdown, html)")
+	scanCmd.PersistentFlags().StringVar(&outputFormat, "output-format", "table", "output format (table, csv, markdown, html, json, sarif)")
 	scanCmd.PersistentFlags().StringVar(&pullSecretFile, "pull-secret", "", "pull secret to use for pulling images")
 	scanCmd.PersistentFlags().DurationVar(&timeLimit, "time-limit", 1*time.Hour, "limit running time")
 	scanCmd.PersistentFlags().StringVar(&cpuProfile, "cpuprofile", "", "write CPU profile to file")
 	scanCmd.PersistentFlags().BoolVarP(&printExceptions, "print-exceptions", "p", false, "display exception list")
+	scanCmd.PersistentFlags().StringVar(&writeExceptions, "write-exceptions", "", "write the exceptions ignoring all the errors found to a toml file, appending the new ones to it if it exists")
+	scanCmd.PersistentFlags().BoolVar(&reportWaivers, "report-waivers", false, "report the config exceptions applied during the scan, with their tickets")
+	scanCmd.PersistentFlags().BoolVar(&reportUnused, "report-unused-exceptions", false, "report the config exceptions which suppressed nothing during the scan")
+	scanCmd.PersistentFlags().BoolVar(&failOnUnused, "fail-on-unused-exceptions", false, "fail if any config exception suppressed nothing during the scan (implies --report-unused-exceptions)")
+	scanCmd.PersistentFlags().BoolVar(&scanJavaArchives, "scan-java-archives", false, "scan java archives (jar, war, ear) for bundled non-FIPS crypto providers (always on for java-image scans)")
+	scanCmd.PersistentFlags().BoolVar(&scanPythonPackages, "scan-python-packages", false, "scan python packages (site-packages, dist-packages, lib-dynload) for bundled OpenSSL copies")
 
 	scanPayload := &cobra.Command{
 		Use:          "payload [image pull spec]",
@@ -280,6 +405,8 @@ func main() {
 			config.UseRPMScan, _ = cmd.Flags().GetBool("rpm-scan")
 			config.JavaDisabledAlgorithms = append(config.JavaDisabledAlgorithms, javaDisabledAlgorithms...)
 			config.Java = true
+			config.SetDefaultSeverity(types.JavaImageSeverity)
+			config.ScanJavaArchives = true
 			results = scan.RunOperatorScan(ctx, &config)
 		},
 	}
@@ -295,6 +422,9 @@ func main() {
 
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(configsCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(inspectCmd)
 	rootCmd.AddCommand(scanCmd)
 
 	// Add klog flags.
@@ -310,47 +440,59 @@ func main() {
 
 func getConfig(config *types.ConfigFile) error {
 	// Handle --config.
-	file := configFile
-	if file == "" {
-		file = defaultConfigFile
+	files := configFiles
+	if len(files) == 0 {
+		if _, err := os.Stat(defaultConfigFile); errors.Is(err, os.ErrNotExist) {
+			// When --config not specified and defaultConfigFile is not found,
+			// fall back to embedded config.
+			klog.Info("using embedded config")
+			cfg, warn, err := releases.ResolveEmbeddedConfig(defaultConfigFile, []byte(embeddedConfig))
+			if err != nil { // Should never happen.
+				panic("invalid embedded config: " + err.Error())
+			}
+			if warn != nil {
+				klog.Warning(warn)
+			}
+			*config = *cfg
+		} else {
+			files = []string{defaultConfigFile}
+		}
 	}
-	res, err := toml.DecodeFile(file, &config)
-	if err == nil {
-		klog.Infof("using config file: %v", file)
-		if un := res.Undecoded(); len(un) != 0 {
-			return fmt.Errorf("unknown keys in config: %+v", un)
+	if len(files) > 0 {
+		cfg, err := loadConfigFiles(files)
+		if err != nil {
+			return err
 		}
-	} else if errors.Is(err, os.ErrNotExist) && configFile == "" {
-		// When --config not specified and defaultConfigFile is not found,
-		// fall back to embedded config.
-		klog.Info("using embedded config")
-		res, err = toml.Decode(embeddedConfig, &config)
-		if err != nil { // Should never happen.
-			panic("invalid embedded config: " + err.Error())
+		*config = *cfg
+	}
+
+	// Handle the drop-in directory, whose files are added in name order.
+	if dir := os.Getenv(configDirEnv); dir != "" {
+		dropIns, err := configDirFiles(dir)
+		if err != nil {
+			return fmt.Errorf("can't read %s: %w", configDirEnv, err)
 		}
-		if un := res.Undecoded(); len(un) != 0 {
-			panic(fmt.Errorf("unknown keys in config: %+v", un))
+		if len(dropIns) > 0 {
+			cfg, err := loadConfigFiles(dropIns)
+			if err != nil {
+				return err
+			}
+			if warn := config.Add(cfg); warn != nil {
+				klog.Warning(warn)
+			}
 		}
-	} else {
-		// Otherwise, error out.
-		return fmt.Errorf("can't parse config file %q: %w", file, err)
 	}
 
 	if configForVersion != "" {
 		// Append to the main config.
-		cfg, err := releases.GetConfigFor(configForVersion)
+		addConfig, warn, err := releases.ResolveConfigFor(configForVersion)
 		if err != nil {
 			return err
 		}
-		klog.Infof("adding rules from embedded config for %s", configForVersion)
-		addConfig := &types.ConfigFile{}
-		res, err = toml.Decode(string(cfg), &addConfig)
-		if err != nil { // Should never happen.
-			panic("invalid embedded config: " + err.Error())
-		}
-		if un := res.Undecoded(); len(un) != 0 {
-			panic(fmt.Errorf("unknown keys in config: %+v", un))
+		if warn != nil {
+			klog.Warning(warn)
 		}
+		klog.Infof("adding rules from embedded config for %s", configForVersion)
 		if warn := config.Add(addConfig); warn != nil {
 			klog.Warning(warn)
 		}
@@ -358,3 +500,50 @@ func getConfig(config *types.ConfigFile) error {
 
 	return nil
 }
+
+// loadConfigFiles reads the config files, resolves their extends and
+// include directives, and adds them up in order.
+func loadConfigFiles(files []string) (*types.ConfigFile, error) {
+	var config *types.ConfigFile
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse config file %q: %w", file, err)
+		}
+		klog.Infof("using config file: %v", file)
+		cfg, warn, err := releases.ResolveConfig(file, data)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse config file %q: %w", file, err)
+		}
+		if warn != nil {
+			klog.Warning(warn)
+		}
+		if config == nil {
+			config = cfg
+		} else if warn := config.Add(cfg); warn != nil {
+			klog.Warning(warn)
+		}
+	}
+	return config, nil
+}
+
+// configDirFiles returns the config files of the drop-in directory dir,
+// sorted by name. Hidden files, and files without a .toml, .json, .yaml or
+// .yml extension, are skipped.
+func configDirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".toml", ".json", ".yaml", ".yml":
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,23 @@
+#!/bin/bash
+set -euo pipefail
+# Build a minimal rpmdb.sqlite for testing the in-process sqlite reader.
+# It has the same Packages table as rpm creates, filled with synthetic
+# blobs (see sqliteFixtureBlob in internal/rpm/rpm_test.go). A small page
+# size is used so that the table has interior pages and overflow chains.
+SCRIPT_DIR="$(cd "$(dirname "$0")" && pwd)"
+OUT="$SCRIPT_DIR/rpmdb.sqlite"
+rm -f "$OUT"
+python3 - "$OUT" <<'PY'
+import sqlite3, sys
+
+db = sqlite3.connect(sys.argv[1])
+db.execute("PRAGMA page_size = 1024")
+db.execute("CREATE TABLE 'Packages' (hnum INTEGER PRIMARY KEY AUTOINCREMENT, blob BLOB NOT NULL)")
+for i in range(1, 101):
+    n = i * 31 % 2500
+    db.execute("INSERT INTO Packages (blob) VALUES (?)", (bytes((i + j) % 256 for j in range(n)),))
+db.commit()
+db.execute("VACUUM")
+db.close()
+PY
+echo "Output: $OUT"
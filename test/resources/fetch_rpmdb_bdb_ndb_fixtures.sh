@@ -0,0 +1,28 @@
+#!/bin/bash
+set -euo pipefail
+# Fetch rpmdb Packages (Berkeley DB) and Packages.db (ndb) files written
+# by rpm itself, for testing the in-process readers against real data
+# rather than only the rpmtest writers. They come from the test data of
+# github.com/knqyf263/go-rpmdb (MIT license), fetched from the Go module
+# proxy at a pinned version and checked against known hashes:
+#  - rpmdb_bdb/Packages: libuuid-2.32.1-42.el8_8.x86_64, from UBI 8.
+#  - rpmdb_ndb/Packages.db: 35 packages of the SLE 15 BCI base image.
+SCRIPT_DIR="$(cd "$(dirname "$0")" && pwd)"
+MOD="github.com/knqyf263/go-rpmdb@v0.1.1"
+URL="https://proxy.golang.org/github.com/knqyf263/go-rpmdb/@v/v0.1.1.zip"
+ZIP_SHA256="5f9388dd11317a9f9443c2dc60a29e8d3329fc2f900b0a0bc3419d136f58e294"
+
+TMP="$(mktemp -d)"
+trap 'rm -rf "$TMP"' EXIT
+curl -fsSL -o "$TMP/mod.zip" "$URL"
+echo "$ZIP_SHA256  $TMP/mod.zip" | sha256sum -c --quiet
+
+fetch() { # src dst sha256
+	unzip -q -o -j -d "$TMP" "$TMP/mod.zip" "$MOD/pkg/testdata/$1"
+	echo "$3  $TMP/$(basename "$1")" | sha256sum -c --quiet
+	mkdir -p "$(dirname "$SCRIPT_DIR/$2")"
+	mv "$TMP/$(basename "$1")" "$SCRIPT_DIR/$2"
+	echo "Output: $SCRIPT_DIR/$2"
+}
+fetch libuuid/Packages rpmdb_bdb/Packages 15bdf607cc34db1800420a6302ae2d43e40e4ed9f3d6f4153a5b726e83a8fec5
+fetch sle15-bci/Packages.db rpmdb_ndb/Packages.db c0578453d18e262823bc498ea1805645c9a94b9a91898d50dcf186a56d378968